@@ -1,14 +1,24 @@
 package analysis_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/tgenz1213/archguard/internal/analysis"
+	"github.com/tgenz1213/archguard/internal/cache"
 	"github.com/tgenz1213/archguard/internal/config"
 	"github.com/tgenz1213/archguard/internal/index"
 	"github.com/tgenz1213/archguard/internal/llm"
@@ -39,6 +49,30 @@ func (m *MockContentProvider) GetDiff(path string) (string, error) {
 	return m.GetContent(path)
 }
 
+// golangADR is the ADR most engine tests check files against.
+var golangADR = index.ADR{ID: "0001", Title: "Use Golang", Status: "Accepted", Content: "All services must be Go."}
+
+// unitEmbedding matches the default MockProvider embedding, so an ADR carrying
+// it is retrieved for every file.
+func unitEmbedding() []float32 {
+	v := make([]float32, 1536)
+	v[0] = 1.0
+	return v
+}
+
+// newStore returns a local index holding adrs, giving each ADR without an
+// embedding unitEmbedding.
+func newStore(adrs ...index.ADR) *index.LocalStore {
+	store := index.NewLocalStore(5)
+	for _, adr := range adrs {
+		if adr.Embedding == nil {
+			adr.Embedding = unitEmbedding()
+		}
+		store.ADRs = append(store.ADRs, adr)
+	}
+	return store
+}
+
 func TestDriftDetection(t *testing.T) {
 	// 1. Setup Mock Provider
 	provider := &llm.MockProvider{
@@ -53,16 +87,7 @@ func TestDriftDetection(t *testing.T) {
 	}
 
 	// 2. Setup Store with one ADR
-	store := index.NewLocalStore(5)
-	store.ADRs = []index.ADR{
-		{
-			ID:        "0001",
-			Title:     "Use Golang",
-			Status:    "Accepted",
-			Content:   "All services must be Go.",
-			Embedding: func() []float32 { v := make([]float32, 1536); v[0] = 1.0; return v }(),
-		},
-	}
+	store := newStore(golangADR)
 
 	// 3. Setup Config
 	cfg := &config.Config{
@@ -80,7 +105,7 @@ func TestDriftDetection(t *testing.T) {
 	// 5. Run Engine
 	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
 	engine.Cache = nil // Disable cache for testing
-	err := engine.Run(context.Background())
+	_, err := engine.Run(context.Background())
 
 	// 6. Verify Results
 	// Expect failure due to violation
@@ -108,16 +133,7 @@ func TestCustomSystemPrompt(t *testing.T) {
 	}
 
 	// 2. Setup Store with one ADR
-	store := index.NewLocalStore(5)
-	store.ADRs = []index.ADR{
-		{
-			ID:        "0001",
-			Title:     "Test ADR",
-			Status:    "Accepted",
-			Content:   "Test content",
-			Embedding: func() []float32 { v := make([]float32, 1536); v[0] = 1.0; return v }(),
-		},
-	}
+	store := newStore(index.ADR{ID: "0001", Title: "Test ADR", Status: "Accepted", Content: "Test content"})
 
 	// 3. Setup Config with custom system prompt
 	cfg := &config.Config{
@@ -138,7 +154,7 @@ func TestCustomSystemPrompt(t *testing.T) {
 	// 5. Run Engine
 	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
 	engine.Cache = nil // Disable cache for testing
-	err := engine.Run(context.Background())
+	_, err := engine.Run(context.Background())
 
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -192,7 +208,7 @@ func TestRun_RespectsMaxConcurrency(t *testing.T) {
 	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
 	engine.Cache = nil
 
-	if err := engine.Run(context.Background()); err != nil {
+	if _, err := engine.Run(context.Background()); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -202,3 +218,1184 @@ func TestRun_RespectsMaxConcurrency(t *testing.T) {
 		t.Errorf("expected at most 3 concurrent GetContent calls, saw %d", content.maxSeen)
 	}
 }
+
+func TestRun_MaxFilesSafeguard(t *testing.T) {
+	content := &MockContentProvider{
+		Files: map[string]string{"a.go": "package a", "b.go": "package b", "c.go": "package c"},
+	}
+	cfg := &config.Config{
+		Analysis: config.Analysis{MaxFiles: 2, ExcludePatterns: []string{}},
+	}
+
+	t.Run("refuses without confirmation", func(t *testing.T) {
+		engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, content, false, false)
+		engine.Cache = nil
+
+		_, err := engine.Run(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "--yes-really") {
+			t.Fatalf("expected max_files refusal, got %v", err)
+		}
+	})
+
+	t.Run("proceeds when confirmed", func(t *testing.T) {
+		engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, content, false, false)
+		engine.Cache = nil
+		asked := false
+		engine.Confirm = func(prompt string) bool { asked = true; return true }
+
+		if _, err := engine.Run(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !asked {
+			t.Error("expected confirmation prompt")
+		}
+	})
+
+	t.Run("proceeds with override", func(t *testing.T) {
+		engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, content, false, false)
+		engine.Cache = nil
+		engine.SkipFileLimit = true
+
+		if _, err := engine.Run(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestRun_SortOutput(t *testing.T) {
+	files := map[string]string{"c.go": "package c", "a.go": "package a", "b.go": "package b"}
+	cfg := &config.Config{
+		Analysis: config.Analysis{ExcludePatterns: []string{}},
+	}
+	engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, &MockContentProvider{Files: files}, false, false)
+	engine.Cache = nil
+	engine.Verbosity = analysis.VerbosityFiles
+	engine.SortOutput = true
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	_, runErr := engine.Run(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	if runErr != nil {
+		t.Fatalf("unexpected error: %v", runErr)
+	}
+	a := strings.Index(string(out), "Analyzing a.go")
+	b := strings.Index(string(out), "Analyzing b.go")
+	c := strings.Index(string(out), "Analyzing c.go")
+	if a < 0 || b < 0 || c < 0 || !(a < b && b < c) {
+		t.Errorf("expected output sorted by path, got:\n%s", out)
+	}
+}
+
+type diffContentProvider struct {
+	MockContentProvider
+	Diffs map[string]string
+}
+
+func (d *diffContentProvider) GetDiff(path string) (string, error) { return d.Diffs[path], nil }
+
+func TestRun_DiffThenFullEscalates(t *testing.T) {
+	var prompts []string
+	var mu sync.Mutex
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			mu.Lock()
+			prompts = append(prompts, user)
+			mu.Unlock()
+			if strings.Contains(user, "+helper()") {
+				return `{"violation": false, "reasoning": "unclear", "quoted_code": "", "needs_full_context": true}`, nil
+			}
+			return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import python_library"}`, nil
+		},
+	}
+
+	store := newStore(golangADR)
+
+	cfg := &config.Config{
+		Analysis: config.Analysis{ExcludePatterns: []string{}, ContextStrategy: analysis.ContextDiffThenFull},
+	}
+	content := &diffContentProvider{
+		MockContentProvider: MockContentProvider{Files: map[string]string{"service.py": "import python_library\nhelper()\n"}},
+		Diffs:               map[string]string{"service.py": "+helper()\n"},
+	}
+
+	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+	engine.Cache = nil
+	_, err := engine.Run(context.Background())
+
+	if !errors.Is(err, analysis.ErrDriftDetected) {
+		t.Fatalf("expected violation after escalation, got %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected a diff pass and a full-content pass, got %d LLM calls", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "import python_library") {
+		t.Errorf("expected second pass to send full content, got:\n%s", prompts[1])
+	}
+}
+
+func TestRun_FunctionsContextReportsFileLines(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": true, "reasoning": "Direct database access.", "quoted_code": "db.Query(\"SELECT 1\")"}`, nil
+		},
+	}
+	src := "package demo\n\nimport (\n\t\"fmt\"\n)\n\n// Untouched is not changed.\nfunc Untouched() {\n\tfmt.Println(\"untouched\")\n}\n\n// Changed calls the database directly.\nfunc Changed() {\n\tdb.Query(\"SELECT 1\")\n}\n"
+	cfg := &config.Config{
+		Analysis: config.Analysis{ExcludePatterns: []string{}, ContextStrategy: analysis.ContextFunctions},
+	}
+	content := &diffContentProvider{
+		MockContentProvider: MockContentProvider{Files: map[string]string{"demo.go": src}},
+		Diffs:               map[string]string{"demo.go": "--- a/demo.go\n+++ b/demo.go\n@@ -14 +14 @@\n-\tdb.Query(\"SELECT 0\")\n+\tdb.Query(\"SELECT 1\")\n"},
+	}
+	engine := analysis.NewEngine(cfg, newStore(golangADR), provider, content, false, false)
+	engine.Cache = nil
+	var out bytes.Buffer
+	engine.Out = &out
+
+	if _, err := engine.Run(context.Background()); !errors.Is(err, analysis.ErrDriftDetected) {
+		t.Fatalf("expected a violation, got %v", err)
+	}
+	if !strings.Contains(out.String(), "[VIOLATION] Use Golang [Line 14]") {
+		t.Errorf("expected the violation at file line 14, got:\n%s", out.String())
+	}
+}
+
+func TestRun_InvalidContextStrategy(t *testing.T) {
+	cfg := &config.Config{
+		Analysis: config.Analysis{ExcludePatterns: []string{}, ContextStrategy: "everything"},
+	}
+	engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, &MockContentProvider{}, false, false)
+	engine.Cache = nil
+
+	if _, err := engine.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "context_strategy") {
+		t.Fatalf("expected context_strategy error, got %v", err)
+	}
+}
+
+func TestRun_EuclideanNeedsPositiveThreshold(t *testing.T) {
+	for _, threshold := range []float64{0, -0.5} {
+		cfg := &config.Config{
+			VectorStore: config.VectorStore{Metric: "euclidean", SimilarityThreshold: threshold},
+			Analysis:    config.Analysis{ExcludePatterns: []string{}},
+		}
+		engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, &MockContentProvider{}, false, false)
+		engine.Cache = nil
+
+		if _, err := engine.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "similarity_threshold") {
+			t.Errorf("threshold %g: expected similarity_threshold error, got %v", threshold, err)
+		}
+	}
+}
+
+func TestRun_OnTruncation(t *testing.T) {
+	var lines []string
+	for i := 0; i < 40; i++ {
+		lines = append(lines, fmt.Sprintf("line_%02d := compute()", i))
+	}
+	source := strings.Join(lines, "\n") + "\n"
+
+	run := func(policy string) ([]string, error) {
+		var prompts []string
+		var mu sync.Mutex
+		provider := &llm.MockProvider{
+			ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+				mu.Lock()
+				prompts = append(prompts, user)
+				mu.Unlock()
+				return `{"violation": false, "reasoning": "ok", "quoted_code": ""}`, nil
+			},
+		}
+		store := newStore(golangADR)
+		cfg := &config.Config{
+			LLM:      config.LLMConfig{MaxTokens: 50},
+			Analysis: config.Analysis{ExcludePatterns: []string{}, ContextStrategy: analysis.ContextFull, OnTruncation: policy},
+		}
+		content := &MockContentProvider{Files: map[string]string{"big.go": source}}
+		engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+		engine.Cache = nil
+		_, err := engine.Run(context.Background())
+		return prompts, err
+	}
+
+	prompts, err := run(analysis.TruncationChunk)
+	if err != nil {
+		t.Fatalf("chunk: unexpected error: %v", err)
+	}
+	if len(prompts) < 2 {
+		t.Fatalf("chunk: expected several LLM calls, got %d", len(prompts))
+	}
+	all := strings.Join(prompts, "\n")
+	for _, line := range lines {
+		if !strings.Contains(all, line) {
+			t.Errorf("chunk: %q was never sent to the LLM", line)
+		}
+	}
+
+	prompts, err = run(analysis.TruncationError)
+	if err == nil || !strings.Contains(err.Error(), "on_truncation") {
+		t.Fatalf("error: expected truncation error, got %v", err)
+	}
+	if len(prompts) != 0 {
+		t.Errorf("error: expected no LLM calls, got %d", len(prompts))
+	}
+
+	if _, err := run("ignore"); err == nil || !strings.Contains(err.Error(), "on_truncation") {
+		t.Errorf("expected invalid on_truncation error, got %v", err)
+	}
+}
+
+func TestLiveCacheKeys_IncludesTrimmedContentKeys(t *testing.T) {
+	var lines []string
+	for i := 0; i < 40; i++ {
+		lines = append(lines, fmt.Sprintf("line_%02d := compute()", i))
+	}
+	hunk := "@@ -1,3 +1,3 @@\n" + strings.Repeat("-x := map[string]int{ \"a\": 1 }\n+x := map[string]int{\"a\": 1}\n", 5)
+	diff := "--- a/svc.go\n+++ b/svc.go\n" + hunk + strings.Replace(hunk, "-1,3 +1,3", "-80,3 +80,3", 1)
+
+	tests := []struct {
+		name     string
+		analysis config.Analysis
+		content  analysis.ContentProvider
+	}{
+		{
+			name:     "chunks",
+			analysis: config.Analysis{ExcludePatterns: []string{}, ContextStrategy: analysis.ContextFull, OnTruncation: analysis.TruncationChunk},
+			content:  &MockContentProvider{Files: map[string]string{"big.go": strings.Join(lines, "\n") + "\n"}},
+		},
+		{
+			name:     "focused diff",
+			analysis: config.Analysis{ExcludePatterns: []string{}, ContextStrategy: analysis.ContextDiff, FocusDiffTokens: 40},
+			content: &diffContentProvider{
+				MockContentProvider: MockContentProvider{Files: map[string]string{"svc.go": "x := 1\n"}},
+				Diffs:               map[string]string{"svc.go": diff},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompts []string
+			var mu sync.Mutex
+			provider := &llm.MockProvider{ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+				mu.Lock()
+				prompts = append(prompts, user)
+				mu.Unlock()
+				return `{"violation": false, "reasoning": "ok", "quoted_code": ""}`, nil
+			}}
+			store := newStore(golangADR)
+			cfg := &config.Config{LLM: config.LLMConfig{MaxTokens: 50}, Analysis: tt.analysis}
+			engine := analysis.NewEngine(cfg, store, provider, tt.content, false, false)
+			c, err := cache.NewCacheWithDir(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine.Cache = c
+			if _, err := engine.Run(context.Background()); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if tt.analysis.FocusDiffTokens > 0 && (len(prompts) != 1 || strings.Contains(prompts[0], "@@ -80,3")) {
+				t.Fatalf("expected the diff to be trimmed to one hunk, got %q", prompts)
+			}
+
+			entries, err := c.Entries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) == 0 {
+				t.Fatal("expected the run to write cache entries")
+			}
+			live, err := engine.LiveCacheKeys(store.ADRs)
+			if err != nil {
+				t.Fatalf("LiveCacheKeys failed: %v", err)
+			}
+			for _, entry := range entries {
+				if !live[entry.Key] {
+					t.Errorf("cache entry %s written by the run is not reported live", entry.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_EmbedFileHeader(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		var texts []string
+		var mu sync.Mutex
+		provider := &llm.MockProvider{
+			EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+				mu.Lock()
+				texts = append(texts, text)
+				mu.Unlock()
+				v := make([]float32, 1536)
+				v[0] = 1.0
+				return v, nil
+			},
+		}
+		cfg := &config.Config{
+			VectorStore: config.VectorStore{EmbedFileHeader: enabled},
+			Analysis:    config.Analysis{ExcludePatterns: []string{}},
+		}
+		content := &MockContentProvider{Files: map[string]string{"internal/auth/session.go": "package auth\n"}}
+		engine := analysis.NewEngine(cfg, index.NewLocalStore(5), provider, content, false, false)
+		engine.Cache = nil
+		if _, err := engine.Run(context.Background()); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		want := "package auth\n"
+		if enabled {
+			want = "Path: internal/auth/session.go\nLanguage: Go\npackage auth\n"
+		}
+		if len(texts) != 1 || texts[0] != want {
+			t.Errorf("embed_file_header=%v: expected embedding text %q, got %q", enabled, want, texts)
+		}
+	}
+}
+
+func TestRun_OnParseFailure(t *testing.T) {
+	for _, policy := range []string{analysis.ParseFailureSkip, analysis.ParseFailureFail} {
+		var calls atomic.Int32
+		provider := &llm.MockProvider{
+			ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+				calls.Add(1)
+				return "", nil
+			},
+		}
+		store := newStore(golangADR)
+		cfg := &config.Config{
+			LLM:      config.LLMConfig{OnParseFailure: policy},
+			Analysis: config.Analysis{ExcludePatterns: []string{}},
+		}
+		content := &MockContentProvider{Files: map[string]string{"main.go": "package main\n"}}
+		engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+		engine.Cache = nil
+
+		_, err := engine.Run(context.Background())
+		if policy == analysis.ParseFailureSkip && err != nil {
+			t.Fatalf("%s: unexpected error: %v", policy, err)
+		}
+		if policy == analysis.ParseFailureFail && (err == nil || !strings.Contains(err.Error(), "could not be fully analyzed")) {
+			t.Fatalf("%s: expected the failed check to fail the run, got %v", policy, err)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Errorf("%s: expected a single chat attempt, got %d", policy, n)
+		}
+	}
+
+	cfg := &config.Config{LLM: config.LLMConfig{OnParseFailure: "ignore"}}
+	engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, &MockContentProvider{}, false, false)
+	if _, err := engine.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "on_parse_failure") {
+		t.Errorf("expected invalid on_parse_failure error, got %v", err)
+	}
+}
+
+func TestRun_ChangedLinesOnly(t *testing.T) {
+	file := "package main\n\nvar password = \"old\"\nvar token = \"new\"\nreturn err\nreturn err\n"
+	diff := "@@ -1,4 +1,6 @@\n package main\n \n var password = \"old\"\n+var token = \"new\"\n return err\n+return err\n"
+
+	for _, tt := range []struct {
+		quote     string
+		wantDrift bool
+	}{
+		{`var password = "old"`, false},
+		{`var token = "new"`, true},
+		// Only the second occurrence, line 6, was added.
+		{`return err`, true},
+	} {
+		provider := &llm.MockProvider{
+			ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+				return fmt.Sprintf(`{"violation": true, "reasoning": "hardcoded secret", "quoted_code": %q}`, tt.quote), nil
+			},
+		}
+		store := newStore(index.ADR{ID: "0001", Title: "No Secrets", Status: "Accepted", Content: "Do not hardcode secrets."})
+		cfg := &config.Config{Analysis: config.Analysis{ExcludePatterns: []string{}}}
+		content := &diffContentProvider{
+			MockContentProvider: MockContentProvider{Files: map[string]string{"main.go": file}},
+			Diffs:               map[string]string{"main.go": diff},
+		}
+		engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+		engine.Cache = nil
+		engine.ChangedLinesOnly = true
+
+		_, err := engine.Run(context.Background())
+		if got := errors.Is(err, analysis.ErrDriftDetected); got != tt.wantDrift {
+			t.Errorf("quote %q: expected drift=%v, got err %v", tt.quote, tt.wantDrift, err)
+		}
+	}
+}
+
+func TestRun_ListsIncompleteFilesAfterViolations(t *testing.T) {
+	provider := &llm.MockProvider{
+		EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			if strings.Contains(text, "broken") {
+				return nil, errors.New("embedding service unavailable")
+			}
+			v := make([]float32, 1536)
+			v[0] = 1.0
+			return v, nil
+		},
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": true, "reasoning": "uses Python", "quoted_code": "import os"}`, nil
+		},
+	}
+	store := newStore(golangADR)
+	files := map[string]string{"a.py": "import os\n", "b.go": "package broken\n"}
+	cfg := &config.Config{Analysis: config.Analysis{ExcludePatterns: []string{}}}
+	engine := analysis.NewEngine(cfg, store, provider, &MockContentProvider{Files: files}, false, false)
+	engine.Cache = nil
+	engine.SortOutput = true
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	summary, runErr := engine.Run(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	if runErr == nil || !strings.Contains(runErr.Error(), "1 file(s) could not be fully analyzed (1 violations found)") {
+		t.Fatalf("expected an incomplete-analysis error, got %v", runErr)
+	}
+	if summary == nil || summary.Violations != 1 {
+		t.Fatalf("expected the violation in a.py to be counted, got %+v", summary)
+	}
+	violation := strings.Index(string(out), "[VIOLATION] Use Golang")
+	list := strings.Index(string(out), "  b.go: embedding failed: embedding service unavailable")
+	if violation < 0 || list < 0 || violation > list {
+		t.Errorf("expected the violation before the list of incomplete files, got:\n%s", out)
+	}
+
+	// --ci fails the same way: a coverage gap must not pass a pipeline.
+	ci := analysis.NewEngine(cfg, store, provider, &MockContentProvider{Files: files}, false, true)
+	ci.Cache = nil
+	ci.Out = io.Discard
+	if _, err := ci.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "1 file(s) could not be fully analyzed") {
+		t.Errorf("expected --ci to fail on incomplete files, got %v", err)
+	}
+}
+
+func TestRun_BaselineMarksKnownViolations(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": true, "reasoning": "hardcoded secret", "quoted_code": "password = \"x\""}`, nil
+		},
+	}
+	store := newStore(index.ADR{ID: "0001", Title: "No Secrets", Status: "Accepted", Content: "Do not hardcode secrets."})
+	files := map[string]string{"old.go": "password = \"x\"\n", "new.go": "password = \"x\"\n"}
+	cfg := &config.Config{Analysis: config.Analysis{ExcludePatterns: []string{}}}
+	engine := analysis.NewEngine(cfg, store, provider, &MockContentProvider{Files: files}, false, false)
+	engine.Cache = nil
+	engine.Baseline = []analysis.ViolationRecord{{File: "old.go", ADRID: "0001", Line: 7, Code: "password  =  \"x\""}}
+	engine.CollectViolations = true
+	var out bytes.Buffer
+	engine.JSONL = &out
+
+	summary, err := engine.Run(context.Background())
+	var drift *analysis.DriftDetectedError
+	if !errors.As(err, &drift) || drift.Count != 1 {
+		t.Fatalf("expected only the new violation to count, got %v", err)
+	}
+	if summary.Violations != 1 || summary.Known != 1 {
+		t.Errorf("expected 1 new and 1 known violation, got %d and %d", summary.Violations, summary.Known)
+	}
+	if len(summary.Records) != 2 || len(summary.NewRecords()) != 1 || summary.NewRecords()[0].File != "new.go" {
+		t.Errorf("expected both violations recorded and only new.go as new, got %+v", summary.Records)
+	}
+
+	records, err := analysis.ReadViolationRecords(&out)
+	if err != nil {
+		t.Fatalf("failed to read JSONL output: %v", err)
+	}
+	baseline := map[string]bool{}
+	for _, rec := range records {
+		baseline[rec.File] = rec.Baseline
+	}
+	if len(records) != 2 || !baseline["old.go"] || baseline["new.go"] {
+		t.Errorf("expected old.go tagged as baseline and new.go not, got %+v", records)
+	}
+}
+
+func TestRun_MaxTokensBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		llm    config.LLMConfig
+		budget config.Analysis
+		limit  string
+	}{
+		{
+			name:   "tokens",
+			llm:    config.LLMConfig{SystemPrompt: "Check."},
+			budget: config.Analysis{MaxTokensBudget: 200},
+			limit:  "analysis.max_tokens_budget: 200",
+		},
+		{
+			// At $1 per token, $200 buys the same 200 tokens.
+			name:   "cost",
+			llm:    config.LLMConfig{SystemPrompt: "Check.", InputCostPerMTok: 1e6, OutputCostPerMTok: 1e6},
+			budget: config.Analysis{MaxCostUSD: 200},
+			limit:  "analysis.max_cost_usd: 200",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			provider := &llm.MockProvider{
+				ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+					calls.Add(1)
+					return `{"violation": false, "reasoning": "ok", "quoted_code": ""}`, nil
+				},
+			}
+			store := newStore(golangADR)
+			body := "package main\n" + strings.Repeat("fmt.Println(i)\n", 30)
+			tt.budget.ExcludePatterns = []string{}
+			tt.budget.MaxConcurrency = 1
+			cfg := &config.Config{LLM: tt.llm, Analysis: tt.budget}
+			content := &MockContentProvider{Files: map[string]string{"a.go": body, "b.go": body, "c.go": body}}
+			engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+			engine.Cache = nil
+
+			_, err := engine.Run(context.Background())
+			if !errors.Is(err, analysis.ErrBudgetExhausted) {
+				t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.limit) || !strings.Contains(err.Error(), "scanned 1 of 3 files") {
+				t.Errorf("expected the limit and scanned count in error, got %v", err)
+			}
+			if n := calls.Load(); n != 1 {
+				t.Errorf("expected the budget to allow a single LLM call, got %d", n)
+			}
+		})
+	}
+}
+
+func TestRun_MaxCostUSDNeedsPrices(t *testing.T) {
+	cfg := &config.Config{
+		Analysis: config.Analysis{ExcludePatterns: []string{}, MaxCostUSD: 5},
+	}
+	engine := analysis.NewEngine(cfg, index.NewLocalStore(5), &llm.MockProvider{}, &MockContentProvider{}, false, false)
+	engine.Cache = nil
+
+	if _, err := engine.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "input_cost_per_mtok") {
+		t.Fatalf("expected an error naming the missing prices, got %v", err)
+	}
+}
+
+func TestRun_ParallelADRs(t *testing.T) {
+	for _, tt := range []struct {
+		parallel bool
+		peak     int32
+	}{
+		{parallel: false, peak: 1},
+		{parallel: true, peak: 2},
+	} {
+		t.Run(fmt.Sprintf("parallel=%v", tt.parallel), func(t *testing.T) {
+			var inFlight, peak atomic.Int32
+			provider := &llm.MockProvider{
+				ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+					n := inFlight.Add(1)
+					for {
+						p := peak.Load()
+						if n <= p || peak.CompareAndSwap(p, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+					inFlight.Add(-1)
+					return `{"violation": true, "reasoning": "bad", "quoted_code": "package main"}`, nil
+				},
+			}
+			store := newStore()
+			for _, id := range []string{"0001", "0002", "0003"} {
+				store.ADRs = append(store.ADRs, index.ADR{ID: id, Title: "Rule " + id, Status: "Accepted", Content: "Rule " + id, Embedding: unitEmbedding()})
+			}
+			cfg := &config.Config{
+				LLM:      config.LLMConfig{SystemPrompt: "Check."},
+				Analysis: config.Analysis{ExcludePatterns: []string{}, MaxConcurrency: 2},
+			}
+			content := &MockContentProvider{Files: map[string]string{"main.go": "package main\n"}}
+			engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+			engine.Cache = nil
+			engine.ParallelADRs = tt.parallel
+
+			_, err := engine.Run(context.Background())
+			var driftErr *analysis.DriftDetectedError
+			if !errors.As(err, &driftErr) || driftErr.Count != 3 {
+				t.Fatalf("expected 3 violations, got %v", err)
+			}
+			if got := peak.Load(); got != tt.peak {
+				t.Errorf("expected at most %d concurrent LLM calls, got %d", tt.peak, got)
+			}
+		})
+	}
+}
+
+func TestRun_ConcurrencyRamp(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			mu.Lock()
+			starts = append(starts, time.Now())
+			mu.Unlock()
+			return `{"violation": false, "reasoning": "ok", "quoted_code": ""}`, nil
+		},
+	}
+	store := newStore(golangADR)
+	cfg := &config.Config{
+		LLM:      config.LLMConfig{SystemPrompt: "Check."},
+		Analysis: config.Analysis{ExcludePatterns: []string{}, MaxConcurrency: 4, ConcurrencyRampMs: 150},
+	}
+	content := &MockContentProvider{Files: map[string]string{"a.go": "package a", "b.go": "package b", "c.go": "package c", "d.go": "package d"}}
+	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+	engine.Cache = nil
+
+	if _, err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	if len(starts) != 4 {
+		t.Fatalf("expected 4 LLM calls, got %d", len(starts))
+	}
+	// The fourth file may start only after the full 150ms ramp.
+	if spread := starts[3].Sub(starts[0]); spread < 140*time.Millisecond {
+		t.Errorf("expected calls spread over the 150ms ramp, got %v", spread)
+	}
+}
+
+func TestRun_StrictnessSelectsSystemPrompt(t *testing.T) {
+	var capturedSystemPrompt string
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			capturedSystemPrompt = system
+			return `{"violation": false, "reasoning": "none", "quoted_code": ""}`, nil
+		},
+	}
+
+	store := newStore(index.ADR{ID: "0001", Title: "Test ADR", Status: "Accepted", Content: "Test content"})
+
+	cfg := &config.Config{
+		Analysis: config.Analysis{ExcludePatterns: []string{}, Strictness: llm.StrictnessStrict},
+	}
+	content := &MockContentProvider{Files: map[string]string{"test.go": "package test"}}
+
+	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+	engine.Cache = nil
+	if _, err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if capturedSystemPrompt != llm.StrictSystemPrompt {
+		t.Errorf("expected strict system prompt, got %q", capturedSystemPrompt)
+	}
+
+	cfg.Analysis.Strictness = "paranoid"
+	if _, err := engine.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "strictness") {
+		t.Errorf("expected strictness error, got %v", err)
+	}
+}
+
+func TestRun_ReturnsSummary(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import os"}`, nil
+		},
+	}
+
+	store := newStore(golangADR)
+
+	cfg := &config.Config{
+		Analysis: config.Analysis{ExcludePatterns: []string{"vendor/**"}},
+	}
+	content := &MockContentProvider{Files: map[string]string{
+		"service.py":    "import os",
+		"vendor/lib.go": "package lib",
+	}}
+
+	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+	engine.Cache = nil
+	summary, err := engine.Run(context.Background())
+
+	if !errors.Is(err, analysis.ErrDriftDetected) {
+		t.Fatalf("expected drift error, got %v", err)
+	}
+	if summary == nil {
+		t.Fatal("expected a summary alongside the drift error")
+	}
+	if summary.FilesScanned != 1 || summary.FilesSkipped != 1 || summary.Violations != 1 || summary.LLMCalls != 1 || summary.CacheHits != 0 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if summary.CacheHitRate() != 0 {
+		t.Errorf("expected cache hit rate 0, got %v", summary.CacheHitRate())
+	}
+}
+
+func TestSubtreeProvider_FiltersToRoot(t *testing.T) {
+	inner := &MockContentProvider{Files: map[string]string{
+		"internal/cli/cli.go":      "",
+		"internal/clientx/x.go":    "",
+		"internal/cli/sub/deep.go": "",
+		"main.go":                  "",
+	}}
+	p := &analysis.SubtreeProvider{ContentProvider: inner, Root: "internal/cli"}
+
+	files, err := p.GetFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	want := []string{"internal/cli/cli.go", "internal/cli/sub/deep.go"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("GetFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestRun_MaxViolationsCapsOutput(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import os"}`, nil
+		},
+	}
+
+	store := newStore(golangADR)
+
+	cfg := &config.Config{Analysis: config.Analysis{ExcludePatterns: []string{}}}
+	files := map[string]string{"a.py": "import os", "b.py": "import os", "c.py": "import os"}
+	engine := analysis.NewEngine(cfg, store, provider, &MockContentProvider{Files: files}, false, false)
+	engine.Cache = nil
+	engine.MaxViolations = 1
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	summary, runErr := engine.Run(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	if summary == nil || summary.Violations != 3 {
+		t.Fatalf("expected all 3 violations to be counted, got %+v (err %v)", summary, runErr)
+	}
+	if n := strings.Count(string(out), "[VIOLATION]"); n != 1 {
+		t.Errorf("expected 1 printed violation, got %d:\n%s", n, out)
+	}
+	if !strings.Contains(string(out), "... and 2 more violations") {
+		t.Errorf("expected a hidden-violations note, got:\n%s", out)
+	}
+}
+
+func TestRun_MaxViolationsSortedKeepsFirstFiles(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			// a.py finishes last, so a completion-order cap would hide it.
+			if strings.Contains(user, "import a") {
+				time.Sleep(50 * time.Millisecond)
+				return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import a"}`, nil
+			}
+			return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import other"}`, nil
+		},
+	}
+
+	store := newStore(golangADR)
+
+	cfg := &config.Config{Analysis: config.Analysis{ExcludePatterns: []string{}}}
+	files := map[string]string{"a.py": "import a", "b.py": "import other", "c.py": "import other"}
+	engine := analysis.NewEngine(cfg, store, provider, &MockContentProvider{Files: files}, false, false)
+	engine.Cache = nil
+	engine.SortOutput = true
+	engine.MaxViolations = 1
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	_, _ = engine.Run(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	if n := strings.Count(string(out), "[VIOLATION]"); n != 1 {
+		t.Errorf("expected 1 printed violation, got %d:\n%s", n, out)
+	}
+	if !strings.Contains(string(out), "Code: import a") {
+		t.Errorf("expected the violation in the first file by path to be shown, got:\n%s", out)
+	}
+	if strings.Contains(string(out), "\x00") {
+		t.Errorf("expected block markers to be removed, got %q", out)
+	}
+}
+
+func TestRun_ExplainPassPrintsDecisionTrail(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": false, "reasoning": "The service is written in Go.", "quoted_code": ""}`, nil
+		},
+	}
+
+	store := newStore(golangADR, index.ADR{ID: "0002", Title: "Frontend in TypeScript", Status: "Accepted", Content: "Use TypeScript.", Scope: index.StringList{"web/**"}})
+
+	cfg := &config.Config{Analysis: config.Analysis{ExcludePatterns: []string{}}}
+	engine := analysis.NewEngine(cfg, store, provider, &MockContentProvider{Files: map[string]string{"main.go": "package main"}}, false, false)
+	engine.Cache = nil
+	engine.ExplainPass = true
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	_, runErr := engine.Run(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+
+	if runErr != nil {
+		t.Fatalf("unexpected error: %v", runErr)
+	}
+	for _, want := range []string{
+		"Skipping ADR Frontend in TypeScript",
+		"outside its scope",
+		"[PASS] Use Golang",
+		"The service is written in Go.",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRun_CommitMessageUsesScopedADRsOnly(t *testing.T) {
+	var checked []string
+	var mu sync.Mutex
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			mu.Lock()
+			checked = append(checked, user)
+			mu.Unlock()
+			return `{"violation": true, "reasoning": "No ticket referenced.", "quoted_code": "Add users table"}`, nil
+		},
+	}
+
+	provider.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) { return unitEmbedding(), nil }
+	// The scoped ADR is less similar than every generic one, so it must not
+	// lose its place among the top matches to ADRs that never apply.
+	scoped := unitEmbedding()
+	scoped[1] = 1.0
+	store := newStore(
+		golangADR,
+		index.ADR{ID: "0002", Title: "Reference tickets", Status: "Accepted", Content: "Migration commits must reference a ticket.", Scope: index.StringList{analysis.CommitMessagePath}, Embedding: scoped},
+		index.ADR{ID: "0003", Title: "Use gRPC", Status: "Accepted", Content: "Services talk gRPC."},
+		index.ADR{ID: "0004", Title: "Use Postgres", Status: "Accepted", Content: "Store data in Postgres."},
+		index.ADR{ID: "0005", Title: "Structured logging", Status: "Accepted", Content: "Log with slog."},
+	)
+
+	msgFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	if err := os.WriteFile(msgFile, []byte("Add users table\n# Please enter the commit message\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Analysis: config.Analysis{ExcludePatterns: []string{}}}
+	engine := analysis.NewEngine(cfg, store, provider, &analysis.CommitMessageProvider{Path: msgFile}, false, false)
+	engine.Cache = nil
+	engine.ScopedOnly = true
+	_, err := engine.Run(context.Background())
+
+	if !errors.Is(err, analysis.ErrDriftDetected) {
+		t.Fatalf("expected drift from the commit message ADR, got %v", err)
+	}
+	if len(checked) != 1 {
+		t.Fatalf("expected only the scoped ADR to be checked, got %d LLM calls", len(checked))
+	}
+	if !strings.Contains(checked[0], "Add users table") || strings.Contains(checked[0], "Please enter") {
+		t.Errorf("expected the message without comment lines, got:\n%s", checked[0])
+	}
+}
+
+func TestRun_TagsRestrictADRs(t *testing.T) {
+	var mu sync.Mutex
+	var checked []string
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			mu.Lock()
+			checked = append(checked, user)
+			mu.Unlock()
+			return `{"violation": false, "reasoning": "ok", "quoted_code": ""}`, nil
+		},
+	}
+	golang := golangADR
+	golang.ID = "0003"
+	store := newStore(
+		index.ADR{ID: "0001", Title: "No Secrets in Logs", Status: "Accepted", Content: "Never log secrets.", Tags: []string{"security", "pci"}},
+		index.ADR{ID: "0002", Title: "Short Functions", Status: "Accepted", Content: "Keep functions short.", Tags: []string{"style"}},
+		golang,
+	)
+	cfg := &config.Config{
+		LLM:      config.LLMConfig{SystemPrompt: "Check."},
+		Analysis: config.Analysis{ExcludePatterns: []string{}},
+	}
+	content := &MockContentProvider{Files: map[string]string{"main.go": "package main"}}
+	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+	engine.Cache = nil
+	engine.Tags = []string{"Security"}
+
+	if _, err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(checked) != 1 {
+		t.Fatalf("expected only the security ADR to be checked, got %d LLM calls", len(checked))
+	}
+	if !strings.Contains(checked[0], "Never log secrets.") {
+		t.Errorf("expected the security ADR in the prompt, got:\n%s", checked[0])
+	}
+}
+
+func TestEngine_Select(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import os"}`, nil
+		},
+	}
+	cfg := &config.Config{
+		LLM:      config.LLMConfig{SystemPrompt: "Check."},
+		Analysis: config.Analysis{ExcludePatterns: []string{}},
+	}
+	content := &MockContentProvider{Files: map[string]string{"tools/run.py": "#!/usr/bin/env python\nimport os"}}
+	engine := analysis.NewEngine(cfg, nil, provider, content, false, false)
+	engine.Cache = nil
+	// The ADR is scoped away from the file; --select checks it anyway.
+	adr := &index.ADR{ID: "0001", Title: "Use Golang", Content: "All services must be Go.", Scope: index.StringList{"cmd/**"}, RelPath: "0001-use-go.md"}
+
+	var out strings.Builder
+	res, err := engine.Select(context.Background(), adr, "tools/run.py", &out)
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if !res.Violation {
+		t.Errorf("expected the violation to be returned, got %+v", res)
+	}
+	for _, want := range []string{
+		"=== System prompt ===\nCheck.",
+		"All services must be Go.",
+		"=== Raw response ===\n{\"violation\": true",
+		`"reasoning": "Python is not allowed."`,
+		"Quoted code is at tools/run.py:2",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestAudit_ResumesAndSkipsUnchangedFiles(t *testing.T) {
+	var chatCalls atomic.Int64
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			chatCalls.Add(1)
+			if strings.Contains(user, "import os") {
+				return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import os"}`, nil
+			}
+			return `{"violation": false, "reasoning": "Go code."}`, nil
+		},
+	}
+
+	store := newStore(golangADR)
+
+	content := &MockContentProvider{Files: map[string]string{
+		"service.py": "import os",
+		"main.go":    "package main",
+	}}
+	statePath := filepath.Join(t.TempDir(), "audit-state.json")
+
+	engine := analysis.NewEngine(&config.Config{}, store, provider, content, false, false)
+	engine.Cache = nil
+	state, err := engine.Audit(context.Background(), statePath)
+	if err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+	if len(state.Files) != 2 || state.Violations() != 1 || chatCalls.Load() != 2 {
+		t.Fatalf("unexpected first audit: %d files, %d violations, %d chat calls", len(state.Files), state.Violations(), chatCalls.Load())
+	}
+
+	// A second run only re-analyzes the file whose content changed.
+	content.Files["main.go"] = "package main\n\nfunc main() {}"
+	chatCalls.Store(0)
+	state, err = engine.Audit(context.Background(), statePath)
+	if err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+	if chatCalls.Load() != 1 {
+		t.Errorf("expected only the changed file to be re-analyzed, got %d chat calls", chatCalls.Load())
+	}
+	if state.Violations() != 1 {
+		t.Errorf("expected the earlier violation to be kept, got %d", state.Violations())
+	}
+
+	var report strings.Builder
+	if err := state.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report.String(), "service.py") || !strings.Contains(report.String(), "[VIOLATION] Use Golang") {
+		t.Errorf("expected report to list the violation, got:\n%s", report.String())
+	}
+
+	// Saved verdicts are stale once the ADRs or the chat model change.
+	for _, change := range []func(){
+		func() { engine.IndexHash = "edited-adrs" },
+		func() { engine.Config.LLM.Model = "another-model" },
+	} {
+		change()
+		chatCalls.Store(0)
+		if _, err := engine.Audit(context.Background(), statePath); err != nil {
+			t.Fatalf("Audit failed: %v", err)
+		}
+		if chatCalls.Load() != 2 {
+			t.Errorf("expected every file to be re-audited, got %d chat calls", chatCalls.Load())
+		}
+	}
+}
+
+func TestRun_JSONLEmitsOneRecordPerViolation(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": true, "reasoning": "Python is not allowed.", "quoted_code": "import os"}`, nil
+		},
+	}
+
+	store := newStore(golangADR)
+
+	files := map[string]string{"a.py": "import os", "b.py": "import os", "c.py": "import os"}
+	engine := analysis.NewEngine(&config.Config{}, store, provider, &MockContentProvider{Files: files}, false, false)
+	engine.Cache = nil
+	var out strings.Builder
+	engine.JSONL = &out
+
+	if _, err := engine.Run(context.Background()); !errors.Is(err, analysis.ErrDriftDetected) {
+		t.Fatalf("expected drift error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got %d:\n%s", len(lines), out.String())
+	}
+	seen := map[string]bool{}
+	for _, line := range lines {
+		var rec analysis.ViolationRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("line is not a JSON record: %q (%v)", line, err)
+		}
+		if rec.ADRID != "0001" || rec.Line != 1 || rec.Code != "import os" {
+			t.Errorf("unexpected record: %+v", rec)
+		}
+		seen[rec.File] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected one record per file, got %v", seen)
+	}
+}
+
+func TestRun_ReportsUncoveredFiles(t *testing.T) {
+	provider := &llm.MockProvider{
+		EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			v := make([]float32, 4)
+			if strings.Contains(text, "sql") {
+				v[0] = 1
+			} else {
+				v[1] = 1
+			}
+			return v, nil
+		},
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": false, "reasoning": "ok", "quoted_code": ""}`, nil
+		},
+	}
+	store := index.NewLocalStore(1)
+	store.ADRs = []index.ADR{{ID: "0001", Title: "No raw SQL", Status: "Accepted", Content: "Use the query builder.", Embedding: []float32{1, 0, 0, 0}}}
+	cfg := &config.Config{
+		LLM:         config.LLMConfig{SystemPrompt: "Check."},
+		VectorStore: config.VectorStore{SimilarityThreshold: 0.5},
+		Analysis:    config.Analysis{ExcludePatterns: []string{}},
+	}
+	content := &MockContentProvider{Files: map[string]string{
+		"db/users.go": "package db // sql",
+		"ui/view.go":  "package ui",
+		"ui/form.go":  "package ui",
+	}}
+	engine := analysis.NewEngine(cfg, store, provider, content, false, false)
+	engine.Cache = nil
+
+	summary, err := engine.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := strings.Join(summary.Uncovered, ","); got != "ui/form.go,ui/view.go" {
+		t.Errorf("expected the ui files to be uncovered, got %q", got)
+	}
+}
+
+func TestRun_ExplainCache(t *testing.T) {
+	provider := &llm.MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return `{"violation": false, "reasoning": "ok", "quoted_code": ""}`, nil
+		},
+	}
+	embedding := make([]float32, 1536)
+	embedding[0] = 1.0
+	store := index.NewLocalStore(1)
+	store.ADRs = []index.ADR{{ID: "0001", Title: "Use Golang", Status: "Accepted", Content: "All services must be Go.", Embedding: embedding}}
+	cfg := &config.Config{
+		LLM:      config.LLMConfig{Model: "gpt-4o", SystemPrompt: "Check."},
+		Analysis: config.Analysis{ExcludePatterns: []string{}},
+	}
+	c, err := cache.NewCacheWithDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() string {
+		engine := analysis.NewEngine(cfg, store, provider, &MockContentProvider{Files: map[string]string{"main.go": "package main"}}, false, false)
+		engine.Cache = c
+		engine.ExplainCache = true
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stdout := os.Stdout
+		os.Stdout = w
+		_, runErr := engine.Run(context.Background())
+		os.Stdout = stdout
+		w.Close()
+		out, _ := io.ReadAll(r)
+		if runErr != nil {
+			t.Fatalf("unexpected error: %v", runErr)
+		}
+		return string(out)
+	}
+
+	first, second := run(), run()
+	if !strings.Contains(first, "Cache miss for ADR 0001 (Use Golang)") {
+		t.Errorf("expected a miss on the first run, got:\n%s", first)
+	}
+	if !strings.Contains(second, "Cache hit for ADR 0001 (Use Golang)") {
+		t.Errorf("expected a hit on the second run, got:\n%s", second)
+	}
+	for _, want := range []string{"model:           gpt-4o", "file content:    sha256:", "(12 bytes)", "system prompt:", "prompt template:"} {
+		if !strings.Contains(second, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, second)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package analysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tgenz1213/archguard/internal/llm"
+)
+
+// AuditStateFile is where `archguard audit` checkpoints its progress, relative
+// to the state directory (see config.StatePath).
+const AuditStateFile = "audit-state.json"
+
+// AuditState is the checkpoint of a resumable audit: every file analyzed so
+// far, keyed by path.
+type AuditState struct {
+	StartedAt time.Time            `json:"started_at"`
+	Files     map[string]AuditFile `json:"files"`
+
+	// The ADR index, chat model, and prompts the saved verdicts were reached
+	// with. When any of them changes the verdicts are stale and every file is
+	// audited again.
+	IndexHash  string `json:"index_hash,omitempty"`
+	Model      string `json:"model,omitempty"`
+	PromptHash string `json:"prompt_hash,omitempty"`
+}
+
+// AuditFile records the outcome of analyzing one file during an audit.
+type AuditFile struct {
+	Hash       string `json:"hash"` // SHA-256 of the content that was analyzed
+	Violations int    `json:"violations"`
+	Output     string `json:"output,omitempty"` // Violation details, kept for the report
+}
+
+// LoadAuditState reads the checkpoint at path, or returns an empty state when
+// none exists.
+func LoadAuditState(path string) (*AuditState, error) {
+	state := &AuditState{StartedAt: time.Now(), Files: make(map[string]AuditFile)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse audit state %s: %w", path, err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]AuditFile)
+	}
+	return state, nil
+}
+
+// Save writes the checkpoint atomically, so an interrupted audit never leaves
+// a truncated state file behind.
+func (s *AuditState) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Violations returns the total number of violations recorded in the state.
+func (s *AuditState) Violations() int {
+	total := 0
+	for _, f := range s.Files {
+		total += f.Violations
+	}
+	return total
+}
+
+// WriteReport writes a plain-text report of every file with violations,
+// sorted by path.
+func (s *AuditState) WriteReport(w io.Writer) error {
+	var paths []string
+	for path, f := range s.Files {
+		if f.Violations > 0 {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ArchGuard audit report (started %s)\n", s.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "%d files analyzed, %d with violations, %d violations\n", len(s.Files), len(paths), s.Violations())
+	for _, path := range paths {
+		fmt.Fprintf(&sb, "\n%s\n%s", path, s.Files[path].Output)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Audit analyzes every file from the ContentProvider one at a time,
+// checkpointing to statePath after each file. Files whose content hash matches
+// the checkpoint are skipped, so an interrupted audit resumes where it
+// stopped. Files that hit a read, embedding, or LLM error are left out of the
+// checkpoint and retried on the next run. The checkpoint is discarded when
+// e.IndexHash, llm.model, or the prompts differ from the run that saved it.
+// When ctx is cancelled the state
+// saved so far is returned along with ctx.Err().
+func (e *Engine) Audit(ctx context.Context, statePath string) (*AuditState, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	files, err := e.Content.GetFiles()
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, file := range files {
+		if !e.shouldExclude(file) {
+			targets = append(targets, file)
+		}
+	}
+
+	state, err := LoadAuditState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	// Forget files that were deleted or excluded since the last run.
+	current := make(map[string]bool, len(targets))
+	for _, file := range targets {
+		current[file] = true
+	}
+	for path := range state.Files {
+		if !current[path] {
+			delete(state.Files, path)
+		}
+	}
+	prompt := sha256.Sum256([]byte(systemPrompt(e.Config) + llm.ChatPrompt))
+	promptHash := hex.EncodeToString(prompt[:])
+	if len(state.Files) > 0 && (state.IndexHash != e.IndexHash || state.Model != e.Config.LLM.Model || state.PromptHash != promptHash) {
+		fmt.Fprintln(e.out(), "ADRs, llm.model, or the analysis prompt changed since the saved audit; auditing every file again.")
+		state.StartedAt = time.Now()
+		state.Files = make(map[string]AuditFile)
+	}
+	state.IndexHash, state.Model, state.PromptHash = e.IndexHash, e.Config.LLM.Model, promptHash
+
+	for i, file := range targets {
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+
+		content, err := e.Content.GetContent(file)
+		if err != nil {
+			fmt.Fprintf(e.out(), "[%d/%d] %s: read failed, will retry on the next run: %v\n", i+1, len(targets), file, err)
+			continue
+		}
+		sum := sha256.Sum256([]byte(content))
+		hash := hex.EncodeToString(sum[:])
+		if prev, ok := state.Files[file]; ok && prev.Hash == hash {
+			e.Log("Skipping %s: unchanged since it was audited", file)
+			continue
+		}
+
+		var sb strings.Builder
+		failures := e.failures.Load()
+		violations := e.analyzeFile(ctx, file, &sb)
+		fmt.Fprint(e.out(), e.colorize(sb.String()))
+		if e.exhausted.Load() {
+			return state, fmt.Errorf("%w (%s); run audit again to continue", ErrBudgetExhausted, e.budgetLimits())
+		}
+		if e.failures.Load() != failures {
+			fmt.Fprintf(e.out(), "[%d/%d] %s: analysis incomplete, will retry on the next run\n", i+1, len(targets), file)
+			continue
+		}
+
+		entry := AuditFile{Hash: hash, Violations: violations}
+		if violations > 0 {
+			entry.Output = sb.String()
+		}
+		state.Files[file] = entry
+		if err := state.Save(statePath); err != nil {
+			return state, fmt.Errorf("failed to save audit state: %w", err)
+		}
+		fmt.Fprintf(e.out(), "[%d/%d] %s: %d violations\n", i+1, len(targets), file, violations)
+	}
+	// Persist pruned entries even when every remaining file was skipped.
+	if err := state.Save(statePath); err != nil {
+		return state, fmt.Errorf("failed to save audit state: %w", err)
+	}
+	return state, nil
+}
@@ -0,0 +1,42 @@
+package analysis
+
+import "strings"
+
+// ANSI escape codes for Engine.Color.
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[1;31m"
+	ansiYellow = "\033[33m"
+	ansiDim    = "\033[2m"
+)
+
+// colorize highlights the violation, warning, and debug lines of s with ANSI
+// colors when e.Color is set, and returns s unchanged otherwise. Output kept
+// for later, such as the audit report, is stored uncolored.
+func (e *Engine) colorize(s string) string {
+	if !e.Color || s == "" {
+		return s
+	}
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if code := lineColor(line); code != "" {
+			body := strings.TrimSuffix(line, "\n")
+			lines[i] = code + body + ansiReset + line[len(body):]
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// lineColor returns the color for an output line by its marker.
+func lineColor(line string) string {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(trimmed, "[VIOLATION]"), strings.HasPrefix(trimmed, "Error"):
+		return ansiRed
+	case strings.HasPrefix(trimmed, "Warning"), strings.HasPrefix(trimmed, "[WARN-OPEN]"):
+		return ansiYellow
+	case strings.HasPrefix(trimmed, "[DEBUG]"):
+		return ansiDim
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package analysis
+
+import "testing"
+
+func TestColorize(t *testing.T) {
+	out := "Analyzing a.go...\n    [VIOLATION] Use Go [Line 3]\n    Warning: LLM analysis failed\n[DEBUG]   Cache Miss\n"
+
+	e := &Engine{}
+	if got := e.colorize(out); got != out {
+		t.Errorf("expected output unchanged without Color, got %q", got)
+	}
+
+	e.Color = true
+	want := "Analyzing a.go...\n" +
+		ansiRed + "    [VIOLATION] Use Go [Line 3]" + ansiReset + "\n" +
+		ansiYellow + "    Warning: LLM analysis failed" + ansiReset + "\n" +
+		ansiDim + "[DEBUG]   Cache Miss" + ansiReset + "\n"
+	if got := e.colorize(out); got != want {
+		t.Errorf("colorize() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package analysis
+
+import "strings"
+
+// commentStyle describes how a language marks comments.
+type commentStyle struct {
+	line       []string // Prefixes of line comments, e.g. "//"
+	blockStart string   // Opening of block comments, e.g. "/*"; "" if none
+	blockEnd   string
+}
+
+var (
+	cStyle    = commentStyle{line: []string{"//"}, blockStart: "/*", blockEnd: "*/"}
+	hashStyle = commentStyle{line: []string{"#"}}
+)
+
+// commentStyles maps the languages of languageForPath to their comment
+// syntax. Languages not listed are never stripped.
+var commentStyles = map[string]commentStyle{
+	"Go":               cStyle,
+	"JavaScript":       cStyle,
+	"TypeScript":       cStyle,
+	"Java":             cStyle,
+	"Kotlin":           cStyle,
+	"Scala":            cStyle,
+	"C#":               cStyle,
+	"C":                cStyle,
+	"C++":              cStyle,
+	"Rust":             cStyle,
+	"Swift":            cStyle,
+	"Protocol Buffers": cStyle,
+	"PHP":              {line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"},
+	"Terraform":        {line: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/"},
+	"SQL":              {line: []string{"--"}, blockStart: "/*", blockEnd: "*/"},
+	"Python":           hashStyle,
+	"Ruby":             hashStyle,
+	"Shell":            hashStyle,
+	"YAML":             hashStyle,
+	"Dockerfile":       hashStyle,
+}
+
+// stripComments removes comments from the embedding text of file under
+// analysis.strip_comments, so license headers and boilerplate do not dominate
+// retrieval. It is deliberately conservative: only lines that consist
+// entirely of a comment are dropped, so comment markers inside strings and
+// trailing comments after code are left alone. Unified diffs are handled
+// line by line after their +, -, or space prefix. Text in an unrecognized
+// language, or text that would be left empty, is returned unchanged.
+func stripComments(file, text string) string {
+	style, ok := commentStyles[languageForPath(file)]
+	if !ok {
+		return text
+	}
+	diff := false
+	for _, line := range strings.Split(text, "\n") {
+		if hunkHeader.MatchString(line) {
+			diff = true
+			break
+		}
+	}
+
+	var out []string
+	inBlock := false
+	for _, line := range strings.Split(text, "\n") {
+		prefix, body := "", line
+		if diff {
+			if strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff ") ||
+				strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") || line == "" {
+				out = append(out, line)
+				continue
+			}
+			prefix, body = line[:1], line[1:]
+		}
+		trimmed := strings.TrimSpace(body)
+
+		if inBlock {
+			end := strings.Index(trimmed, style.blockEnd)
+			if end == -1 {
+				continue
+			}
+			inBlock = false
+			if rest := strings.TrimSpace(trimmed[end+len(style.blockEnd):]); rest != "" {
+				out = append(out, prefix+rest)
+			}
+			continue
+		}
+		if style.blockStart != "" && strings.HasPrefix(trimmed, style.blockStart) {
+			rest := trimmed[len(style.blockStart):]
+			end := strings.Index(rest, style.blockEnd)
+			if end == -1 {
+				inBlock = true
+				continue
+			}
+			if rest = strings.TrimSpace(rest[end+len(style.blockEnd):]); rest != "" {
+				out = append(out, prefix+rest)
+			}
+			continue
+		}
+		if isLineComment(trimmed, style.line) {
+			continue
+		}
+		out = append(out, line)
+	}
+
+	stripped := strings.Join(out, "\n")
+	if strings.TrimSpace(stripped) == "" {
+		return text
+	}
+	return stripped
+}
+
+func isLineComment(trimmed string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(trimmed, p) {
+			return true
+		}
+	}
+	return false
+}
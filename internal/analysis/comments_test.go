@@ -0,0 +1,53 @@
+package analysis
+
+import "testing"
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name, file, in, want string
+	}{
+		{
+			"go license header and line comments",
+			"main.go",
+			"/*\n * Copyright 2024 Example Corp.\n * Licensed under Apache 2.0.\n */\npackage main\n\n// main starts the server.\nfunc main() {\n\turl := \"http://localhost\" // trailing comments stay\n}\n",
+			"package main\n\nfunc main() {\n\turl := \"http://localhost\" // trailing comments stay\n}\n",
+		},
+		{
+			"python hash comments",
+			"app.py",
+			"#!/usr/bin/env python\n# Copyright Example\nimport os\nx = \"#not a comment\"\n",
+			"import os\nx = \"#not a comment\"\n",
+		},
+		{
+			"code after a block comment is kept",
+			"query.sql",
+			"/* header */ SELECT 1;\n-- note\nSELECT 2;",
+			"SELECT 1;\nSELECT 2;",
+		},
+		{
+			"unified diff",
+			"main.go",
+			"@@ -1,3 +1,4 @@\n // Package main.\n package main\n+// new helper\n+func helper() {}\n",
+			"@@ -1,3 +1,4 @@\n package main\n+func helper() {}\n",
+		},
+		{
+			"unknown language is untouched",
+			"notes.txt",
+			"// not code\n",
+			"// not code\n",
+		},
+		{
+			"all-comment file is untouched",
+			"doc.go",
+			"// Package doc is documentation only.\n",
+			"// Package doc is documentation only.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripComments(tt.file, tt.in); got != tt.want {
+				t.Errorf("stripComments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
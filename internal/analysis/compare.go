@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Fingerprint identifies a violation across runs by ADR, file, and quoted
+// code. Line numbers and reasoning are left out because they shift with
+// unrelated edits and between model responses.
+func (r ViolationRecord) Fingerprint() string {
+	code := strings.Join(strings.Fields(r.Code), " ")
+	return r.ADRID + "\x00" + r.File + "\x00" + code
+}
+
+// ReadViolationRecords parses a report written by `check --format jsonl`, one
+// record per line. A JSON array of records and a saved LastRun are accepted as
+// well.
+func ReadViolationRecords(r io.Reader) ([]ViolationRecord, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var records []ViolationRecord
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	}
+	// A single JSONL record has no "violations" key, so it falls through.
+	var run struct {
+		Violations *[]ViolationRecord `json:"violations"`
+	}
+	if err := json.Unmarshal(trimmed, &run); err == nil && run.Violations != nil {
+		return *run.Violations, nil
+	}
+
+	var records []ViolationRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec ViolationRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// ReportDiff is the result of comparing two reports.
+type ReportDiff struct {
+	Added     []ViolationRecord // In the new report only
+	Removed   []ViolationRecord // In the old report only, i.e. fixed
+	Unchanged []ViolationRecord // In both, as recorded in the new report
+}
+
+// CompareReports matches violations by Fingerprint. Duplicate fingerprints
+// are matched one for one, so a second copy of an existing violation counts
+// as added.
+func CompareReports(old, current []ViolationRecord) ReportDiff {
+	remaining := make(map[string][]ViolationRecord)
+	for _, rec := range old {
+		fp := rec.Fingerprint()
+		remaining[fp] = append(remaining[fp], rec)
+	}
+
+	var diff ReportDiff
+	for _, rec := range current {
+		fp := rec.Fingerprint()
+		if len(remaining[fp]) > 0 {
+			remaining[fp] = remaining[fp][1:]
+			diff.Unchanged = append(diff.Unchanged, rec)
+		} else {
+			diff.Added = append(diff.Added, rec)
+		}
+	}
+	for _, rec := range old {
+		fp := rec.Fingerprint()
+		if len(remaining[fp]) > 0 {
+			remaining[fp] = remaining[fp][1:]
+			diff.Removed = append(diff.Removed, rec)
+		}
+	}
+	return diff
+}
+
+// MergeViolationRecords adds records to the baseline report at path, creating
+// it if needed, and rewrites it as JSONL. Records already in the report, by
+// Fingerprint, are not added twice. It returns how many records were added.
+func MergeViolationRecords(path string, records []ViolationRecord) (int, error) {
+	var existing []ViolationRecord
+	if f, err := os.Open(path); err == nil {
+		existing, err = ReadViolationRecords(f)
+		f.Close()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, rec := range existing {
+		seen[rec.Fingerprint()] = true
+	}
+	merged := existing
+	for _, rec := range records {
+		if seen[rec.Fingerprint()] {
+			continue
+		}
+		seen[rec.Fingerprint()] = true
+		rec.Baseline = false
+		merged = append(merged, rec)
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range merged {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return 0, err
+	}
+	return len(merged) - len(existing), nil
+}
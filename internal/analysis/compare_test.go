@@ -0,0 +1,38 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareReports(t *testing.T) {
+	old, err := ReadViolationRecords(strings.NewReader(`
+{"file":"a.go","line":3,"adr_id":"0001","adr_title":"Use Go","reasoning":"r1","code":"import  os"}
+{"file":"b.go","line":9,"adr_id":"0002","adr_title":"No ORM","reasoning":"r2","code":"gorm.Open()"}
+`))
+	if err != nil {
+		t.Fatalf("ReadViolationRecords failed: %v", err)
+	}
+	current, err := ReadViolationRecords(strings.NewReader(`[
+		{"file":"a.go","line":7,"adr_id":"0001","adr_title":"Use Go","reasoning":"reworded","code":"import os"},
+		{"file":"c.go","line":1,"adr_id":"0002","adr_title":"No ORM","reasoning":"r3","code":"gorm.Open()"}
+	]`))
+	if err != nil {
+		t.Fatalf("ReadViolationRecords failed: %v", err)
+	}
+
+	diff := CompareReports(old, current)
+	if len(diff.Unchanged) != 1 || diff.Unchanged[0].File != "a.go" || diff.Unchanged[0].Line != 7 {
+		t.Errorf("expected a.go to be unchanged despite moved line and whitespace, got %+v", diff.Unchanged)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].File != "c.go" {
+		t.Errorf("expected c.go to be added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].File != "b.go" {
+		t.Errorf("expected b.go to be removed, got %+v", diff.Removed)
+	}
+
+	if _, err := ReadViolationRecords(strings.NewReader("{\"file\":\"a.go\"}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected a parse error naming line 2, got %v", err)
+	}
+}
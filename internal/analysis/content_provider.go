@@ -1,7 +1,12 @@
 package analysis
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/tgenz1213/archguard/internal/git"
 )
@@ -13,14 +18,52 @@ type ContentProvider interface {
 	GetDiff(path string) (string, error)
 }
 
-// UncommittedProvider scans files with worktree changes.
-type UncommittedProvider struct{}
+// DiffOptions configures how a provider reads diffs from git. Providers that
+// diff against git embed it; see SetDiffContextLines.
+type DiffOptions struct {
+	ContextLines int // Unified diff context; 0 uses git.DefaultDiffContextLines
+}
 
-func (p *UncommittedProvider) GetFiles() ([]string, error) {
-	return git.GetUncommittedFiles()
+func (o *DiffOptions) setContextLines(n int) { o.ContextLines = n }
+
+// SetDiffContextLines applies analysis.diff_context_lines to p if it reads
+// diffs from git.
+func SetDiffContextLines(p ContentProvider, n int) {
+	if o, ok := p.(interface{ setContextLines(int) }); ok {
+		o.setContextLines(n)
+	}
 }
 
-func (p *UncommittedProvider) GetContent(path string) (string, error) {
+// ErrExternalSymlink is returned when reading a symlink that resolves outside
+// the repository and analysis.follow_external_symlinks is not set.
+var ErrExternalSymlink = errors.New("symlink points outside the repository")
+
+// ReadOptions configures how providers read files from the worktree.
+// Providers that read the worktree embed it; see SetFollowExternalSymlinks.
+type ReadOptions struct {
+	FollowExternalSymlinks bool // Read symlinks that resolve outside the repository root
+}
+
+func (o *ReadOptions) setFollowExternalSymlinks(follow bool) { o.FollowExternalSymlinks = follow }
+
+// SetFollowExternalSymlinks applies analysis.follow_external_symlinks to p if
+// it reads the worktree.
+func SetFollowExternalSymlinks(p ContentProvider, follow bool) {
+	if o, ok := p.(interface{ setFollowExternalSymlinks(bool) }); ok {
+		o.setFollowExternalSymlinks(follow)
+	}
+}
+
+// readFile reads a repository-relative path. Symlinks are followed only while
+// their target stays inside the repository root (the working directory),
+// unless FollowExternalSymlinks is set, so a link such as config ->
+// /etc/secrets is never sent to the LLM by accident.
+func (o *ReadOptions) readFile(path string) (string, error) {
+	if !o.FollowExternalSymlinks {
+		if err := checkSymlinkInRepo(path); err != nil {
+			return "", err
+		}
+	}
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return "", err
@@ -28,12 +71,61 @@ func (p *UncommittedProvider) GetContent(path string) (string, error) {
 	return string(b), nil
 }
 
+// checkSymlinkInRepo returns ErrExternalSymlink if path, or a directory on
+// the way to it, is a symlink resolving outside the working directory.
+func checkSymlinkInRepo(path string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// Missing files are reported by the read itself.
+		return nil
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		root = r
+	}
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(root, resolved)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %s resolves to %s", ErrExternalSymlink, path, resolved)
+	}
+	return nil
+}
+
+// UncommittedProvider scans files with worktree changes, plus untracked files
+// unless SkipUntracked is set.
+type UncommittedProvider struct {
+	DiffOptions
+	ReadOptions
+	SkipUntracked bool
+}
+
+func (p *UncommittedProvider) GetFiles() ([]string, error) {
+	files, err := git.GetUncommittedFiles()
+	if err != nil || p.SkipUntracked {
+		return files, err
+	}
+	untracked, err := git.GetUntrackedFiles()
+	if err != nil {
+		return nil, err
+	}
+	return append(files, untracked...), nil
+}
+
+func (p *UncommittedProvider) GetContent(path string) (string, error) {
+	return p.readFile(path)
+}
+
 func (p *UncommittedProvider) GetDiff(path string) (string, error) {
-	return git.GetWorktreeDiff(path)
+	return git.GetWorktreeDiff(path, p.ContextLines)
 }
 
 // StagedProvider scans files currently in the git index.
-type StagedProvider struct{}
+type StagedProvider struct{ DiffOptions }
 
 func (p *StagedProvider) GetFiles() ([]string, error) {
 	return git.GetStagedFiles()
@@ -44,43 +136,263 @@ func (p *StagedProvider) GetContent(path string) (string, error) {
 }
 
 func (p *StagedProvider) GetDiff(path string) (string, error) {
-	return git.GetStagedDiff(path)
+	return git.GetStagedDiff(path, p.ContextLines)
+}
+
+// WorkingProvider scans files with staged or unstaged changes, reading their
+// current worktree content so both kinds of edits are analyzed together.
+type WorkingProvider struct {
+	DiffOptions
+	ReadOptions
+}
+
+func (p *WorkingProvider) GetFiles() ([]string, error) {
+	staged, err := git.GetStagedFiles()
+	if err != nil {
+		return nil, err
+	}
+	unstaged, err := git.GetUncommittedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// A file edited both before and after `git add` appears in both lists.
+	seen := make(map[string]bool, len(staged))
+	var files []string
+	for _, f := range append(staged, unstaged...) {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func (p *WorkingProvider) GetContent(path string) (string, error) {
+	return p.readFile(path)
+}
+
+func (p *WorkingProvider) GetDiff(path string) (string, error) {
+	return git.GetWorkingDiff(path, p.ContextLines)
 }
 
 // AllProvider scans all tracked files in the repository.
-type AllProvider struct{}
+type AllProvider struct {
+	DiffOptions
+	ReadOptions
+}
 
 func (p *AllProvider) GetFiles() ([]string, error) {
 	return git.GetAllTrackedFiles()
 }
 
 func (p *AllProvider) GetContent(path string) (string, error) {
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
-	return string(b), nil
+	return p.readFile(path)
 }
 
 func (p *AllProvider) GetDiff(path string) (string, error) {
-	return git.GetWorktreeDiff(path)
+	return git.GetWorktreeDiff(path, p.ContextLines)
 }
 
 // SingleFileProvider scans a specific file path from the worktree.
-type SingleFileProvider struct{ Path string }
+type SingleFileProvider struct {
+	DiffOptions
+	ReadOptions
+	Path string
+}
 
 func (p *SingleFileProvider) GetFiles() ([]string, error) {
 	return []string{p.Path}, nil
 }
 
 func (p *SingleFileProvider) GetContent(path string) (string, error) {
-	b, err := os.ReadFile(path)
+	return p.readFile(path)
+}
+
+func (p *SingleFileProvider) GetDiff(path string) (string, error) {
+	return git.GetWorktreeDiff(path, p.ContextLines)
+}
+
+// GlobProvider scans tracked files matching a glob pattern, e.g. 'internal/handlers/**/*.go'.
+type GlobProvider struct {
+	DiffOptions
+	ReadOptions
+	Pattern string
+}
+
+func (p *GlobProvider) GetFiles() ([]string, error) {
+	tracked, err := git.GetAllTrackedFiles()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range tracked {
+		if matchGlob(p.Pattern, f) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func (p *GlobProvider) GetContent(path string) (string, error) {
+	return p.readFile(path)
+}
+
+func (p *GlobProvider) GetDiff(path string) (string, error) {
+	return git.GetWorktreeDiff(path, p.ContextLines)
+}
+
+// FileSystemProvider scans every regular file under the current directory
+// without consulting git, for source trees with no .git (e.g. an extracted
+// tarball). It has no diffs, and an optional Pattern narrows it like
+// GlobProvider. Exclude patterns still apply in the engine.
+type FileSystemProvider struct {
+	ReadOptions
+	Pattern string
+}
+
+func (p *FileSystemProvider) GetFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == ".git" || name == ".archguard" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			// Symlinks to files are listed and vetted by readFile; symlinks
+			// to directories are not followed.
+			if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+		path = filepath.ToSlash(path)
+		if p.Pattern == "" || matchGlob(p.Pattern, path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (p *FileSystemProvider) GetContent(path string) (string, error) {
+	return p.readFile(path)
+}
+
+func (p *FileSystemProvider) GetDiff(path string) (string, error) {
+	return "", nil
+}
+
+// SubtreeProvider restricts another provider to files under Root, a
+// slash-separated path relative to the repository root.
+type SubtreeProvider struct {
+	ContentProvider
+	Root string
+}
+
+func (p *SubtreeProvider) setContextLines(n int) { SetDiffContextLines(p.ContentProvider, n) }
+
+func (p *SubtreeProvider) setFollowExternalSymlinks(follow bool) {
+	SetFollowExternalSymlinks(p.ContentProvider, follow)
+}
+
+func (p *SubtreeProvider) GetFiles() ([]string, error) {
+	all, err := p.ContentProvider.GetFiles()
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSuffix(p.Root, "/") + "/"
+	var files []string
+	for _, f := range all {
+		if strings.HasPrefix(f, prefix) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// CommitMessagePath is the virtual file name a commit message is analyzed
+// under; ADRs opt in to commit message checks with `scope: COMMIT_MSG`.
+const CommitMessagePath = "COMMIT_MSG"
+
+// CommitMessageProvider presents a commit message file (as passed to a
+// commit-msg hook) as a single virtual file named CommitMessagePath.
+type CommitMessageProvider struct{ Path string }
+
+func (p *CommitMessageProvider) GetFiles() ([]string, error) {
+	return []string{CommitMessagePath}, nil
+}
+
+// GetContent returns the message without the "#" comment lines git adds to
+// the editor template.
+func (p *CommitMessageProvider) GetContent(path string) (string, error) {
+	b, err := os.ReadFile(p.Path)
 	if err != nil {
 		return "", err
 	}
-	return string(b), nil
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n", nil
 }
 
-func (p *SingleFileProvider) GetDiff(path string) (string, error) {
-	return git.GetWorktreeDiff(path)
+func (p *CommitMessageProvider) GetDiff(path string) (string, error) {
+	return "", nil
+}
+
+// IsGlob reports whether a path argument contains glob metacharacters and
+// does not name an existing file, so paths like pages/[id].tsx are still
+// checked as the file they name.
+func IsGlob(path string) bool {
+	if !strings.ContainsAny(path, "*?[{") {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err != nil
+}
+
+// RangeProvider scans files changed between Base and HEAD, e.g. HEAD~5 for
+// the last five commits (--last). Content is read as of HEAD via git objects,
+// so uncommitted edits are not mixed in.
+type RangeProvider struct {
+	DiffOptions
+	Base string
+}
+
+func (p *RangeProvider) GetFiles() ([]string, error) {
+	return git.GetChangedFilesBetween(p.Base, "HEAD")
+}
+
+func (p *RangeProvider) GetContent(path string) (string, error) {
+	return git.GetFileContentAtRev("HEAD", path)
+}
+
+func (p *RangeProvider) GetDiff(path string) (string, error) {
+	return git.GetRangeDiff(p.Base, "HEAD", path, p.ContextLines)
+}
+
+// RevisionProvider scans the tree of a specific commit (read-only audit).
+// Content is read via git objects, so the worktree is never touched.
+type RevisionProvider struct{ Rev string }
+
+func (p *RevisionProvider) GetFiles() ([]string, error) {
+	return git.GetFilesAtRev(p.Rev)
+}
+
+func (p *RevisionProvider) GetContent(path string) (string, error) {
+	return git.GetFileContentAtRev(p.Rev, path)
+}
+
+// GetDiff returns no diff: a historical audit evaluates the full file state.
+func (p *RevisionProvider) GetDiff(path string) (string, error) {
+	return "", nil
 }
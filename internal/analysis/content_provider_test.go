@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFile_ExternalSymlinks(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secrets.env")
+	if err := os.WriteFile(outside, []byte("TOKEN=abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"internal.go":  filepath.Join("src", "main.go"),
+		"external.env": outside,
+		"linked-dir":   filepath.Dir(outside),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	p := &FileSystemProvider{}
+	files, err := p.GetFiles()
+	if err != nil {
+		t.Fatalf("GetFiles failed: %v", err)
+	}
+	if got := strings.Join(files, ","); got != "external.env,internal.go,src/main.go" {
+		t.Errorf("unexpected files: %s", got)
+	}
+
+	if content, err := p.GetContent("internal.go"); err != nil || content != "package main\n" {
+		t.Errorf("expected symlink inside the repository to be read, got %q, %v", content, err)
+	}
+	if _, err := p.GetContent("external.env"); !errors.Is(err, ErrExternalSymlink) {
+		t.Errorf("expected ErrExternalSymlink, got %v", err)
+	}
+	if _, err := p.GetContent("linked-dir/secrets.env"); !errors.Is(err, ErrExternalSymlink) {
+		t.Errorf("expected ErrExternalSymlink through a symlinked directory, got %v", err)
+	}
+
+	SetFollowExternalSymlinks(&SubtreeProvider{ContentProvider: p, Root: "."}, true)
+	if content, err := p.GetContent("external.env"); err != nil || content != "TOKEN=abc\n" {
+		t.Errorf("expected external symlink to be followed when enabled, got %q, %v", content, err)
+	}
+}
+
+func TestRangeProvider(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	commit := func(file, content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", file)
+		git("commit", "-q", "-m", file)
+	}
+
+	git("init", "-q")
+	commit("old.go", "package old\n")
+	commit("a.go", "package a\n")
+	commit("b.go", "package b\n")
+	// Uncommitted edits are not part of the range.
+	if err := os.WriteFile("a.go", []byte("package a // edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &RangeProvider{Base: "HEAD~2"}
+	files, err := p.GetFiles()
+	if err != nil {
+		t.Fatalf("GetFiles failed: %v", err)
+	}
+	if got := strings.Join(files, ","); got != "a.go,b.go" {
+		t.Errorf("expected the files of the last two commits, got %s", got)
+	}
+	if content, err := p.GetContent("a.go"); err != nil || content != "package a\n" {
+		t.Errorf("expected committed content, got %q, %v", content, err)
+	}
+	if diff, err := p.GetDiff("b.go"); err != nil || !strings.Contains(diff, "+package b") {
+		t.Errorf("expected b.go's diff since HEAD~2, got %q, %v", diff, err)
+	}
+}
+
+func TestSetDiffContextLines(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile("a.txt", []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "a")
+	lines[9] = "line 10 edited"
+	if err := os.WriteFile("a.txt", []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "a.txt")
+
+	staged := &StagedProvider{}
+	SetDiffContextLines(staged, 2)
+	diff, err := staged.GetDiff("a.txt")
+	if err != nil {
+		t.Fatalf("GetDiff failed: %v", err)
+	}
+	// Two lines of context on each side of the change, as with git diff -U2.
+	if !strings.Contains(diff, "@@ -8,5 +8,5 @@") || !strings.Contains(diff, "\n line 12\n") || strings.Contains(diff, "\n line 13") {
+		t.Errorf("expected a diff with 2 context lines, got:\n%s", diff)
+	}
+
+	// Providers that never diff against git are left alone.
+	SetDiffContextLines(&CommitMessageProvider{Path: "msg"}, 10)
+}
@@ -0,0 +1,45 @@
+package analysis
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// changedLines returns the new-file line numbers of the lines a unified diff
+// adds or modifies. It returns nil when diff has no hunks, e.g. for a file
+// read in full, so callers can tell "nothing changed" from "no diff".
+func changedLines(diff string) map[int]bool {
+	var lines map[int]bool
+	line := 0
+	for _, l := range strings.Split(diff, "\n") {
+		if m := hunkHeader.FindStringSubmatch(l); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			if lines == nil {
+				lines = make(map[int]bool)
+			}
+			continue
+		}
+		if line == 0 || strings.HasPrefix(l, "-") || strings.HasPrefix(l, "\\") {
+			continue
+		}
+		if strings.HasPrefix(l, "+") {
+			lines[line] = true
+		}
+		line++
+	}
+	return lines
+}
+
+// touchesChangedLine reports whether any of the lines spanned by a quote
+// starting at line start is in changed.
+func touchesChangedLine(changed map[int]bool, start int, quote string) bool {
+	for i := 0; i <= strings.Count(strings.TrimRight(quote, "\n"), "\n"); i++ {
+		if changed[start+i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedLines(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ package main
+-import "log"
++import "fmt"
++import "os"
+ 
+ func main() {}
+@@ -20,2 +21,3 @@ func helper() {
+ 	a := 1
++	b := 2
+ }
+`
+	want := map[int]bool{2: true, 3: true, 22: true}
+	if got := changedLines(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("changedLines() = %v, want %v", got, want)
+	}
+
+	if got := changedLines("package main\n"); got != nil {
+		t.Errorf("expected nil for content without hunks, got %v", got)
+	}
+
+	if !touchesChangedLine(want, 21, "a := 1\nb := 2") {
+		t.Errorf("expected a quote spanning line 22 to touch a changed line")
+	}
+	if touchesChangedLine(want, 5, "func main() {}") {
+		t.Errorf("expected an unchanged line not to match")
+	}
+}
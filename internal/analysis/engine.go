@@ -2,10 +2,20 @@ package analysis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/tgenz1213/archguard/internal/cache"
@@ -17,18 +27,239 @@ import (
 
 // Engine coordinates the analysis of source files against ADRs using LLM providers.
 type Engine struct {
-	Config   *config.Config
-	Store    index.VectorStore
-	Provider llm.Provider
-	Content  ContentProvider
-	Debug    bool
-	CI       bool // CI-safe mode (Warn-Open behavior)
-	Cache    *cache.Cache
+	Config    *config.Config
+	Store     index.VectorStore
+	Provider  llm.Provider
+	Content   ContentProvider
+	Verbosity int  // See the Verbosity* constants; VerbosityDebug matches --debug
+	CI        bool // CI-safe mode (Warn-Open behavior)
+	Cache     *cache.Cache
+
+	// IndexHash identifies the ADR index (VectorStore.CalculateHash). Audit
+	// saves it with its checkpoint so ADR edits invalidate saved verdicts.
+	IndexHash string
+
+	// Ignore holds the patterns from .archguardignore, applied on top of
+	// analysis.exclude_patterns.
+	Ignore *IgnoreList
+
+	// SortOutput buffers per-file output and prints it sorted by path after all
+	// files finish, so logs are stable across runs.
+	SortOutput bool
+
+	// Timings, when non-nil, records per-phase durations and prints them at
+	// the end of Run (--timings).
+	Timings *Timings
+
+	// ExplainPass prints each file's decision trail (--explain-pass): retrieved
+	// ADRs with scores, scope and suppression skips, and the model's reasoning
+	// for passing verdicts, to diagnose expected violations that were missed.
+	ExplainPass bool
+
+	// ChangedLinesOnly drops violations whose quoted code lies entirely on
+	// lines the file's diff did not add or modify (--changed-lines-only).
+	// Files analyzed without a diff are reported in full.
+	ChangedLinesOnly bool
+
+	// ParallelADRs analyzes a file's matched ADRs concurrently instead of one
+	// after another (--parallel-adrs). In-flight LLM calls across all files
+	// stay bounded by analysis.max_concurrency.
+	ParallelADRs bool
+
+	// ExplainCache prints, for each analysis, the inputs hashed into its
+	// cache key and whether it was a hit or a miss (--explain-cache).
+	ExplainCache bool
+
+	// ScopedOnly skips ADRs without a scope, so that generic code ADRs are not
+	// applied to non-code content such as commit messages (--commit-msg).
+	ScopedOnly bool
+
+	// Color highlights violations, warnings, and debug lines in the printed
+	// output with ANSI colors (--color).
+	Color bool
+
+	// Tags restricts analysis to ADRs carrying at least one of these
+	// frontmatter tags (--tags). Empty checks every ADR.
+	Tags []string
+
+	// JSONL, when non-nil, receives one JSON ViolationRecord per line as each
+	// violation is found (--format jsonl), instead of the text block.
+	JSONL io.Writer
+
+	// Out receives progress, violation blocks, and the incomplete-file list.
+	// Nil means os.Stdout; --format jsonl and markdown point it at stderr.
+	Out io.Writer
+
+	// CollectViolations keeps every violation as a ViolationRecord in
+	// RunSummary.Records, for integrations such as --github-pr.
+	CollectViolations bool
+
+	// Baseline lists known violations accepted as existing debt (--baseline).
+	// Violations matching one by Fingerprint are still shown, marked KNOWN,
+	// but do not count toward the violation total or the exit code.
+	Baseline []ViolationRecord
+
+	// MaxViolations caps how many violation blocks are printed (--max-violations).
+	// Every violation is still counted; 0 prints all of them.
+	MaxViolations int
+
+	// SkipFileLimit bypasses the analysis.max_files safeguard (--yes-really).
+	SkipFileLimit bool
+	// Confirm asks the user to approve a run exceeding analysis.max_files.
+	// A nil Confirm means the session is non-interactive and the run is refused.
+	Confirm func(prompt string) bool
+
+	// Per-run counters reported in RunSummary.
+	cacheHits atomic.Int64
+	llmCalls  atomic.Int64
+	printed   atomic.Int64 // violations seen against MaxViolations
+	failures  atomic.Int64 // read, embedding, and LLM errors; Audit retries such files
+	truncated atomic.Int64 // files refused under analysis.on_truncation: error
+	tokens    atomic.Int64 // estimated chat tokens spent against analysis.max_tokens_budget
+	inTokens  atomic.Int64 // the prompt share of tokens, priced for analysis.max_cost_usd
+	outTokens atomic.Int64 // the response share of tokens
+	exhausted atomic.Bool  // set once the token budget refuses an LLM call
+	unscanned atomic.Int64 // files not fully analyzed because the budget ran out
+	jsonlMu   sync.Mutex
+	recordsMu sync.Mutex
+	records   []ViolationRecord
+
+	// llmSlots bounds in-flight LLM calls across files under ParallelADRs.
+	llmSlots chan struct{}
+
+	incompleteMu sync.Mutex
+	incomplete   map[string]string // file -> why it was not fully analyzed
+
+	uncoveredMu sync.Mutex
+	uncovered   []string // files whose retrieval matched no ADR
+
+	known      atomic.Int64 // violations matched against Baseline
+	baselineMu sync.Mutex
+	baseline   map[string]int // unmatched Baseline fingerprints and their counts
+
+	tokenizerOnce sync.Once
+	tokenizer     *tiktoken.Tiktoken
+	tokenizerErr  error
+
+	redactErr error // invalid analysis.redact_patterns, reported by validate
 }
 
+// ViolationRecord is one line of --format jsonl output.
+type ViolationRecord struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	ADRID     string `json:"adr_id"`
+	ADRTitle  string `json:"adr_title"`
+	Reasoning string `json:"reasoning"`
+	Rule      string `json:"rule,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Baseline  bool   `json:"baseline,omitempty"` // Known violation from --baseline
+	// LineApproximate is set when Code was not found verbatim and Line is the
+	// closest match by shared identifiers.
+	LineApproximate bool `json:"line_approximate,omitempty"`
+}
+
+// RunSummary collects the outcome of a Run for the closing report.
+type RunSummary struct {
+	FilesScanned int           // Files analyzed after exclusions
+	FilesSkipped int           // Files dropped by exclude patterns or .archguardignore
+	Violations   int           // Violations reported across all files, excluding known ones
+	Known        int           // Violations matched against Engine.Baseline
+	CacheHits    int           // Analyses served from the cache
+	LLMCalls     int           // Analyses that required an LLM call
+	Duration     time.Duration // Wall-clock time of the run
+
+	// Records lists every violation when Engine.CollectViolations is set,
+	// including known ones, which are marked Baseline; see NewRecords.
+	Records []ViolationRecord
+
+	// Uncovered lists, sorted, the files for which retrieval matched no ADR
+	// above the similarity threshold (check --require-coverage).
+	Uncovered []string
+}
+
+// NewRecords returns the Records that are not known from the baseline.
+func (s *RunSummary) NewRecords() []ViolationRecord {
+	var records []ViolationRecord
+	for _, rec := range s.Records {
+		if !rec.Baseline {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+// CacheHitRate returns the fraction of analyses served from the cache, or 0
+// when no analyses ran.
+func (s *RunSummary) CacheHitRate() float64 {
+	total := s.CacheHits + s.LLMCalls
+	if total == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(total)
+}
+
+// Context strategies for analysis.context_strategy.
+const (
+	// ContextAuto sends full content when it fits in llm.max_tokens and falls
+	// back to the diff (or truncated content) otherwise.
+	ContextAuto = "auto"
+	// ContextDiff sends the diff whenever one is available.
+	ContextDiff = "diff"
+	// ContextFull always sends full (possibly truncated) content.
+	ContextFull = "full"
+	// ContextDiffThenFull analyzes the diff first and re-checks with full
+	// content only for ADRs where the model asked for more context.
+	ContextDiffThenFull = "diff-then-full"
+	// ContextFunctions sends, for a changed Go file, its package clause and
+	// imports plus the declarations enclosing the changed lines. Other files,
+	// and Go files where that is not possible, are handled as ContextAuto.
+	ContextFunctions = "functions"
+)
+
+// Truncation policies for analysis.on_truncation, applied when a file's
+// content exceeds llm.max_tokens and no diff is sent instead.
+const (
+	// TruncationAnalyze silently analyzes the truncated content.
+	TruncationAnalyze = "analyze"
+	// TruncationWarn analyzes the truncated content and says how much was cut.
+	TruncationWarn = "warn"
+	// TruncationError skips the file and fails the run.
+	TruncationError = "error"
+	// TruncationChunk analyzes the whole file in pieces that each fit.
+	TruncationChunk = "chunk"
+)
+
+// Parse failure policies for llm.on_parse_failure, applied when the model's
+// response is empty or not valid JSON.
+const (
+	// ParseFailureRetry retries with backoff and then fails the check.
+	ParseFailureRetry = "retry"
+	// ParseFailureSkip skips the check with a warning without retrying.
+	ParseFailureSkip = "skip"
+	// ParseFailureFail fails the check without retrying.
+	ParseFailureFail = "fail"
+)
+
+// Verbosity levels accumulated by repeating -v on the command line.
+const (
+	VerbosityQuiet  = 0 // violations and errors only
+	VerbosityFiles  = 1 // per-file progress and matched ADR counts
+	VerbosityScores = 2 // adds the ADRs checked per file with similarity scores
+	VerbosityDebug  = 3 // adds context modes, cache activity, and internal logging
+)
+
+// maxADRsPerFile is the number of most similar ADRs each file is checked against.
+const maxADRsPerFile = 3
+
 // ErrDriftDetected identifies analysis results that contain architectural violations.
 var ErrDriftDetected = errors.New("architectural drift detected")
 
+// ErrBudgetExhausted is returned once analysis.max_tokens_budget or
+// analysis.max_cost_usd would be exceeded; no further LLM calls are made in
+// the run.
+var ErrBudgetExhausted = errors.New("token budget exhausted")
+
 // DriftDetectedError reports the number of architectural violations found.
 type DriftDetectedError struct {
 	Count int
@@ -42,38 +273,109 @@ func (e *DriftDetectedError) Is(target error) bool {
 	return target == ErrDriftDetected
 }
 
-// NewEngine initializes a new analysis engine with a local cache.
+// NewEngine initializes a new analysis engine with a local cache, or the
+// shared cache directory configured via cache.dir. content is wrapped in a
+// RedactingProvider when analysis.redact_patterns is set.
 func NewEngine(cfg *config.Config, store index.VectorStore, provider llm.Provider, content ContentProvider, debug bool, ci bool) *Engine {
-	c, _ := cache.NewCache(".")
+	var c *cache.Cache
+	if cfg.Cache.Dir != "" {
+		c, _ = cache.NewCacheWithDir(cfg.Cache.Dir)
+	} else {
+		c, _ = cache.NewCacheWithDir(config.StatePath("cache"))
+	}
+
+	ignore, err := LoadIgnoreFile(IgnoreFileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read %s: %v\n", IgnoreFileName, err)
+	}
+
+	verbosity := VerbosityQuiet
+	if debug {
+		verbosity = VerbosityDebug
+	}
+
+	content, redactErr := NewRedactingProvider(content, cfg.Analysis.RedactPatterns)
 
 	return &Engine{
-		Config:   cfg,
-		Store:    store,
-		Provider: provider,
-		Content:  content,
-		Debug:    debug,
-		CI:       ci,
-		Cache:    c,
+		Config:    cfg,
+		Store:     store,
+		Provider:  provider,
+		Content:   content,
+		Verbosity: verbosity,
+		CI:        ci,
+		Cache:     c,
+		Ignore:    ignore,
+		redactErr: redactErr,
 	}
 }
 
+// verbose reports whether output at the given verbosity level should be shown.
+func (e *Engine) verbose(level int) bool {
+	return e.Verbosity >= level
+}
+
 // Log prints debug information if the engine is in debug mode.
 func (e *Engine) Log(format string, args ...interface{}) {
-	if e.Debug {
-		fmt.Printf("[DEBUG] "+format+"\n", args...)
+	if e.verbose(VerbosityDebug) {
+		fmt.Fprint(e.out(), e.colorize(fmt.Sprintf("[DEBUG] "+format+"\n", args...)))
+	}
+}
+
+// out returns the writer for human-readable output.
+func (e *Engine) out() io.Writer {
+	if e.Out == nil {
+		return os.Stdout
 	}
+	return e.Out
 }
 
 // Info prints standard informational messages.
 func (e *Engine) Info(format string, args ...interface{}) {
-	fmt.Printf(format+"\n", args...)
+	fmt.Fprint(e.out(), e.colorize(fmt.Sprintf(format+"\n", args...)))
 }
 
 // Run executes the analysis pipeline across all files provided by the ContentProvider.
-func (e *Engine) Run(ctx context.Context) error {
+// The summary is non-nil whenever files were analyzed, including when drift is detected.
+func (e *Engine) Run(ctx context.Context) (*RunSummary, error) {
+	start := time.Now()
+
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
 	files, err := e.Content.GetFiles()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var targets []string
+	for _, file := range files {
+		if !e.shouldExclude(file) {
+			targets = append(targets, file)
+		}
+	}
+
+	if err := e.checkFileLimit(len(targets)); err != nil {
+		return nil, err
+	}
+
+	e.cacheHits.Store(0)
+	e.llmCalls.Store(0)
+	e.printed.Store(0)
+	e.truncated.Store(0)
+	e.tokens.Store(0)
+	e.exhausted.Store(false)
+	e.unscanned.Store(0)
+	e.records = nil
+	e.incomplete = nil
+	e.uncovered = nil
+	e.known.Store(0)
+	e.baseline = nil
+	if e.Baseline != nil {
+		e.baseline = make(map[string]int, len(e.Baseline))
+		for _, rec := range e.Baseline {
+			e.baseline[rec.Fingerprint()]++
+		}
 	}
 
 	var (
@@ -88,185 +390,1034 @@ func (e *Engine) Run(ctx context.Context) error {
 
 	var g errgroup.Group
 	g.SetLimit(concurrency)
+	e.llmSlots = nil
+	if e.ParallelADRs {
+		e.llmSlots = make(chan struct{}, concurrency)
+	}
 
-	for _, file := range files {
-		if e.shouldExclude(file) {
-			continue
-		}
+	outputs := make(map[string]string)
+
+	// With analysis.concurrency_ramp_ms, the n-th of the first `concurrency`
+	// files starts no sooner than n steps in, so parallelism grows from 1 and
+	// a cold model server is not hit with every request at once.
+	var rampStep time.Duration
+	if ramp := e.Config.Analysis.ConcurrencyRampMs; ramp > 0 && concurrency > 1 {
+		rampStep = time.Duration(ramp) * time.Millisecond / time.Duration(concurrency-1)
+	}
+	rampStart := time.Now()
 
+	for n, file := range targets {
 		file := file
+		if rampStep > 0 && n > 0 && n < concurrency {
+			select {
+			case <-time.After(time.Until(rampStart.Add(time.Duration(n) * rampStep))):
+			case <-ctx.Done():
+			}
+		}
 		g.Go(func() error {
+			if e.exhausted.Load() {
+				e.unscanned.Add(1)
+				return nil
+			}
 			// buffer output to ensure atomic printing per file
 			var sb strings.Builder
+			localViolations := e.analyzeFile(ctx, file, &sb)
 
-			if e.Debug {
-				fmt.Fprintf(&sb, "Analyzing %s...\n", file)
+			mu.Lock()
+			if e.SortOutput {
+				outputs[file] = sb.String()
+			} else {
+				fmt.Fprint(e.out(), e.colorize(sb.String()))
 			}
+			violations += localViolations
+			mu.Unlock()
+			return nil
+		})
+	}
 
-			content, diffMode, err := e.fetchContext(file)
-			if err != nil {
-				fmt.Fprintf(&sb, "Error reading file %s: %v\n", file, err)
-				mu.Lock()
-				fmt.Print(sb.String())
-				mu.Unlock()
-				return nil
+	_ = g.Wait()
+
+	if e.SortOutput {
+		paths := make([]string, 0, len(outputs))
+		for path := range outputs {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		shown := 0
+		for _, path := range paths {
+			fmt.Fprint(e.out(), e.colorize(capViolations(outputs[path], e.MaxViolations, &shown)))
+		}
+	}
+
+	if e.MaxViolations > 0 && violations > e.MaxViolations {
+		fmt.Fprintf(e.out(), "... and %d more violations (raise --max-violations to see them)\n", violations-e.MaxViolations)
+	}
+
+	// Listed after every violation has been printed, so that errors on some
+	// files never hide what was found in the others.
+	if len(e.incomplete) > 0 {
+		fmt.Fprintf(e.out(), "\n%d file(s) could not be fully analyzed; violations in them may be missing:\n", len(e.incomplete))
+		paths := make([]string, 0, len(e.incomplete))
+		for path := range e.incomplete {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(e.out(), "  %s: %s\n", path, e.incomplete[path])
+		}
+	}
+
+	e.Timings.Print(e.out())
+
+	sort.Slice(e.records, func(i, j int) bool {
+		if e.records[i].File != e.records[j].File {
+			return e.records[i].File < e.records[j].File
+		}
+		return e.records[i].Line < e.records[j].Line
+	})
+	summary := &RunSummary{
+		FilesScanned: len(targets),
+		FilesSkipped: len(files) - len(targets),
+		Violations:   violations,
+		Known:        int(e.known.Load()),
+		CacheHits:    int(e.cacheHits.Load()),
+		LLMCalls:     int(e.llmCalls.Load()),
+		Duration:     time.Since(start),
+		Records:      e.records,
+		Uncovered:    e.uncovered,
+	}
+	sort.Strings(summary.Uncovered)
+
+	if e.exhausted.Load() {
+		scanned := len(targets) - int(e.unscanned.Load())
+		return summary, fmt.Errorf("%w (%s): scanned %d of %d files", ErrBudgetExhausted, e.budgetLimits(), scanned, len(targets))
+	}
+	if n := e.truncated.Load(); n > 0 {
+		return summary, fmt.Errorf("%d file(s) exceed llm.max_tokens and were not analyzed; raise llm.max_tokens or set analysis.on_truncation to chunk", n)
+	}
+	if n := len(e.incomplete); n > 0 {
+		return summary, fmt.Errorf("%d file(s) could not be fully analyzed (%d violations found)", n, violations)
+	}
+	if violations > 0 {
+		return summary, &DriftDetectedError{Count: violations}
+	}
+
+	return summary, nil
+}
+
+// validate rejects invalid analysis settings before any file is analyzed.
+func (e *Engine) validate() error {
+	if e.redactErr != nil {
+		return e.redactErr
+	}
+	for _, cfg := range e.Config.Resolved() {
+		switch contextStrategy(cfg) {
+		case ContextAuto, ContextDiff, ContextFull, ContextDiffThenFull, ContextFunctions:
+		default:
+			return fmt.Errorf("invalid analysis.context_strategy %q (expected auto, diff, full, diff-then-full, or functions)", cfg.Analysis.ContextStrategy)
+		}
+		if _, err := llm.SystemPromptForStrictness(cfg.Analysis.Strictness); err != nil {
+			return err
+		}
+		switch onTruncation(cfg) {
+		case TruncationAnalyze, TruncationWarn, TruncationError, TruncationChunk:
+		default:
+			return fmt.Errorf("invalid analysis.on_truncation %q (expected analyze, warn, error, or chunk)", cfg.Analysis.OnTruncation)
+		}
+		if metric, err := index.ParseMetric(cfg.VectorStore.Metric); err == nil {
+			if err := metric.CheckThreshold(cfg.VectorStore.SimilarityThreshold); err != nil {
+				return err
 			}
+		}
+	}
+	switch e.onParseFailure() {
+	case ParseFailureRetry, ParseFailureSkip, ParseFailureFail:
+	default:
+		return fmt.Errorf("invalid llm.on_parse_failure %q (expected retry, skip, or fail)", e.Config.LLM.OnParseFailure)
+	}
+	if n := e.Config.LLM.MaxRetries; n != nil && *n < 0 {
+		return fmt.Errorf("invalid llm.max_retries %d (expected 0 or more)", *n)
+	}
+	if e.Config.LLM.StreamIdleTimeoutMs < 0 {
+		return fmt.Errorf("invalid llm.stream_idle_timeout_ms %d (expected 0 for the default, or a positive duration)", e.Config.LLM.StreamIdleTimeoutMs)
+	}
+	if e.Config.Analysis.MaxTokensBudget < 0 {
+		return fmt.Errorf("invalid analysis.max_tokens_budget %d (expected 0 to disable, or a positive token count)", e.Config.Analysis.MaxTokensBudget)
+	}
+	if e.Config.LLM.InputCostPerMTok < 0 || e.Config.LLM.OutputCostPerMTok < 0 {
+		return fmt.Errorf("invalid llm.input_cost_per_mtok or llm.output_cost_per_mtok (expected 0 or more)")
+	}
+	if c := e.Config.Analysis.MaxCostUSD; c < 0 {
+		return fmt.Errorf("invalid analysis.max_cost_usd %g (expected 0 to disable, or a positive amount)", c)
+	} else if c > 0 && e.Config.LLM.InputCostPerMTok == 0 && e.Config.LLM.OutputCostPerMTok == 0 {
+		return fmt.Errorf("analysis.max_cost_usd needs llm.input_cost_per_mtok and llm.output_cost_per_mtok to price LLM calls")
+	}
+	if e.Config.Analysis.ConcurrencyRampMs < 0 {
+		return fmt.Errorf("invalid analysis.concurrency_ramp_ms %d (expected 0 or more)", e.Config.Analysis.ConcurrencyRampMs)
+	}
+	if e.Config.Analysis.FocusDiffTokens < 0 {
+		return fmt.Errorf("invalid analysis.focus_diff_tokens %d (expected 0 to disable, or a positive token count)", e.Config.Analysis.FocusDiffTokens)
+	}
+	return nil
+}
 
-			if e.Debug {
-				fmt.Fprintf(&sb, "  Context mode: %s\n", diffMode)
+// analyzeFile runs retrieval and LLM analysis for a single file, writing all
+// output to sb, and returns the number of violations found.
+func (e *Engine) analyzeFile(ctx context.Context, file string, sb *strings.Builder) int {
+	if e.verbose(VerbosityFiles) || e.ExplainPass {
+		fmt.Fprintf(sb, "Analyzing %s...\n", file)
+	}
+
+	stop := e.Timings.Track(PhaseContext)
+	content, diffMode, err := e.fetchContext(file)
+	stop()
+	if errors.Is(err, fs.ErrNotExist) {
+		// Deleted files are listed as changes but leave nothing to analyze.
+		if e.verbose(VerbosityFiles) {
+			fmt.Fprintf(sb, "Skipping %s: file was deleted\n", file)
+		}
+		return 0
+	}
+	if errors.Is(err, ErrExternalSymlink) {
+		fmt.Fprintf(sb, "Skipping %s: %v (set analysis.follow_external_symlinks to analyze it)\n", file, err)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(sb, "Error reading file %s: %v\n", file, err)
+		e.markIncomplete(file, fmt.Sprintf("read failed: %v", err))
+		return 0
+	}
+
+	if e.verbose(VerbosityDebug) {
+		fmt.Fprintf(sb, "  Context mode: %s\n", diffMode)
+	}
+
+	cfg := e.Config.ForPath(file)
+
+	// chunks, when set, replaces content as the code sent for each ADR.
+	var chunks []string
+	var chunkLines []int
+	if diffMode == "truncated" {
+		switch onTruncation(cfg) {
+		case TruncationChunk:
+			full, err := e.Content.GetContent(file)
+			if err == nil {
+				chunks, chunkLines, err = e.chunkContent(cfg, full)
 			}
+			if err != nil {
+				fmt.Fprintf(sb, "Error reading file %s: %v\n", file, err)
+				e.markIncomplete(file, fmt.Sprintf("read failed: %v", err))
+				return 0
+			}
+			if e.verbose(VerbosityFiles) || e.ExplainPass {
+				fmt.Fprintf(sb, "  File exceeds llm.max_tokens; analyzing it in %d chunks\n", len(chunks))
+			}
+		case TruncationError:
+			fmt.Fprintf(sb, "Error: %s exceeds llm.max_tokens and was not analyzed; raise llm.max_tokens or set analysis.on_truncation to chunk\n", file)
+			e.markIncomplete(file, "exceeds llm.max_tokens")
+			e.truncated.Add(1)
+			return 0
+		default:
+			if e.CI {
+				fmt.Fprintf(sb, "  [WARN-OPEN] File %s was truncated for analysis. In CI mode this is treated as a warning (no failure).\n", file)
+				return 0
+			}
+			if onTruncation(cfg) == TruncationWarn {
+				fmt.Fprintf(sb, "  Warning: %s exceeds llm.max_tokens; only its first %d lines were analyzed\n", file, strings.Count(content, "\n"))
+			}
+		}
+	}
 
-			if diffMode == "truncated" && e.CI {
-				fmt.Fprintf(&sb, "  [WARN-OPEN] File %s was truncated for analysis. In CI mode this is treated as a warning (no failure).\n", file)
-				mu.Lock()
-				fmt.Print(sb.String())
-				mu.Unlock()
-				return nil
+	diffForEmbedding, err := e.Content.GetDiff(file)
+	// changed, when set, holds the line numbers violations must touch.
+	var changed map[int]bool
+	if e.ChangedLinesOnly && err == nil {
+		changed = changedLines(diffForEmbedding)
+	}
+	if err != nil || diffForEmbedding == "" {
+		diffForEmbedding = content
+	}
+	if e.Config.Analysis.StripComments {
+		diffForEmbedding = stripComments(file, diffForEmbedding)
+	}
+
+	diffForEmbedding = truncateUTF8(diffForEmbedding, 6000)
+	if e.Config.VectorStore.EmbedFileHeader {
+		diffForEmbedding = fileHeader(file) + diffForEmbedding
+	}
+
+	embedding, err := e.embed(ctx, diffForEmbedding)
+	if err != nil {
+		fmt.Fprintf(sb, "Error generating embedding for %s: %v\n", file, err)
+		e.markIncomplete(file, fmt.Sprintf("embedding failed: %v", err))
+		return 0
+	}
+
+	stop = e.Timings.Track(PhaseSearch)
+	limit := maxADRsPerFile
+	if e.ScopedOnly {
+		// Unscoped ADRs never apply here, so they must not take up the
+		// top-K slots: retrieve every match and keep the closest scoped ones.
+		limit = math.MaxInt32
+	}
+	hits := e.Store.Search(embedding, cfg.VectorStore.SimilarityThreshold, limit)
+	stop()
+	if e.ScopedOnly {
+		hits = e.scopedHits(hits, sb)
+	}
+	if len(hits) == 0 {
+		e.uncoveredMu.Lock()
+		e.uncovered = append(e.uncovered, file)
+		e.uncoveredMu.Unlock()
+		if e.ExplainPass {
+			e.explainNoHits(sb, embedding, cfg.VectorStore.SimilarityThreshold)
+		} else if e.verbose(VerbosityFiles) {
+			fmt.Fprintf(sb, "  No relevant ADRs found.\n")
+		}
+		return 0
+	}
+
+	if e.verbose(VerbosityFiles) || e.ExplainPass {
+		fmt.Fprintf(sb, "  Matched %d ADRs\n", len(hits))
+	}
+
+	// checks holds the matched ADRs that apply to this file.
+	var checks []index.SearchResult
+	for _, hit := range hits {
+		if !inScope(hit.ADR, file) {
+			if e.ExplainPass {
+				fmt.Fprintf(sb, "  Skipping ADR %s (%.2f): file is outside its scope\n", hit.ADR.Title, hit.Score)
 			}
+			continue
+		}
+		if !hit.ADR.HasAnyTag(e.Tags) {
+			if e.ExplainPass {
+				fmt.Fprintf(sb, "  Skipping ADR %s (%.2f): it has none of the tags %s\n", hit.ADR.Title, hit.Score, strings.Join(e.Tags, ", "))
+			}
+			continue
+		}
 
-			diffForEmbedding, err := e.Content.GetDiff(file)
-			if err != nil || diffForEmbedding == "" {
-				diffForEmbedding = content
+		// Check for ignore directive (optimization: only check header)
+		header := content
+		if len(header) > 2000 {
+			header = header[:2000]
+		}
+		if strings.Contains(header, IgnoreDirective(hit.ADR.ID)) {
+			if e.verbose(VerbosityScores) || e.ExplainPass {
+				fmt.Fprintf(sb, "  Skipping ADR %s (Suppressed)\n", hit.ADR.Title)
 			}
+			continue
+		}
+
+		checks = append(checks, hit)
+	}
 
-			if len(diffForEmbedding) > 6000 {
-				diffForEmbedding = diffForEmbedding[:6000]
+	// check analyzes the file against one ADR, writing its results to sb. It
+	// returns ErrBudgetExhausted once the token budget refuses a call.
+	check := func(hit index.SearchResult, sb *strings.Builder) (int, error) {
+		if e.verbose(VerbosityScores) || e.ExplainPass {
+			fmt.Fprintf(sb, "  Checking against ADR: %s (%.2f)\n", hit.ADR.Title, hit.Score)
+		}
+
+		localViolations := 0
+		parts := chunks
+		if parts == nil {
+			parts = []string{content}
+		}
+		for i, part := range parts {
+			// focused marks a diff trimmed to the hunks relevant to this ADR,
+			// whose line numbers no longer match the diff the user sees. Its
+			// result is cached under the whole diff, so cache prune can
+			// recognize it without embedding the hunks again.
+			focused := false
+			keyContent := part
+			if limit := e.Config.Analysis.FocusDiffTokens; limit > 0 && diffMode == "diff" && chunks == nil {
+				trimmed, kept, total, err := e.focusDiff(ctx, hit.ADR, part, limit)
+				if err != nil {
+					fmt.Fprintf(sb, "    Warning: could not rank diff hunks for ADR %s, sending the whole diff: %v\n", hit.ADR.Title, err)
+				} else if kept < total {
+					part, focused, keyContent = trimmed, true, focusKey(e.Config, part)
+					if e.verbose(VerbosityScores) || e.ExplainPass {
+						fmt.Fprintf(sb, "    Sending %d of %d diff hunks most relevant to %s\n", kept, total, hit.ADR.Title)
+					}
+				}
 			}
 
-			embedding, err := e.Provider.CreateEmbedding(ctx, diffForEmbedding)
+			res, err := e.analyzeWithCache(ctx, hit.ADR, part, keyContent, file, sb)
+			if errors.Is(err, ErrBudgetExhausted) {
+				fmt.Fprintf(sb, "Stopped analyzing %s: %v\n", file, err)
+				return localViolations, err
+			}
+			if err != nil && llm.IsParseFailure(err) && e.onParseFailure() == ParseFailureSkip {
+				fmt.Fprintf(sb, "    Warning: skipping ADR %s: %v\n", hit.ADR.Title, err)
+				continue
+			}
 			if err != nil {
-				fmt.Fprintf(&sb, "Error generating embedding for %s: %v\n", file, err)
-				mu.Lock()
-				fmt.Print(sb.String())
-				mu.Unlock()
-				return nil
+				fmt.Fprintf(sb, "    Warning: LLM analysis failed: %v\n", err)
+				e.markIncomplete(file, fmt.Sprintf("analysis against ADR %s failed: %v", hit.ADR.Title, err))
+				continue
 			}
 
-			hits := e.Store.Search(embedding, e.Config.VectorStore.SimilarityThreshold, 3)
-			if len(hits) == 0 {
-				if e.Debug {
-					fmt.Fprintf(&sb, "  No relevant ADRs found.\n")
+			analyzed := part
+			if res.NeedsFullContext && diffMode == "diff" && contextStrategy(cfg) == ContextDiffThenFull {
+				full, err := e.fullContext(file)
+				if err != nil {
+					fmt.Fprintf(sb, "    Warning: failed to read full content for escalation: %v\n", err)
+				} else {
+					if e.verbose(VerbosityScores) {
+						fmt.Fprintf(sb, "  Diff inconclusive for %s; re-checking with full content\n", hit.ADR.Title)
+					}
+					fullRes, err := e.analyzeWithCache(ctx, hit.ADR, full, full, file, sb)
+					if err != nil {
+						fmt.Fprintf(sb, "    Warning: LLM analysis failed: %v\n", err)
+					} else {
+						res, analyzed, focused = fullRes, full, false
+					}
 				}
-				mu.Lock()
-				fmt.Print(sb.String())
-				mu.Unlock()
-				return nil
 			}
 
-			localViolations := 0
-			for _, hit := range hits {
-				if hit.ADR.Scope != "" && !matchGlob(hit.ADR.Scope, file) {
+			if res.Violation && changed != nil {
+				// An approximate line is not trusted to drop a violation, and
+				// quoted code that occurs several times is kept if any
+				// occurrence was changed.
+				lines, approximate := e.fileLineNumbers(file, res.QuotedCode)
+				if len(lines) > 0 && !approximate && !slices.ContainsFunc(lines, func(line int) bool {
+					return touchesChangedLine(changed, line, res.QuotedCode)
+				}) {
+					if e.verbose(VerbosityScores) || e.ExplainPass {
+						fmt.Fprintf(sb, "    Skipping violation of %s at line %d: the line was not changed\n", hit.ADR.Title, lines[0])
+					}
 					continue
 				}
+			}
 
-				// Check for ignore directive (optimization: only check header)
-				header := content
-				if len(header) > 2000 {
-					header = header[:2000]
+			if res.Violation {
+				var rec ViolationRecord
+				if e.JSONL != nil || e.CollectViolations || e.baseline != nil {
+					rec = ViolationRecord{
+						File:      file,
+						ADRID:     hit.ADR.ID,
+						ADRTitle:  hit.ADR.Title,
+						Reasoning: res.Reasoning,
+						Rule:      res.ViolatedRule,
+						Code:      res.QuotedCode,
+					}
+					rec.Line, rec.LineApproximate = e.fileLineNumber(file, res.QuotedCode)
 				}
-				if strings.Contains(header, fmt.Sprintf("archguard-ignore: %s", hit.ADR.ID)) {
-					if e.Debug {
-						fmt.Fprintf(&sb, "  Skipping ADR %s (Suppressed)\n", hit.ADR.Title)
+				if e.matchBaseline(rec) {
+					e.known.Add(1)
+					rec.Baseline = true
+					if e.CollectViolations {
+						e.recordsMu.Lock()
+						e.records = append(e.records, rec)
+						e.recordsMu.Unlock()
+					}
+					if e.JSONL != nil {
+						e.emitJSONL(rec)
+					} else {
+						fmt.Fprintf(sb, "    [KNOWN] %s [Line %d] (in baseline)\n", hit.ADR.Title, rec.Line)
 					}
 					continue
 				}
-
-				if e.Debug {
-					fmt.Fprintf(&sb, "  Checking against ADR: %s (%.2f)\n", hit.ADR.Title, hit.Score)
+				localViolations++
+				if e.CollectViolations {
+					e.recordsMu.Lock()
+					e.records = append(e.records, rec)
+					e.recordsMu.Unlock()
 				}
-
-				systemPrompt := e.Config.LLM.SystemPrompt
-				if systemPrompt == "" {
-					systemPrompt = llm.DefaultSystemPrompt
+				// With sorted output the cap is applied while printing, so the
+				// violations shown do not depend on which files finish first.
+				sortedCap := e.SortOutput && e.MaxViolations > 0 && e.JSONL == nil
+				if e.MaxViolations > 0 && !sortedCap && e.printed.Add(1) > int64(e.MaxViolations) {
+					continue
 				}
-
-				cacheKey := cache.ComputeAnalysisKey(e.Config.LLM.Model, hit.ADR.Content, content, systemPrompt, llm.ChatPrompt)
-
-				var res *llm.AnalysisResult
-				if e.Cache != nil {
-					cachedRes, found, err := e.Cache.Get(cacheKey)
-					if err == nil && found {
-						// We can't log debug easily to sb properly unless we implement a custom logger on Engine
-						// but skipping for now or just append
-						if e.Debug {
-							fmt.Fprintf(&sb, "[DEBUG]   Cache Hit for %s\n", hit.ADR.Title)
-						}
-						res = cachedRes
-					}
+				if e.JSONL != nil {
+					e.emitJSONL(rec)
+					continue
 				}
-
-				if res == nil {
-					if e.Debug {
-						fmt.Fprintf(&sb, "[DEBUG]   Cache Miss. Calling LLM...\n")
-					}
-					res, err = llm.AnalyzeDrift(ctx, e.Provider, hit.ADR.Content, content, file, systemPrompt)
-					if err != nil {
-						fmt.Fprintf(&sb, "    Warning: LLM analysis failed: %v\n", err)
-						continue
-					}
-					if e.Cache != nil {
-						if err := e.Cache.Put(cacheKey, res); err != nil {
-							e.Log("Failed to cache analysis result: %v", err)
-						}
-					}
+				if sortedCap {
+					sb.WriteString(violationStart)
 				}
-
-				if res.Violation {
-					lineNum := e.findLineNumber(content, res.QuotedCode)
-					fmt.Fprintf(&sb, "    [VIOLATION] %s [Line %d]\n", hit.ADR.Title, lineNum)
-					fmt.Fprintf(&sb, "    Reasoning: %s\n", res.Reasoning)
-					if res.QuotedCode != "" {
-						fmt.Fprintf(&sb, "    Code: %s\n", res.QuotedCode)
+				lineNum, approximate := locateQuote(analyzed, res.QuotedCode)
+				switch {
+				case focused || diffMode == "functions":
+					// Neither a trimmed diff nor extracted functions keep the
+					// file's line numbers.
+					lineNum, approximate = e.fileLineNumber(file, res.QuotedCode)
+				case lineNum > 0 && chunks != nil:
+					lineNum += chunkLines[i]
+				}
+				if approximate {
+					fmt.Fprintf(sb, "    [VIOLATION] %s [Line %d, approximate]\n", hit.ADR.Title, lineNum)
+				} else {
+					fmt.Fprintf(sb, "    [VIOLATION] %s [Line %d]\n", hit.ADR.Title, lineNum)
+				}
+				fmt.Fprintf(sb, "    Reasoning: %s\n", res.Reasoning)
+				if res.ViolatedRule != "" {
+					if containsNormalized(hit.ADR.Content, res.ViolatedRule) {
+						fmt.Fprintf(sb, "    Rule: %s\n", res.ViolatedRule)
+					} else {
+						fmt.Fprintf(sb, "    Rule: %s (not found verbatim in ADR)\n", res.ViolatedRule)
 					}
-					localViolations++
 				}
+				if res.QuotedCode != "" {
+					fmt.Fprintf(sb, "    Code: %s\n", res.QuotedCode)
+				}
+				if sortedCap {
+					sb.WriteString(violationEnd)
+				}
+			} else if e.ExplainPass {
+				fmt.Fprintf(sb, "    [PASS] %s\n", hit.ADR.Title)
+				fmt.Fprintf(sb, "    Reasoning: %s\n", res.Reasoning)
 			}
+		}
+		return localViolations, nil
+	}
 
-			mu.Lock()
-			fmt.Print(sb.String())
-			violations += localViolations
-			mu.Unlock()
-			return nil
-		})
+	// Each ADR writes to its own buffer so that results stay grouped by ADR,
+	// in similarity order, when they are analyzed concurrently.
+	outputs := make([]strings.Builder, len(checks))
+	counts := make([]int, len(checks))
+	errs := make([]error, len(checks))
+	if e.ParallelADRs {
+		var g errgroup.Group
+		for i, hit := range checks {
+			g.Go(func() error {
+				counts[i], errs[i] = check(hit, &outputs[i])
+				return nil
+			})
+		}
+		_ = g.Wait()
+	} else {
+		for i, hit := range checks {
+			if counts[i], errs[i] = check(hit, &outputs[i]); errs[i] != nil {
+				break
+			}
+		}
 	}
 
-	_ = g.Wait()
+	localViolations := 0
+	for i := range checks {
+		sb.WriteString(outputs[i].String())
+		localViolations += counts[i]
+	}
+	for _, err := range errs {
+		if err != nil {
+			e.markIncomplete(file, err.Error())
+			e.unscanned.Add(1)
+			break
+		}
+	}
+	return localViolations
+}
 
-	if violations > 0 {
-		return &DriftDetectedError{Count: violations}
+// matchBaseline reports whether rec is a known violation, consuming one
+// matching Baseline entry so that a second occurrence of the same violation
+// in a file counts as new.
+func (e *Engine) matchBaseline(rec ViolationRecord) bool {
+	if e.baseline == nil {
+		return false
+	}
+	e.baselineMu.Lock()
+	defer e.baselineMu.Unlock()
+	fp := rec.Fingerprint()
+	if e.baseline[fp] == 0 {
+		return false
 	}
+	e.baseline[fp]--
+	return true
+}
+
+// scopedHits keeps the first maxADRsPerFile hits whose ADR has an explicit
+// scope, for ScopedOnly runs such as --commit-msg.
+func (e *Engine) scopedHits(hits []index.SearchResult, sb *strings.Builder) []index.SearchResult {
+	var scoped []index.SearchResult
+	for _, hit := range hits {
+		if len(hit.ADR.Scope) == 0 {
+			if e.ExplainPass {
+				fmt.Fprintf(sb, "  Skipping ADR %s (%.2f): it has no scope, so it does not apply to commit messages\n", hit.ADR.Title, hit.Score)
+			}
+			continue
+		}
+		if len(scoped) < maxADRsPerFile {
+			scoped = append(scoped, hit)
+		}
+	}
+	return scoped
+}
+
+// violationStart and violationEnd delimit each violation block in buffered
+// sorted output, so capViolations can apply --max-violations in path order.
+const (
+	violationStart = "\x00violation>"
+	violationEnd   = "\x00<violation"
+)
+
+// capViolations removes the block markers from out, dropping violation blocks
+// once *shown reaches limit (0 keeps all of them). *shown counts the blocks
+// kept so far across files.
+func capViolations(out string, limit int, shown *int) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(out, violationStart)
+		if start < 0 {
+			b.WriteString(out)
+			return b.String()
+		}
+		b.WriteString(out[:start])
+		out = out[start+len(violationStart):]
+		end := strings.Index(out, violationEnd)
+		if end < 0 {
+			end = len(out)
+		}
+		if limit == 0 || *shown < limit {
+			b.WriteString(out[:end])
+			*shown++
+		}
+		out = strings.TrimPrefix(out[end:], violationEnd)
+	}
+}
+
+// markIncomplete counts a failure and records why file was not fully
+// analyzed. Only the first reason per file is kept.
+func (e *Engine) markIncomplete(file, reason string) {
+	e.failures.Add(1)
+	e.incompleteMu.Lock()
+	defer e.incompleteMu.Unlock()
+	if e.incomplete == nil {
+		e.incomplete = make(map[string]string)
+	}
+	if _, ok := e.incomplete[file]; !ok {
+		e.incomplete[file] = reason
+	}
+}
+
+// emitJSONL writes rec to e.JSONL as a single line. Writes are serialized so
+// concurrent files never interleave within a line.
+func (e *Engine) emitJSONL(rec ViolationRecord) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		e.Log("Failed to encode violation: %v", err)
+		return
+	}
+	e.jsonlMu.Lock()
+	defer e.jsonlMu.Unlock()
+	if _, err := e.JSONL.Write(append(data, '\n')); err != nil {
+		e.Log("Failed to write violation: %v", err)
+	}
+}
+
+// explainNoHits reports the nearest ADRs that missed the similarity
+// threshold, so --explain-pass shows how close retrieval came.
+func (e *Engine) explainNoHits(sb *strings.Builder, embedding []float32, threshold float64) {
+	metric, err := index.ParseMetric(e.Config.VectorStore.Metric)
+	if err != nil {
+		metric = index.MetricCosine
+	}
+	nearest := e.Store.Search(embedding, metric.NoThreshold(), maxADRsPerFile)
+	if len(nearest) == 0 {
+		fmt.Fprintf(sb, "  No ADRs retrieved: the index is empty.\n")
+		return
+	}
+	if metric == index.MetricEuclidean {
+		fmt.Fprintf(sb, "  No ADRs within distance threshold %.2f. Nearest:\n", threshold)
+	} else {
+		fmt.Fprintf(sb, "  No ADRs above similarity threshold %.2f. Nearest:\n", threshold)
+	}
+	for _, hit := range nearest {
+		fmt.Fprintf(sb, "    %s (%.2f)\n", hit.ADR.Title, hit.Score)
+	}
+}
 
+// truncateUTF8 cuts s to at most n bytes, backing off to a character boundary
+// so a multi-byte character is not split before s is sent to the embedding API.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// embed returns the embedding for text, consulting the embedding cache first.
+func (e *Engine) embed(ctx context.Context, text string) ([]float32, error) {
+	var key string
+	if e.Cache != nil {
+		key = cache.ComputeEmbeddingKey(e.Config.VectorStore.Model, text)
+		stop := e.Timings.Track(PhaseCacheIO)
+		vec, found, err := e.Cache.GetEmbedding(key)
+		stop()
+		if err == nil && found {
+			return vec, nil
+		}
+	}
+
+	stop := e.Timings.Track(PhaseEmbedding)
+	vec, err := e.Provider.CreateEmbedding(ctx, text)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Cache != nil {
+		stop := e.Timings.Track(PhaseCacheIO)
+		err := e.Cache.PutEmbedding(key, vec)
+		stop()
+		if err != nil {
+			e.Log("Failed to cache embedding: %v", err)
+		}
+	}
+	return vec, nil
+}
+
+// analyzeWithCache asks the LLM whether content violates adr, consulting and
+// populating the analysis cache.
+func (e *Engine) analyzeWithCache(ctx context.Context, adr *index.ADR, content, keyContent, file string, sb *strings.Builder) (*llm.AnalysisResult, error) {
+	cfg := e.Config.ForPath(file)
+	key := cacheKey(cfg, adr, keyContent)
+
+	var res *llm.AnalysisResult
+	if e.Cache != nil {
+		stop := e.Timings.Track(PhaseCacheIO)
+		cachedRes, found, err := e.Cache.Get(key)
+		stop()
+		if err == nil && found {
+			if e.verbose(VerbosityDebug) {
+				fmt.Fprintf(sb, "[DEBUG]   Cache Hit for %s\n", adr.Title)
+			}
+			res = cachedRes
+			e.cacheHits.Add(1)
+		}
+	}
+	if e.ExplainCache {
+		e.explainCache(sb, cfg, adr, keyContent, key, res != nil)
+	}
+
+	if res == nil {
+		if e.verbose(VerbosityDebug) {
+			fmt.Fprintf(sb, "[DEBUG]   Cache Miss. Calling LLM...\n")
+		}
+		prompt := systemPrompt(cfg)
+		if err := e.spendTokens(prompt + adr.Content + content); err != nil {
+			return nil, err
+		}
+		if e.llmSlots != nil {
+			select {
+			case e.llmSlots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		e.llmCalls.Add(1)
+		var err error
+		stop := e.Timings.Track(PhaseLLM)
+		res, err = llm.AnalyzeDriftWithOptions(ctx, e.Provider, adr.Content, content, file, prompt, e.retryOptions())
+		stop()
+		if e.llmSlots != nil {
+			<-e.llmSlots
+		}
+		if err != nil {
+			return nil, err
+		}
+		n := int64(e.countTokens(res.Raw))
+		e.tokens.Add(n)
+		e.outTokens.Add(n)
+		if e.Cache != nil {
+			stop := e.Timings.Track(PhaseCacheIO)
+			err := e.Cache.Put(key, res)
+			stop()
+			if err != nil {
+				e.Log("Failed to cache analysis result: %v", err)
+			}
+		}
+	}
+
+	if e.verbose(VerbosityDebug) && res.Raw != "" {
+		fmt.Fprintf(sb, "[DEBUG]   Raw response: %s\n", res.Raw)
+	}
+	return res, nil
+}
+
+// spendTokens reserves the estimated tokens of a prompt against
+// analysis.max_tokens_budget and analysis.max_cost_usd, refusing the call
+// with ErrBudgetExhausted if it would exceed either. Reserving before the call
+// keeps concurrent workers from overshooting together; responses are added
+// once they arrive.
+func (e *Engine) spendTokens(prompt string) error {
+	budget := int64(e.Config.Analysis.MaxTokensBudget)
+	maxCost := e.Config.Analysis.MaxCostUSD
+	if budget <= 0 && maxCost <= 0 {
+		return nil
+	}
+	if e.exhausted.Load() {
+		return ErrBudgetExhausted
+	}
+	n := int64(e.countTokens(prompt))
+	total := e.tokens.Add(n)
+	e.inTokens.Add(n)
+	if budget > 0 && total > budget || maxCost > 0 && e.estimatedCost() > maxCost {
+		e.exhausted.Store(true)
+		return ErrBudgetExhausted
+	}
 	return nil
 }
 
+// estimatedCost prices the tokens spent so far with llm.input_cost_per_mtok
+// and llm.output_cost_per_mtok.
+func (e *Engine) estimatedCost() float64 {
+	in := float64(e.inTokens.Load()) * e.Config.LLM.InputCostPerMTok
+	out := float64(e.outTokens.Load()) * e.Config.LLM.OutputCostPerMTok
+	return (in + out) / 1e6
+}
+
+// budgetLimits names the configured budgets for ErrBudgetExhausted messages,
+// e.g. "analysis.max_cost_usd: 5".
+func (e *Engine) budgetLimits() string {
+	var limits []string
+	if n := e.Config.Analysis.MaxTokensBudget; n > 0 {
+		limits = append(limits, fmt.Sprintf("analysis.max_tokens_budget: %d", n))
+	}
+	if c := e.Config.Analysis.MaxCostUSD; c > 0 {
+		limits = append(limits, fmt.Sprintf("analysis.max_cost_usd: %g", c))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// countTokens estimates the tokens in text with the engine's tokenizer, or
+// approximately 4 bytes per token when it is unavailable.
+func (e *Engine) countTokens(text string) int {
+	tkm, err := e.getTokenizer()
+	if err != nil {
+		return (len(text) + 3) / 4
+	}
+	return len(tkm.Encode(text, nil, nil))
+}
+
+// systemPrompt returns the configured system prompt or the built-in prompt for
+// analysis.strictness. Run rejects invalid strictness values up front.
+func systemPrompt(cfg *config.Config) string {
+	if cfg.LLM.SystemPrompt != "" {
+		return cfg.LLM.SystemPrompt
+	}
+	prompt, err := llm.SystemPromptForStrictness(cfg.Analysis.Strictness)
+	if err != nil {
+		return llm.DefaultSystemPrompt
+	}
+	return prompt
+}
+
+// explainCache writes the inputs of an analysis cache key and the lookup
+// outcome, so a surprising hit or miss can be traced to the input that
+// changed between runs.
+func (e *Engine) explainCache(sb *strings.Builder, cfg *config.Config, adr *index.ADR, content, key string, hit bool) {
+	outcome := "miss"
+	switch {
+	case e.Cache == nil:
+		outcome = "disabled"
+	case hit:
+		outcome = "hit"
+	}
+	fmt.Fprintf(sb, "  Cache %s for ADR %s (%s): key %s\n", outcome, adr.ID, adr.Title, key[:12])
+	for _, c := range cache.AnalysisKeyComponents(cfg.LLM.Model, adr.Content, content, systemPrompt(cfg), llm.ChatPrompt) {
+		fmt.Fprintf(sb, "    %-16s %s\n", c.Name+":", c.Value)
+	}
+}
+
+// cacheKey derives the analysis cache key for an ADR and the code context sent to the LLM.
+func cacheKey(cfg *config.Config, adr *index.ADR, content string) string {
+	return cache.ComputeAnalysisKey(cfg.LLM.Model, adr.Content, content, systemPrompt(cfg), llm.ChatPrompt)
+}
+
+// focusKey stands in for a diff trimmed by analysis.focus_diff_tokens in its
+// cache key: the whole diff plus the settings that decide which hunks are
+// kept, so the key can be recomputed without embedding the hunks.
+func focusKey(cfg *config.Config, diff string) string {
+	return fmt.Sprintf("%s\x00focus_diff_tokens:%d\x00embedding model:%s", diff, cfg.Analysis.FocusDiffTokens, cfg.VectorStore.Model)
+}
+
+// LiveCacheKeys returns the analysis cache keys that the current files and ADRs
+// could produce: one per (non-excluded file, in-scope ADR) pair. Entries outside
+// this set can never be hit again and are safe to prune.
+func (e *Engine) LiveCacheKeys(adrs []index.ADR) (map[string]bool, error) {
+	files, err := e.Content.GetFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	live := make(map[string]bool)
+	for _, file := range files {
+		if e.shouldExclude(file) {
+			continue
+		}
+		content, mode, err := e.fetchContext(file)
+		if err != nil {
+			e.Log("Skipping %s while computing cache keys: %v", file, err)
+			continue
+		}
+		contents := []string{content}
+		cfg := e.Config.ForPath(file)
+		switch {
+		case mode == "truncated" && onTruncation(cfg) == TruncationChunk:
+			// Each chunk of an oversized file is cached under its own key.
+			full, err := e.Content.GetContent(file)
+			if err == nil {
+				contents, _, err = e.chunkContent(cfg, full)
+			}
+			if err != nil {
+				e.Log("Skipping %s while computing cache keys: %v", file, err)
+				continue
+			}
+		case mode == "diff":
+			// A diff trimmed by focus_diff_tokens is cached under focusKey.
+			if e.Config.Analysis.FocusDiffTokens > 0 {
+				contents = append(contents, focusKey(e.Config, content))
+			}
+			// diff-then-full may also have cached a full-content escalation
+			if contextStrategy(cfg) == ContextDiffThenFull {
+				if full, err := e.fullContext(file); err == nil {
+					contents = append(contents, full)
+				}
+			}
+		}
+		for i := range adrs {
+			if !inScope(&adrs[i], file) {
+				continue
+			}
+			for _, c := range contents {
+				live[cacheKey(cfg, &adrs[i], c)] = true
+			}
+		}
+	}
+	return live, nil
+}
+
+// checkFileLimit enforces analysis.max_files, printing a rough cost estimate and
+// asking for confirmation before an unexpectedly large run.
+func (e *Engine) checkFileLimit(count int) error {
+	limit := e.Config.Analysis.MaxFiles
+	if limit <= 0 || count <= limit || e.SkipFileLimit {
+		return nil
+	}
+
+	e.Info("About to analyze %d files, exceeding analysis.max_files (%d).", count, limit)
+	e.Info("Estimated cost before cache hits: up to %d embedding calls and %d LLM analysis calls.", count, count*maxADRsPerFile)
+
+	if e.Confirm != nil && e.Confirm(fmt.Sprintf("Proceed with analyzing %d files? (y/n): ", count)) {
+		return nil
+	}
+	return fmt.Errorf("refusing to analyze %d files (analysis.max_files is %d); re-run with --yes-really to proceed", count, limit)
+}
+
+// retryOptions applies the configured backoff bounds to the default retry policy.
+func (e *Engine) retryOptions() llm.RetryOptions {
+	opts := llm.DefaultRetryOptions()
+	if e.Config.LLM.RetryBaseMs > 0 {
+		opts.InitialInterval = time.Duration(e.Config.LLM.RetryBaseMs) * time.Millisecond
+	}
+	if e.Config.LLM.RetryMaxMs > 0 {
+		opts.MaxInterval = time.Duration(e.Config.LLM.RetryMaxMs) * time.Millisecond
+	}
+	if e.Config.LLM.MaxRetries != nil {
+		opts.MaxRetries = *e.Config.LLM.MaxRetries
+	}
+	opts.NoParseRetry = e.onParseFailure() != ParseFailureRetry
+	return opts
+}
+
+// onParseFailure returns the normalized llm.on_parse_failure.
+func (e *Engine) onParseFailure() string {
+	policy := strings.ToLower(strings.TrimSpace(e.Config.LLM.OnParseFailure))
+	if policy == "" {
+		return ParseFailureRetry
+	}
+	return policy
+}
+
+// shouldExclude reports whether path is skipped: files under analysis.adr_path,
+// which are the rules rather than code governed by them, and files matching
+// analysis.exclude_patterns or .archguardignore.
 func (e *Engine) shouldExclude(path string) bool {
-	for _, pattern := range e.Config.Analysis.ExcludePatterns {
+	if inDir(path, e.Config.Analysis.ADRPath) {
+		return true
+	}
+	for _, pattern := range e.Config.ForPath(path).Analysis.ExcludePatterns {
 		if matchGlob(pattern, path) {
 			return true
 		}
 	}
-	return false
+	return e.Ignore.Match(path)
 }
 
 func (e *Engine) fetchContext(path string) (string, string, error) {
-	maxTokens := e.Config.LLM.MaxTokens
-	if maxTokens == 0 {
-		maxTokens = 8000
+	fullContent, err := e.Content.GetContent(path)
+	if err != nil {
+		return "", "", err
 	}
 
+	cfg := e.Config.ForPath(path)
+	switch contextStrategy(cfg) {
+	case ContextFull:
+		return e.fitContent(cfg, fullContent)
+	case ContextDiff, ContextDiffThenFull:
+		if diff, err := e.Content.GetDiff(path); err == nil && diff != "" {
+			return diff, "diff", nil
+		}
+		return e.fitContent(cfg, fullContent)
+	case ContextFunctions:
+		if diff, err := e.Content.GetDiff(path); err == nil && diff != "" {
+			if functions, ok := goFunctionContext(path, fullContent, changedLines(diff)); ok {
+				// Functions too long to send whole are sent as the diff.
+				if content, mode, err := e.fitContent(cfg, functions); err != nil || mode == "full" {
+					return content, "functions", err
+				}
+				return diff, "diff", nil
+			}
+		}
+	}
+
+	content, mode, err := e.fitContent(cfg, fullContent)
+	if mode != "truncated" {
+		return content, mode, err
+	}
+
+	diff, err := e.Content.GetDiff(path)
+	if err != nil || diff == "" {
+		return content, mode, nil
+	}
+	return diff, "diff", nil
+}
+
+// fullContext returns the file's full content, truncated to fit the token budget.
+func (e *Engine) fullContext(path string) (string, error) {
 	fullContent, err := e.Content.GetContent(path)
 	if err != nil {
-		return "", "", err
+		return "", err
+	}
+	content, _, err := e.fitContent(e.Config.ForPath(path), fullContent)
+	return content, err
+}
+
+// fitContent returns content unchanged ("full") if it fits within
+// llm.max_tokens, otherwise truncated at a line boundary ("truncated").
+func (e *Engine) fitContent(cfg *config.Config, fullContent string) (string, string, error) {
+	maxTokens := cfg.LLM.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 8000
 	}
 
 	tkm, err := e.getTokenizer()
 	if err != nil {
-		// Fallback if tokenizer fails completely (unlikely with cl100k_base fallback)
-		e.Log("Tokenizer initialization failed: %v", err)
-		if len(fullContent) > maxTokens*4 {
-			return fullContent[:maxTokens*4], "truncated", nil
+		// Approximate 4 bytes per token; getTokenizer has already warned.
+		if len(fullContent) <= maxTokens*4 {
+			return fullContent, "full", nil
 		}
-		return fullContent, "full", nil
+		truncatedContent := fullContent[:maxTokens*4]
+		if lastNewline := strings.LastIndex(truncatedContent, "\n"); lastNewline != -1 {
+			truncatedContent = truncatedContent[:lastNewline+1]
+		}
+		return truncatedContent, "truncated", nil
 	}
 
 	tokenIds := tkm.Encode(fullContent, nil, nil)
@@ -274,45 +1425,111 @@ func (e *Engine) fetchContext(path string) (string, string, error) {
 		return fullContent, "full", nil
 	}
 
-	diff, err := e.Content.GetDiff(path)
-	if err != nil || diff == "" {
-		// Truncate using tokens for precision
-		truncatedIds := tokenIds[:maxTokens]
-		truncatedContent := tkm.Decode(truncatedIds)
+	// Truncate using tokens for precision
+	truncatedIds := tokenIds[:maxTokens]
+	truncatedContent := tkm.Decode(truncatedIds)
 
-		// Smart Truncate: Roll back to the nearest preceding newline character
-		if lastNewline := strings.LastIndex(truncatedContent, "\n"); lastNewline != -1 {
-			truncatedContent = truncatedContent[:lastNewline+1]
+	// Smart Truncate: Roll back to the nearest preceding newline character
+	if lastNewline := strings.LastIndex(truncatedContent, "\n"); lastNewline != -1 {
+		truncatedContent = truncatedContent[:lastNewline+1]
+	}
+
+	return truncatedContent, "truncated", nil
+}
+
+// chunkContent splits content at line boundaries into consecutive pieces that
+// each fit llm.max_tokens, returning the pieces and the number of file lines
+// preceding each one.
+func (e *Engine) chunkContent(cfg *config.Config, content string) ([]string, []int, error) {
+	var chunks []string
+	var lines []int
+	offset := 0
+	for content != "" {
+		chunk, _, err := e.fitContent(cfg, content)
+		if err != nil {
+			return nil, nil, err
+		}
+		if chunk == "" {
+			chunk = content
 		}
+		chunks = append(chunks, chunk)
+		lines = append(lines, offset)
+		offset += strings.Count(chunk, "\n")
+		content = content[len(chunk):]
+	}
+	return chunks, lines, nil
+}
 
-		return truncatedContent, "truncated", nil
+// onTruncation returns the normalized analysis.on_truncation.
+func onTruncation(cfg *config.Config) string {
+	policy := strings.ToLower(strings.TrimSpace(cfg.Analysis.OnTruncation))
+	if policy == "" {
+		return TruncationAnalyze
 	}
-	return diff, "diff", nil
+	return policy
 }
 
-func (e *Engine) getTokenizer() (*tiktoken.Tiktoken, error) {
-	model := e.Config.LLM.Model
-	if model == "" {
-		model = "gpt-3.5-turbo"
+// contextStrategy returns the normalized analysis.context_strategy.
+func contextStrategy(cfg *config.Config) string {
+	strategy := strings.ToLower(strings.TrimSpace(cfg.Analysis.ContextStrategy))
+	if strategy == "" {
+		return ContextAuto
 	}
+	return strategy
+}
 
-	tkm, err := tiktoken.EncodingForModel(model)
+// getTokenizer loads the tokenizer once per engine. The vocabulary is
+// downloaded on first use unless TIKTOKEN_CACHE_DIR (which the CLI sets from
+// llm.tiktoken_cache_dir) already holds it, so a failure usually means an
+// air-gapped machine.
+func (e *Engine) getTokenizer() (*tiktoken.Tiktoken, error) {
+	e.tokenizerOnce.Do(func() {
+		model := e.Config.LLM.Model
+		if model == "" {
+			model = "gpt-3.5-turbo"
+		}
+
+		e.tokenizer, e.tokenizerErr = tiktoken.EncodingForModel(model)
+		if e.tokenizerErr != nil {
+			// Fallback to cl100k_base for unknown models (e.g. Ollama)
+			e.tokenizer, e.tokenizerErr = tiktoken.GetEncoding("cl100k_base")
+		}
+		if e.tokenizerErr != nil {
+			// Written to stderr: it can come from any worker, and must not land
+			// in machine-readable stdout such as --format jsonl.
+			fmt.Fprintf(os.Stderr, "Warning: tokenizer unavailable (%v); truncating by an approximate 4 bytes per token instead.\n", e.tokenizerErr)
+			fmt.Fprintln(os.Stderr, "         In air-gapped environments, point llm.tiktoken_cache_dir at a directory with the cached tokenizer vocabulary.")
+		}
+	})
+	return e.tokenizer, e.tokenizerErr
+}
+
+// fileLineNumber locates quote in the file itself rather than in the analyzed
+// context, which may be a diff, so records carry real file line numbers. See
+// locateQuote for when the line is approximate.
+func (e *Engine) fileLineNumber(file, quote string) (int, bool) {
+	content, err := e.Content.GetContent(file)
 	if err != nil {
-		// Fallback to cl100k_base for unknown models (e.g. Ollama)
-		return tiktoken.GetEncoding("cl100k_base")
+		return 0, false
 	}
-	return tkm, nil
+	return locateQuote(content, quote)
 }
 
-func (e *Engine) findLineNumber(content, quote string) int {
-	if quote == "" {
-		return 0
-	}
-	idx := strings.Index(content, quote)
-	if idx == -1 {
-		return 0
+// fileLineNumbers returns every line of the file where quote occurs; see
+// locateQuoteAll.
+func (e *Engine) fileLineNumbers(file, quote string) ([]int, bool) {
+	content, err := e.Content.GetContent(file)
+	if err != nil {
+		return nil, false
 	}
+	return locateQuoteAll(content, quote)
+}
 
-	lines := strings.Split(content[:idx], "\n")
-	return len(lines)
+// containsNormalized reports whether needle appears in haystack, ignoring
+// differences in whitespace and letter case.
+func containsNormalized(haystack, needle string) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	return strings.Contains(normalize(haystack), normalize(needle))
 }
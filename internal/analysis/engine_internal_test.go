@@ -1,6 +1,7 @@
 package analysis
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/tgenz1213/archguard/internal/config"
@@ -14,6 +15,24 @@ func (m *MockTruncationProvider) GetFiles() ([]string, error)            { retur
 func (m *MockTruncationProvider) GetContent(path string) (string, error) { return m.Content, nil }
 func (m *MockTruncationProvider) GetDiff(path string) (string, error)    { return "", nil }
 
+func TestTruncateUTF8(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"naïve", 3, "na"},
+		{"naïve", 4, "naï"},
+		{"日本", 2, ""},
+	} {
+		if got := truncateUTF8(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
 func TestFetchContext_SmartTruncation(t *testing.T) {
 	// A long string with newlines.
 	// We want enough tokens so that MaxTokens=5 cuts it off.
@@ -34,6 +53,9 @@ func TestFetchContext_SmartTruncation(t *testing.T) {
 		Config:  cfg,
 		Content: &MockTruncationProvider{Content: longContent},
 	}
+	if _, err := engine.getTokenizer(); err != nil {
+		t.Skipf("tokenizer vocabulary unavailable (offline?): %v", err)
+	}
 
 	content, mode, err := engine.fetchContext("test.go")
 	if err != nil {
@@ -79,3 +101,90 @@ func TestShouldExclude_RecursiveTestPattern(t *testing.T) {
 		}
 	}
 }
+
+func TestContainsNormalized(t *testing.T) {
+	adr := "## Decision\nAll services   must be\nwritten in Go."
+
+	if !containsNormalized(adr, "all services must be written in Go.") {
+		t.Error("expected rule to match despite whitespace and case differences")
+	}
+	if containsNormalized(adr, "Services must use Rust.") {
+		t.Error("expected hallucinated rule not to match")
+	}
+}
+
+type mockDiffProvider struct {
+	Content string
+	Diff    string
+}
+
+func (m *mockDiffProvider) GetFiles() ([]string, error)            { return []string{"test.go"}, nil }
+func (m *mockDiffProvider) GetContent(path string) (string, error) { return m.Content, nil }
+func (m *mockDiffProvider) GetDiff(path string) (string, error)    { return m.Diff, nil }
+
+func TestFetchContext_ContextStrategy(t *testing.T) {
+	cases := []struct {
+		strategy string
+		want     string
+		wantMode string
+	}{
+		{"", "package main\n", "full"},
+		{"auto", "package main\n", "full"},
+		{"full", "package main\n", "full"},
+		{"diff", "+package main\n", "diff"},
+		{"diff-then-full", "+package main\n", "diff"},
+	}
+
+	for _, c := range cases {
+		engine := &Engine{
+			Config: &config.Config{
+				LLM:      config.LLMConfig{Model: "gpt-3.5-turbo"},
+				Analysis: config.Analysis{ContextStrategy: c.strategy},
+			},
+			Content: &mockDiffProvider{Content: "package main\n", Diff: "+package main\n"},
+		}
+
+		content, mode, err := engine.fetchContext("test.go")
+		if err != nil {
+			t.Fatalf("fetchContext(%q) failed: %v", c.strategy, err)
+		}
+		if content != c.want || mode != c.wantMode {
+			t.Errorf("strategy %q: got (%q, %s), want (%q, %s)", c.strategy, content, mode, c.want, c.wantMode)
+		}
+	}
+}
+
+func TestFetchContext_OfflineTokenizerFallback(t *testing.T) {
+	cfg := &config.Config{LLM: config.LLMConfig{MaxTokens: 4}}
+	engine := &Engine{
+		Config:  cfg,
+		Content: &MockTruncationProvider{Content: "Line1\nLine2\nLine3\nLine4"},
+	}
+	// Simulate an air-gapped machine where the vocabulary cannot be downloaded.
+	engine.tokenizerOnce.Do(func() { engine.tokenizerErr = errors.New("no network") })
+
+	content, mode, err := engine.fetchContext("test.go")
+	if err != nil {
+		t.Fatalf("fetchContext failed: %v", err)
+	}
+	if mode != "truncated" {
+		t.Errorf("expected mode truncated, got %s", mode)
+	}
+	// 4 tokens * 4 bytes = 16 bytes ("Line1\nLine2\nLine"), rolled back to a newline.
+	if want := "Line1\nLine2\n"; content != want {
+		t.Errorf("expected %q, got %q", want, content)
+	}
+}
+
+func TestRetryOptions_MaxRetries(t *testing.T) {
+	e := &Engine{Config: &config.Config{}}
+	if got := e.retryOptions().MaxRetries; got != 3 {
+		t.Errorf("unset max_retries: expected default 3, got %d", got)
+	}
+
+	zero := 0
+	e.Config.LLM.MaxRetries = &zero
+	if got := e.retryOptions().MaxRetries; got != 0 {
+		t.Errorf("max_retries 0: expected 0, got %d", got)
+	}
+}
@@ -1,13 +1,70 @@
 package analysis
 
-import "github.com/bmatcuk/doublestar/v4"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/tgenz1213/archguard/internal/index"
+)
 
 // matchGlob matches a file path against a glob pattern, supporting standard
 // single-segment wildcards as well as recursive double-star (**) patterns.
+// Both are compared with forward slashes, as git reports paths, so Windows
+// paths and patterns written with backslashes match too.
 func matchGlob(pattern, name string) bool {
-	matched, err := doublestar.Match(pattern, name)
+	matched, err := doublestar.Match(filepath.ToSlash(pattern), filepath.ToSlash(name))
 	if err != nil {
 		return false
 	}
 	return matched
 }
+
+// matchAnyGlob reports whether name matches at least one of the patterns.
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// inScope reports whether an ADR applies to the given file: the file must match
+// one of the ADR's scope patterns (if any) and must not match any of its
+// scope_exclude patterns.
+func inScope(adr *index.ADR, name string) bool {
+	if len(adr.Scope) > 0 && !matchAnyGlob(adr.Scope, name) {
+		return false
+	}
+	for _, pattern := range adr.ScopeExclude {
+		if matchGlob(pattern, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// inDir reports whether the repository-relative path lies under dir, which
+// may be relative (e.g. "./docs/arch") or absolute. A dir of "" or "." never
+// matches, so that ADRs kept at the repository root do not exclude every file.
+func inDir(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	if filepath.IsAbs(dir) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return false
+		}
+		if dir, err = filepath.Rel(wd, dir); err != nil {
+			return false
+		}
+	}
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	if dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+		return false
+	}
+	return strings.HasPrefix(filepath.ToSlash(filepath.Clean(path))+"/", dir+"/")
+}
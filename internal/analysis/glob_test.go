@@ -1,6 +1,12 @@
 package analysis
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tgenz1213/archguard/internal/index"
+)
 
 func TestMatchGlob(t *testing.T) {
 	tests := []struct {
@@ -18,6 +24,8 @@ func TestMatchGlob(t *testing.T) {
 		{"double star prefix matches top-level file", "**/*_test.go", "foo_test.go", true},
 		{"double star prefix matches nested file (regression)", "**/*_test.go", "internal/analysis/glob_test.go", true},
 		{"double star prefix does not match non-test file", "**/*_test.go", "internal/analysis/glob.go", false},
+		{"native separators match", "vendor/**", filepath.FromSlash("vendor/pkg/foo.go"), true},
+		{"native separators in pattern match", filepath.FromSlash("vendor/**"), "vendor/pkg/foo.go", true},
 	}
 
 	for _, tt := range tests {
@@ -28,3 +36,92 @@ func TestMatchGlob(t *testing.T) {
 		})
 	}
 }
+
+func TestInScope(t *testing.T) {
+	adr := &index.ADR{Scope: index.StringList{"**/*.go"}, ScopeExclude: []string{"test/**", "generated/**"}}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"internal/server/main.go", true},
+		{"test/e2e_test.go", false},
+		{"generated/models/user.go", false},
+		{"web/app.ts", false},
+	}
+
+	for _, tt := range tests {
+		if got := inScope(adr, tt.path); got != tt.want {
+			t.Errorf("inScope(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	multi := &index.ADR{Scope: index.StringList{"cmd/**", "internal/server/**"}}
+	for path, want := range map[string]bool{
+		"cmd/archguard/main.go":       true,
+		"internal/server/http.go":     true,
+		"internal/analysis/engine.go": false,
+	} {
+		if got := inScope(multi, path); got != want {
+			t.Errorf("inScope(multi, %q) = %v, want %v", path, got, want)
+		}
+	}
+
+	unscoped := &index.ADR{ScopeExclude: []string{"test/**"}}
+	if !inScope(unscoped, "web/app.ts") {
+		t.Errorf("expected ADR without scope to apply outside scope_exclude")
+	}
+	if inScope(unscoped, "test/helpers.go") {
+		t.Errorf("expected scope_exclude to apply to ADR without scope")
+	}
+}
+
+func TestIsGlob(t *testing.T) {
+	for path, want := range map[string]bool{
+		"internal/handlers/**/*.go": true,
+		"cmd/?ain.go":               true,
+		"internal/[ab]*.go":         true,
+		"internal/analysis/glob.go": false,
+		".":                         false,
+	} {
+		if got := IsGlob(path); got != want {
+			t.Errorf("IsGlob(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	for _, name := range []string{"[id].tsx", "{legacy}.go"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if IsGlob(path) {
+			t.Errorf("IsGlob(%q) = true for an existing file, want false", path)
+		}
+	}
+}
+
+func TestInDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		dir  string
+		want bool
+	}{
+		{"docs/arch/0001-use-go.md", "./docs/arch", true},
+		{"docs/arch/rules/forbidden.txt", "docs/arch/", true},
+		{"docs/arch/0001-use-go.md", filepath.Join(wd, "docs", "arch"), true},
+		{"docs/architecture.md", "./docs/arch", false},
+		{"cmd/main.go", "./docs/arch", false},
+		{"main.go", ".", false},
+		{"main.go", "", false},
+		{"main.go", "../governance/adrs", false},
+	}
+	for _, tt := range tests {
+		if got := inDir(tt.path, tt.dir); got != tt.want {
+			t.Errorf("inDir(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
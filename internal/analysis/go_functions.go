@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// goFunctionContext returns the code sent for a Go file under
+// analysis.context_strategy: functions: the file up to its package clause and
+// imports, followed by each top-level declaration (with its doc comment) that
+// contains a changed line, separated by "// ..." markers. ok is false when
+// the file is not Go, does not parse, or no changed line falls inside a
+// declaration, so the caller can fall back to another strategy.
+//
+// Violation line numbers are unaffected, since quoted code is located in the
+// full file.
+func goFunctionContext(file, content string, changed map[int]bool) (string, bool) {
+	if !strings.HasSuffix(file, ".go") || len(changed) == 0 {
+		return "", false
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, content, parser.ParseComments)
+	if err != nil {
+		return "", false
+	}
+	offset := func(p token.Pos) int { return fset.Position(p).Offset }
+
+	headerEnd := offset(f.Name.End())
+	type span struct{ start, end token.Pos }
+	var picked []span
+	for _, decl := range f.Decls {
+		start, end := decl.Pos(), decl.End()
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				headerEnd = offset(end)
+				continue
+			}
+			doc = d.Doc
+		case *ast.FuncDecl:
+			doc = d.Doc
+		}
+		if doc != nil {
+			start = doc.Pos()
+		}
+		for line := fset.Position(start).Line; line <= fset.Position(end).Line; line++ {
+			if changed[line] {
+				picked = append(picked, span{start, end})
+				break
+			}
+		}
+	}
+	if len(picked) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(content[:headerEnd])
+	b.WriteString("\n")
+	for _, s := range picked {
+		b.WriteString("\n// ...\n\n")
+		b.WriteString(content[offset(s.start):offset(s.end)])
+		b.WriteString("\n")
+	}
+	return b.String(), true
+}
@@ -0,0 +1,52 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+const goFunctionsSrc = `package demo
+
+import (
+	"fmt"
+)
+
+// Untouched is not changed.
+func Untouched() {
+	fmt.Println("untouched")
+}
+
+// Changed calls the database directly.
+func Changed() {
+	db.Query("SELECT 1")
+}
+
+var limit = 10
+`
+
+func TestGoFunctionContext(t *testing.T) {
+	got, ok := goFunctionContext("demo.go", goFunctionsSrc, map[int]bool{14: true})
+	if !ok {
+		t.Fatal("expected function context for a changed Go function")
+	}
+	for _, want := range []string{"package demo", `"fmt"`, "// Changed calls", `db.Query("SELECT 1")`, "// ..."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("context missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Untouched", "limit"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("context includes unchanged %q:\n%s", unwanted, got)
+		}
+	}
+
+	if _, ok := goFunctionContext("demo.go", goFunctionsSrc, map[int]bool{4: true}); ok {
+		t.Error("a change only in the imports should fall back")
+	}
+	if _, ok := goFunctionContext("demo.py", goFunctionsSrc, map[int]bool{14: true}); ok {
+		t.Error("non-Go files should fall back")
+	}
+	if _, ok := goFunctionContext("bad.go", "package demo\nfunc {", map[int]bool{2: true}); ok {
+		t.Error("unparseable files should fall back")
+	}
+}
@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/tgenz1213/archguard/internal/index"
+)
+
+// splitHunks splits a unified diff into the file header preceding the first
+// hunk and the hunks themselves, each starting with its "@@" line.
+func splitHunks(diff string) (string, []string) {
+	var header strings.Builder
+	var hunks []string
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if hunkHeader.MatchString(line) {
+			hunks = append(hunks, line)
+			continue
+		}
+		if len(hunks) == 0 {
+			header.WriteString(line)
+			continue
+		}
+		hunks[len(hunks)-1] += line
+	}
+	return header.String(), hunks
+}
+
+// adrFocus returns the text hunks are scored against: the ADR's title and its
+// Decision section, or its whole content when it has none.
+func adrFocus(adr *index.ADR) string {
+	for _, section := range index.Sections(adr.Content) {
+		if strings.HasPrefix(strings.ToLower(section), "## decision") {
+			return adr.Title + "\n" + section
+		}
+	}
+	return adr.Title + "\n" + adr.Content
+}
+
+// focusDiff trims a diff larger than limit tokens to the hunks most similar to
+// adr, keeping them in diff order, until the next one would exceed limit. The
+// most similar hunk is always kept. It returns the diff unchanged, with kept
+// equal to total, when it fits or has a single hunk.
+func (e *Engine) focusDiff(ctx context.Context, adr *index.ADR, diff string, limit int) (focused string, kept, total int, err error) {
+	header, hunks := splitHunks(diff)
+	if len(hunks) < 2 || e.countTokens(diff) <= limit {
+		return diff, len(hunks), len(hunks), nil
+	}
+
+	target, err := e.embed(ctx, truncateUTF8(adrFocus(adr), 6000))
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	scores := make([]float64, len(hunks))
+	for i, hunk := range hunks {
+		vec, err := e.embed(ctx, truncateUTF8(hunk, 6000))
+		if err != nil {
+			return "", 0, 0, err
+		}
+		scores[i] = index.CosineSimilarity(target, vec)
+	}
+
+	order := make([]int, len(hunks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	keep := make([]bool, len(hunks))
+	used := e.countTokens(header)
+	for n, i := range order {
+		cost := e.countTokens(hunks[i])
+		if n > 0 && used+cost > limit {
+			continue
+		}
+		keep[i] = true
+		used += cost
+		kept++
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header)
+	for i, hunk := range hunks {
+		if keep[i] {
+			sb.WriteString(hunk)
+		}
+	}
+	return sb.String(), kept, len(hunks), nil
+}
@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tgenz1213/archguard/internal/config"
+	"github.com/tgenz1213/archguard/internal/index"
+	"github.com/tgenz1213/archguard/internal/llm"
+)
+
+func TestSplitHunks(t *testing.T) {
+	diff := "--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n-a\n+b\n@@ -10,1 +10,2 @@ func f() {\n c\n+d\n"
+	header, hunks := splitHunks(diff)
+	if header != "--- a/main.go\n+++ b/main.go\n" {
+		t.Errorf("unexpected header %q", header)
+	}
+	if len(hunks) != 2 || !strings.HasPrefix(hunks[1], "@@ -10,1") {
+		t.Fatalf("expected 2 hunks, got %q", hunks)
+	}
+	if header+strings.Join(hunks, "") != diff {
+		t.Errorf("header and hunks do not reassemble the diff")
+	}
+}
+
+func TestFocusDiff_KeepsRelevantHunks(t *testing.T) {
+	// Text mentioning SQL embeds along one axis and everything else along another.
+	provider := &llm.MockProvider{EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+		if strings.Contains(strings.ToLower(text), "sql") {
+			return []float32{1, 0}, nil
+		}
+		return []float32{0, 1}, nil
+	}}
+	engine := &Engine{Config: &config.Config{}, Provider: provider}
+	// Count tokens as 4 bytes each regardless of network access.
+	engine.tokenizerOnce.Do(func() { engine.tokenizerErr = errors.New("no network") })
+
+	reformat := "@@ -1,3 +1,3 @@\n" + strings.Repeat("-x := map[string]int{ \"a\": 1 }\n+x := map[string]int{\"a\": 1}\n", 5)
+	query := "@@ -40,1 +40,2 @@\n+db, _ := sql.Open(\"postgres\", dsn)\n"
+	diff := "--- a/repo.go\n+++ b/repo.go\n" + reformat + query + strings.Replace(reformat, "-1,3 +1,3", "-80,3 +80,3", 1)
+	adr := &index.ADR{Title: "No raw SQL", Content: "## Context\nQueries drift.\n\n## Decision\nUse the repository layer instead of database/sql."}
+
+	focused, kept, total, err := engine.focusDiff(context.Background(), adr, diff, 40)
+	if err != nil {
+		t.Fatalf("focusDiff failed: %v", err)
+	}
+	if kept != 1 || total != 3 {
+		t.Errorf("expected 1 of 3 hunks kept, got %d of %d", kept, total)
+	}
+	if want := "--- a/repo.go\n+++ b/repo.go\n" + query; focused != want {
+		t.Errorf("expected only the SQL hunk, got %q", focused)
+	}
+
+	if same, kept, total, _ := engine.focusDiff(context.Background(), adr, diff, 10000); same != diff || kept != total {
+		t.Errorf("expected a diff within the limit to be unchanged, kept %d of %d", kept, total)
+	}
+}
@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFileName is the gitignore-style file, read from the repository root,
+// whose patterns are merged into analysis.exclude_patterns.
+const IgnoreFileName = ".archguardignore"
+
+// IgnoreList holds the patterns of an ignore file, matched with go-git's
+// implementation of the .gitignore rules.
+type IgnoreList struct {
+	matcher gitignore.Matcher
+}
+
+// LoadIgnoreFile reads gitignore-style patterns from path. A missing file
+// yields an empty list and no error.
+func LoadIgnoreFile(path string) (*IgnoreList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &IgnoreList{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParseIgnore(lines), nil
+}
+
+// ParseIgnore builds an IgnoreList from gitignore-style lines. Blank lines and
+// comments are skipped; every other line is a pattern with .gitignore syntax.
+func ParseIgnore(lines []string) *IgnoreList {
+	var patterns []gitignore.Pattern
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// go-git matches "dir/**" against dir itself, which git does not. With
+		// the parent-directory walk in Match, "dir/*" ignores the same files
+		// while still letting "!dir/file" re-include a direct child.
+		if trimmed, ok := strings.CutSuffix(line, "/**"); ok && trimmed != "" {
+			line = trimmed + "/*"
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	return &IgnoreList{matcher: gitignore.NewMatcher(patterns)}
+}
+
+// Match reports whether the slash-separated relative path is ignored. As in
+// git, a file inside an ignored directory stays ignored even if a later "!"
+// pattern matches it, because git never looks inside that directory.
+func (l *IgnoreList) Match(name string) bool {
+	if l == nil || l.matcher == nil {
+		return false
+	}
+	name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "./")
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		if l.matcher.Match(parts[:i], true) {
+			return true
+		}
+	}
+	return l.matcher.Match(parts, false)
+}
@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreList_Match(t *testing.T) {
+	list := ParseIgnore([]string{
+		"# generated code",
+		"*.pb.go",
+		"build/",
+		"/docs/*.md",
+		"vendor/**",
+		"!vendor/keep.go",
+		"gen/",
+		"!gen/keep.go",
+		"",
+	})
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"api/v1/service.pb.go", true},
+		{"service.pb.go", true},
+		{"service.go", false},
+		{"build/out.go", true},
+		{"cmd/build/out.go", true},
+		{"build", false},
+		{"docs/intro.md", true},
+		{"pkg/docs/intro.md", false},
+		{"vendor/lib/a.go", true},
+		{"vendor/keep.go", false},
+		// A file under an excluded directory cannot be re-included.
+		{"gen/a.go", true},
+		{"gen/keep.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := list.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+
+	list, err := LoadIgnoreFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		t.Fatalf("missing file should not error: %v", err)
+	}
+	if list.Match("main.go") {
+		t.Error("empty list should not ignore anything")
+	}
+
+	path := filepath.Join(dir, IgnoreFileName)
+	if err := os.WriteFile(path, []byte("gen/*\r\n!gen/keep.go\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err = LoadIgnoreFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !list.Match("gen/a.go") || list.Match("gen/keep.go") {
+		t.Errorf("unexpected matches: gen/a.go=%v gen/keep.go=%v", list.Match("gen/a.go"), list.Match("gen/keep.go"))
+	}
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// languages maps file extensions to the language names used in retrieval
+// headers. Unlisted extensions are embedded without a Language line.
+var languages = map[string]string{
+	".go":    "Go",
+	".py":    "Python",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".scala": "Scala",
+	".cs":    "C#",
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".hpp":   "C++",
+	".rs":    "Rust",
+	".rb":    "Ruby",
+	".php":   "PHP",
+	".swift": "Swift",
+	".sql":   "SQL",
+	".sh":    "Shell",
+	".tf":    "Terraform",
+	".proto": "Protocol Buffers",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+	".json":  "JSON",
+	".md":    "Markdown",
+}
+
+// languageForPath returns the language of file judged by its extension, or ""
+// when it is not recognized.
+func languageForPath(file string) string {
+	if filepath.Base(file) == "Dockerfile" {
+		return "Dockerfile"
+	}
+	return languages[strings.ToLower(filepath.Ext(file))]
+}
+
+// fileHeader returns the "Path: ...\nLanguage: ...\n" header prepended to a
+// file's embedding text under vector_store.embed_file_header, mirroring the
+// "Title: ...\nStatus: ..." header on ADR embeddings.
+func fileHeader(file string) string {
+	header := fmt.Sprintf("Path: %s\n", file)
+	if lang := languageForPath(file); lang != "" {
+		header += fmt.Sprintf("Language: %s\n", lang)
+	}
+	return header
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LastRunFile is where check saves the results of each run, for
+// `archguard report` and as an input to `archguard compare`, relative to the
+// state directory (see config.StatePath).
+const LastRunFile = "last-run.json"
+
+// LastRun is the saved outcome of a check run.
+type LastRun struct {
+	FinishedAt   time.Time         `json:"finished_at"`
+	Args         []string          `json:"args"` // check arguments, e.g. ["--all"]
+	FilesScanned int               `json:"files_scanned"`
+	FilesSkipped int               `json:"files_skipped"`
+	ADRsIndexed  int               `json:"adrs_indexed"`
+	Known        int               `json:"known"`
+	CacheHits    int               `json:"cache_hits"`
+	LLMCalls     int               `json:"llm_calls"`
+	DurationMs   int64             `json:"duration_ms"`
+	Error        string            `json:"error,omitempty"` // Why the run failed, other than by finding violations
+	Violations   []ViolationRecord `json:"violations"`
+}
+
+// NewLastRun captures a summary, which must have been produced with
+// Engine.CollectViolations set so that it holds every violation.
+func NewLastRun(s *RunSummary, adrs int, args []string, runErr error) *LastRun {
+	run := &LastRun{
+		FinishedAt:   time.Now(),
+		Args:         args,
+		FilesScanned: s.FilesScanned,
+		FilesSkipped: s.FilesSkipped,
+		ADRsIndexed:  adrs,
+		Known:        s.Known,
+		CacheHits:    s.CacheHits,
+		LLMCalls:     s.LLMCalls,
+		DurationMs:   s.Duration.Milliseconds(),
+		Violations:   s.Records,
+	}
+	if run.Violations == nil {
+		run.Violations = []ViolationRecord{}
+	}
+	var drift *DriftDetectedError
+	if runErr != nil && !errors.As(runErr, &drift) {
+		run.Error = runErr.Error()
+	}
+	return run
+}
+
+// Summary converts the saved run back into the summary printed after a check.
+func (r *LastRun) Summary() *RunSummary {
+	s := &RunSummary{
+		FilesScanned: r.FilesScanned,
+		FilesSkipped: r.FilesSkipped,
+		Known:        r.Known,
+		CacheHits:    r.CacheHits,
+		LLMCalls:     r.LLMCalls,
+		Duration:     time.Duration(r.DurationMs) * time.Millisecond,
+		Records:      r.Violations,
+	}
+	s.Violations = len(s.NewRecords())
+	return s
+}
+
+// Write saves the run to path atomically, replacing the previous run.
+func (r *LastRun) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// ReadLastRun loads a run saved by Write.
+func ReadLastRun(path string) (*LastRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run LastRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
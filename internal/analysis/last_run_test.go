@@ -0,0 +1,55 @@
+package analysis
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLastRun_RoundTrip(t *testing.T) {
+	summary := &RunSummary{
+		FilesScanned: 4,
+		FilesSkipped: 1,
+		CacheHits:    2,
+		LLMCalls:     3,
+		Duration:     1500 * time.Millisecond,
+		Records:      []ViolationRecord{{File: "a.go", Line: 3, ADRID: "0001", ADRTitle: "Use Go", Reasoning: "r", Code: "import os"}},
+	}
+	summary.Violations = len(summary.Records)
+	path := filepath.Join(t.TempDir(), ".archguard", "last-run.json")
+
+	if err := NewLastRun(summary, 7, []string{"--all"}, &DriftDetectedError{Count: 1}).Write(path); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	run, err := ReadLastRun(path)
+	if err != nil {
+		t.Fatalf("ReadLastRun failed: %v", err)
+	}
+	if run.Error != "" {
+		t.Errorf("expected drift not to be saved as an error, got %q", run.Error)
+	}
+	if run.ADRsIndexed != 7 || !reflect.DeepEqual(run.Args, []string{"--all"}) {
+		t.Errorf("unexpected run metadata: %+v", run)
+	}
+	if got := run.Summary(); !reflect.DeepEqual(got, summary) {
+		t.Errorf("Summary() = %+v, want %+v", got, summary)
+	}
+
+	// compare accepts the saved run as a report.
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := ReadViolationRecords(f)
+	if err != nil || !reflect.DeepEqual(records, summary.Records) {
+		t.Errorf("ReadViolationRecords(last run) = %+v, %v", records, err)
+	}
+
+	if run := NewLastRun(&RunSummary{}, 0, nil, errors.New("token budget exhausted")); run.Error == "" || run.Violations == nil {
+		t.Errorf("expected the error saved and an empty violation list, got %+v", run)
+	}
+}
@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/tgenz1213/archguard/internal/index"
+	"github.com/tgenz1213/archguard/internal/llm"
+)
+
+// DefaultConflictThreshold is the similarity above which lint-adrs asks the
+// model whether two ADRs contradict each other.
+const DefaultConflictThreshold = 0.8
+
+// ADRConflict is a pair of similar ADRs the model judged contradictory.
+type ADRConflict struct {
+	A, B      *index.ADR
+	Score     float64 // Embedding similarity of the pair
+	Reasoning string
+	RuleA     string // Conflicting sentence from A
+	RuleB     string // Conflicting sentence from B
+}
+
+// LintADRs asks the model whether each pair of ADRs with an embedding
+// similarity of at least threshold contradict each other, most similar pairs
+// first. ADRs without a stored embedding are embedded on the fly. It returns
+// the conflicts found and the number of pairs checked; pairs that could not
+// be checked are reported as a warning and in the returned error.
+func (e *Engine) LintADRs(ctx context.Context, adrs []index.ADR, threshold float64) ([]ADRConflict, int, error) {
+	embeddings := make([][]float32, len(adrs))
+	for i := range adrs {
+		embeddings[i] = adrs[i].Embedding
+		if len(embeddings[i]) > 0 {
+			continue
+		}
+		vec, err := e.embed(ctx, truncateUTF8(adrs[i].Content, 6000))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to embed ADR %s: %w", adrs[i].ID, err)
+		}
+		embeddings[i] = vec
+	}
+
+	type pair struct {
+		a, b  int
+		score float64
+	}
+	var pairs []pair
+	for i := range adrs {
+		for j := i + 1; j < len(adrs); j++ {
+			if score := index.CosineSimilarity(embeddings[i], embeddings[j]); score >= threshold {
+				pairs = append(pairs, pair{i, j, score})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].score > pairs[j].score })
+
+	var conflicts []ADRConflict
+	failed := 0
+	for _, p := range pairs {
+		a, b := &adrs[p.a], &adrs[p.b]
+		e.Log("Comparing ADR %s and %s (%.2f)", a.ID, b.ID, p.score)
+		res, err := llm.CheckConflict(ctx, e.Provider, a.Title, a.Content, b.Title, b.Content, e.retryOptions())
+		if err != nil {
+			if ctx.Err() != nil {
+				return conflicts, 0, ctx.Err()
+			}
+			e.Info("Warning: could not compare ADR %s and %s: %v", a.ID, b.ID, err)
+			failed++
+			continue
+		}
+		if res.Conflict {
+			conflicts = append(conflicts, ADRConflict{A: a, B: b, Score: p.score, Reasoning: res.Reasoning, RuleA: res.RuleA, RuleB: res.RuleB})
+		}
+	}
+
+	if failed > 0 {
+		return conflicts, len(pairs), fmt.Errorf("%d of %d ADR pair(s) could not be compared", failed, len(pairs))
+	}
+	return conflicts, len(pairs), nil
+}
@@ -0,0 +1,48 @@
+package analysis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tgenz1213/archguard/internal/config"
+	"github.com/tgenz1213/archguard/internal/index"
+	"github.com/tgenz1213/archguard/internal/llm"
+)
+
+func TestLintADRs(t *testing.T) {
+	var compared []string
+	provider := &llm.MockProvider{
+		// Only ADRs mentioning logging share an embedding.
+		EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			if strings.Contains(text, "log") {
+				return []float32{1, 0}, nil
+			}
+			return []float32{0, 1}, nil
+		},
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			compared = append(compared, user)
+			return `{"conflict": true, "reasoning": "One requires zap, the other forbids it.", "rule_a": "Use zap for logging.", "rule_b": "Do not use zap."}`, nil
+		},
+	}
+	adrs := []index.ADR{
+		{ID: "0001", Title: "Structured logging", Content: "Use zap for logging."},
+		{ID: "0002", Title: "Standard library only", Content: "Do not use zap; log with slog."},
+		{ID: "0003", Title: "Use Postgres", Content: "All data lives in Postgres.", Embedding: []float32{0, 1}},
+	}
+	engine := &Engine{Config: &config.Config{}, Provider: provider}
+
+	conflicts, checked, err := engine.LintADRs(context.Background(), adrs, DefaultConflictThreshold)
+	if err != nil {
+		t.Fatalf("LintADRs failed: %v", err)
+	}
+	if checked != 1 || len(compared) != 1 {
+		t.Fatalf("expected only the two logging ADRs to be compared, checked %d pairs", checked)
+	}
+	if len(conflicts) != 1 || conflicts[0].A.ID != "0001" || conflicts[0].B.ID != "0002" || conflicts[0].RuleB != "Do not use zap." {
+		t.Errorf("unexpected conflicts: %+v", conflicts)
+	}
+	if !strings.Contains(compared[0], `<adr_a title="Structured logging">`) {
+		t.Errorf("expected the prompt to carry both ADRs, got %q", compared[0])
+	}
+}
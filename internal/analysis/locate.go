@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minQuoteOverlap is the share of a quote's tokens a window of lines must
+// contain for the fuzzy fallback to report it.
+const minQuoteOverlap = 0.6
+
+var quoteToken = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*|[0-9]+`)
+
+// locateQuote returns the 1-based line of content where quote starts. Models
+// often reindent or rewrap the code they quote, so an exact match falls back
+// to one that ignores whitespace, and then to the window of lines sharing the
+// most identifiers with the quote, which is reported as approximate. It
+// returns 0 when no line matches well enough.
+func locateQuote(content, quote string) (line int, approximate bool) {
+	lines, approximate := locateQuoteAll(content, quote)
+	if len(lines) == 0 {
+		return 0, false
+	}
+	return lines[0], approximate
+}
+
+// locateQuoteAll is locateQuote for every place the quote occurs, in order.
+// Code such as "return err" often appears many times in a file. The fuzzy
+// fallback still reports a single, approximate line.
+func locateQuoteAll(content, quote string) (lines []int, approximate bool) {
+	if strings.TrimSpace(quote) == "" {
+		return nil, false
+	}
+	for offset := 0; ; {
+		idx := strings.Index(content[offset:], quote)
+		if idx == -1 {
+			break
+		}
+		offset += idx
+		lines = append(lines, strings.Count(content[:offset], "\n")+1)
+		offset++
+	}
+	if len(lines) > 0 {
+		return lines, false
+	}
+
+	split := strings.Split(content, "\n")
+	if lines := locateNormalized(split, quote); len(lines) > 0 {
+		return lines, false
+	}
+	if line := locateByTokens(split, quote); line > 0 {
+		return []int{line}, true
+	}
+	return nil, false
+}
+
+// locateNormalized matches quote against content with every run of
+// whitespace, including line breaks, collapsed to a single space, and returns
+// the line of each match.
+func locateNormalized(lines []string, quote string) []int {
+	needle := strings.Join(strings.Fields(quote), " ")
+	var haystack strings.Builder
+	starts := make([]int, len(lines))
+	for i, l := range lines {
+		starts[i] = haystack.Len()
+		if fields := strings.Fields(l); len(fields) > 0 {
+			haystack.WriteString(strings.Join(fields, " "))
+			haystack.WriteString(" ")
+		}
+	}
+	text := haystack.String()
+	var found []int
+	for offset := 0; ; offset++ {
+		idx := strings.Index(text[offset:], needle)
+		if idx == -1 {
+			return found
+		}
+		offset += idx
+		// The match starts on the last line beginning at or before offset.
+		line := sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+		if len(found) == 0 || found[len(found)-1] != line {
+			found = append(found, line)
+		}
+	}
+}
+
+// locateByTokens scores each window of as many lines as the quote has
+// non-blank lines by the share of the quote's distinct tokens it contains, and
+// returns the first line of the best window scoring at least minQuoteOverlap.
+func locateByTokens(lines []string, quote string) int {
+	want := make(map[string]bool)
+	span := 0
+	for _, l := range strings.Split(quote, "\n") {
+		if strings.TrimSpace(l) != "" {
+			span++
+		}
+		for _, tok := range quoteToken.FindAllString(l, -1) {
+			want[tok] = true
+		}
+	}
+	if len(want) < 2 {
+		return 0
+	}
+
+	best, bestScore := 0, 0.0
+	for i := range lines {
+		found := make(map[string]bool)
+		for _, l := range lines[i:min(i+span, len(lines))] {
+			for _, tok := range quoteToken.FindAllString(l, -1) {
+				if want[tok] {
+					found[tok] = true
+				}
+			}
+		}
+		if score := float64(len(found)) / float64(len(want)); score > bestScore {
+			best, bestScore = i+1, score
+		}
+	}
+	if bestScore < minQuoteOverlap {
+		return 0
+	}
+	// Start at the window's first line that shares a token with the quote.
+	for best < len(lines) && !hasAnyToken(lines[best-1], want) {
+		best++
+	}
+	return best
+}
+
+func hasAnyToken(line string, want map[string]bool) bool {
+	for _, tok := range quoteToken.FindAllString(line, -1) {
+		if want[tok] {
+			return true
+		}
+	}
+	return false
+}
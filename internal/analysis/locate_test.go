@@ -0,0 +1,61 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocateQuote(t *testing.T) {
+	content := `package store
+
+func Open(dsn string) (*DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT id, name FROM users WHERE active = true")
+	return &DB{db}, nil
+}
+`
+	tests := []struct {
+		name        string
+		quote       string
+		line        int
+		approximate bool
+	}{
+		{"exact", `db, err := sql.Open("postgres", dsn)`, 4, false},
+		{"reindented and rewrapped", "if err != nil { return nil, err }", 5, false},
+		{"reformatted call", `rows, err := db.Query( "SELECT id, name FROM users WHERE active = true" ) // raw SQL`, 8, true},
+		{"unrelated code", `fmt.Println("hello world")`, 0, false},
+		{"empty quote", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, approximate := locateQuote(content, tt.quote)
+			if line != tt.line || approximate != tt.approximate {
+				t.Errorf("locateQuote(%q) = %d, %v; want %d, %v", tt.quote, line, approximate, tt.line, tt.approximate)
+			}
+		})
+	}
+}
+
+func TestLocateQuoteAll(t *testing.T) {
+	content := "func a() error {\n\treturn err\n}\n\nfunc b() error {\n\treturn err\n}\n"
+	tests := []struct {
+		name  string
+		quote string
+		lines []int
+	}{
+		{"exact", "return err", []int{2, 6}},
+		{"reindented", "return  err", []int{2, 6}},
+		{"single", "func b() error {", []int{5}},
+		{"unrelated", `fmt.Println("hello world")`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if lines, _ := locateQuoteAll(content, tt.quote); !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("locateQuoteAll(%q) = %v; want %v", tt.quote, lines, tt.lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// markdownInlineReasoning is the longest reasoning shown inline; longer
+// reasoning, and any quoted code, goes in a collapsed <details> block.
+const markdownInlineReasoning = 160
+
+// WriteMarkdown renders the summary's new Records, which must have been
+// collected with Engine.CollectViolations, as a Markdown report for pull request
+// descriptions and chat: a table of violations per ADR, then the violations
+// of each file. When linkBase is set, e.g.
+// "https://github.com/org/repo/blob/<sha>", files and lines link to
+// linkBase/<file>#L<line>. adrs is the number of ADRs in the index.
+func (s *RunSummary) WriteMarkdown(w io.Writer, adrs int, linkBase string) error {
+	var b strings.Builder
+	result := "PASS"
+	if s.Violations > 0 {
+		result = "FAIL"
+	}
+	b.WriteString("## ArchGuard Report\n\n")
+	fmt.Fprintf(&b, "**%s**: %d violation(s)", result, s.Violations)
+	if s.Known > 0 {
+		fmt.Fprintf(&b, " (%d known from the baseline)", s.Known)
+	}
+	fmt.Fprintf(&b, " | %d files scanned | %d ADRs indexed | %s\n",
+		s.FilesScanned, adrs, s.Duration.Round(time.Millisecond))
+
+	records := s.NewRecords()
+	if len(records) == 0 {
+		b.WriteString("\nNo architectural violations found.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].File != records[j].File {
+			return records[i].File < records[j].File
+		}
+		return records[i].Line < records[j].Line
+	})
+
+	type adrRow struct {
+		id, title  string
+		violations int
+		files      map[string]bool
+	}
+	var rows []*adrRow
+	byADR := make(map[string]*adrRow)
+	for _, rec := range records {
+		row, ok := byADR[rec.ADRID]
+		if !ok {
+			row = &adrRow{id: rec.ADRID, title: rec.ADRTitle, files: make(map[string]bool)}
+			byADR[rec.ADRID] = row
+			rows = append(rows, row)
+		}
+		row.violations++
+		row.files[rec.File] = true
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].violations > rows[j].violations })
+
+	b.WriteString("\n### Violations by ADR\n\n")
+	b.WriteString("| ADR | Title | Violations | Files |\n")
+	b.WriteString("| --- | --- | ---: | ---: |\n")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "| %s | %s | %d | %d |\n", markdownCell(row.id), markdownCell(row.title), row.violations, len(row.files))
+	}
+
+	b.WriteString("\n### Details\n")
+	for i, rec := range records {
+		if i == 0 || rec.File != records[i-1].File {
+			fmt.Fprintf(&b, "\n#### %s\n\n", markdownLink("`"+rec.File+"`", linkBase, rec.File, 0))
+		}
+		line := "line ?"
+		if rec.Line > 0 {
+			line = fmt.Sprintf("line %d", rec.Line)
+			if rec.LineApproximate {
+				line += ", approximate"
+			}
+			line = markdownLink(line, linkBase, rec.File, rec.Line)
+		}
+		fmt.Fprintf(&b, "- **%s** (%s, %s)", markdownText(rec.ADRTitle), markdownText(rec.ADRID), line)
+
+		reasoning := strings.Join(strings.Fields(rec.Reasoning), " ")
+		if len(reasoning) <= markdownInlineReasoning && rec.Code == "" && rec.Rule == "" {
+			fmt.Fprintf(&b, ": %s\n", markdownText(reasoning))
+			continue
+		}
+		b.WriteString("\n  <details><summary>Reasoning</summary>\n\n")
+		fmt.Fprintf(&b, "  %s\n", markdownText(reasoning))
+		if rec.Rule != "" {
+			fmt.Fprintf(&b, "\n  Rule: %s\n", markdownText(rec.Rule))
+		}
+		if rec.Code != "" {
+			fence := markdownFence(rec.Code)
+			fmt.Fprintf(&b, "\n  %s\n", fence)
+			for _, l := range strings.Split(strings.TrimRight(rec.Code, "\n"), "\n") {
+				fmt.Fprintf(&b, "  %s\n", l)
+			}
+			fmt.Fprintf(&b, "  %s\n", fence)
+		}
+		b.WriteString("\n  </details>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownLink links text to file (and line, when positive) under base, or
+// returns text unchanged when base is empty.
+func markdownLink(text, base, file string, line int) string {
+	if base == "" {
+		return text
+	}
+	url := strings.TrimSuffix(base, "/") + "/" + strings.ReplaceAll(file, " ", "%20")
+	if line > 0 {
+		url += fmt.Sprintf("#L%d", line)
+	}
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
+
+// markdownText keeps model output from opening HTML tags or breaking out of
+// the list item it is rendered in.
+func markdownText(s string) string {
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// markdownCell escapes s for a table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(markdownText(s), "|", `\|`)
+}
+
+// markdownFence returns a code fence longer than any backtick run in code.
+func markdownFence(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
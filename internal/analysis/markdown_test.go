@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	summary := &RunSummary{
+		FilesScanned: 3,
+		Violations:   3,
+		Records: []ViolationRecord{
+			{File: "b.go", Line: 9, ADRID: "0002", ADRTitle: "No | Pipes", Reasoning: "Short."},
+			{File: "a.go", Line: 4, ADRID: "0001", ADRTitle: "Use Go", Reasoning: strings.Repeat("Long reasoning. ", 20), Code: "x := ```y```"},
+			{File: "a.go", Line: 2, ADRID: "0001", ADRTitle: "Use Go", Reasoning: "Uses <script>.", LineApproximate: true},
+			{File: "c.go", Line: 1, ADRID: "0003", ADRTitle: "Known Debt", Reasoning: "Old.", Baseline: true},
+		},
+	}
+	var out strings.Builder
+	if err := summary.WriteMarkdown(&out, 5, "https://github.com/o/r/blob/abc/"); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	got := out.String()
+
+	for _, want := range []string{
+		"**FAIL**: 3 violation(s) | 3 files scanned | 5 ADRs indexed",
+		"| 0001 | Use Go | 2 | 1 |\n| 0002 | No \\| Pipes | 1 | 1 |",
+		"#### [`a.go`](https://github.com/o/r/blob/abc/a.go)",
+		"(0001, [line 2, approximate](https://github.com/o/r/blob/abc/a.go#L2)): Uses &lt;script>.",
+		"<details><summary>Reasoning</summary>",
+		"  ````\n  x := ```y```\n  ````",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "c.go") || strings.Contains(got, "Known Debt") {
+		t.Errorf("expected known violations to be left out, got:\n%s", got)
+	}
+	if strings.Index(got, "a.go#L2") > strings.Index(got, "a.go#L4") || strings.Index(got, "a.go#L4") > strings.Index(got, "b.go#L9") {
+		t.Errorf("expected details sorted by file and line, got:\n%s", got)
+	}
+
+	out.Reset()
+	if err := (&RunSummary{FilesScanned: 1}).WriteMarkdown(&out, 5, ""); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "**PASS**") || !strings.Contains(out.String(), "No architectural violations found.") {
+		t.Errorf("unexpected clean report:\n%s", out.String())
+	}
+}
@@ -0,0 +1,59 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteMetrics writes the summary as gauges in the Prometheus text exposition
+// format, for node_exporter's textfile collector. adrs is the number of ADRs
+// in the index.
+func (s *RunSummary) WriteMetrics(w io.Writer, adrs int) error {
+	metrics := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"archguard_violations_total", "Violations reported by the last run.", float64(s.Violations)},
+		{"archguard_violations_known", "Violations matched against the --baseline report.", float64(s.Known)},
+		{"archguard_files_scanned", "Files analyzed by the last run.", float64(s.FilesScanned)},
+		{"archguard_files_skipped", "Files dropped by exclude patterns or .archguardignore.", float64(s.FilesSkipped)},
+		{"archguard_adrs_indexed", "ADRs in the index used by the last run.", float64(adrs)},
+		{"archguard_cache_hits", "Analyses served from the cache.", float64(s.CacheHits)},
+		{"archguard_llm_calls", "Analyses that required an LLM call.", float64(s.LLMCalls)},
+		{"archguard_duration_seconds", "Wall-clock duration of the last run.", s.Duration.Seconds()},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", m.name, m.help, m.name, m.name, m.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMetricsFile writes the summary's metrics to path atomically, so the
+// textfile collector never reads a partially written file.
+func (s *RunSummary) WriteMetricsFile(path string, adrs int) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteMetrics(f, adrs); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
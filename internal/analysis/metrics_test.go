@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSummary_WriteMetricsFile(t *testing.T) {
+	s := &RunSummary{FilesScanned: 12, FilesSkipped: 3, Violations: 2, CacheHits: 5, LLMCalls: 7, Duration: 1500 * time.Millisecond}
+	path := filepath.Join(t.TempDir(), "textfile", "archguard.prom")
+
+	if err := s.WriteMetricsFile(path, 4); err != nil {
+		t.Fatalf("WriteMetricsFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"# TYPE archguard_violations_total gauge\narchguard_violations_total 2\n",
+		"archguard_files_scanned 12\n",
+		"archguard_files_skipped 3\n",
+		"archguard_adrs_indexed 4\n",
+		"archguard_cache_hits 5\n",
+		"archguard_llm_calls 7\n",
+		"archguard_duration_seconds 1.5\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%s", want, out)
+		}
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected temp file to be renamed away, stat err = %v", err)
+	}
+}
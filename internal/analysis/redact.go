@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces each secret matched by analysis.redact_patterns.
+const Redacted = "[REDACTED]"
+
+// RedactDefaults is the analysis.redact_patterns entry that expands to
+// DefaultRedactPatterns.
+const RedactDefaults = "default"
+
+// DefaultRedactPatterns match common secret shapes: AWS access key IDs and
+// secret keys, JWTs, private key blocks, and GitHub and Slack tokens.
+var DefaultRedactPatterns = []string{
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`,
+	`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`,
+	`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(?:-----END [A-Z ]*PRIVATE KEY-----|\z)`,
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+}
+
+// compileRedactPatterns compiles analysis.redact_patterns, expanding
+// RedactDefaults.
+func compileRedactPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		expanded := []string{p}
+		if p == RedactDefaults {
+			expanded = DefaultRedactPatterns
+		}
+		for _, e := range expanded {
+			re, err := regexp.Compile(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid analysis.redact_patterns entry %q: %w", p, err)
+			}
+			res = append(res, re)
+		}
+	}
+	return res, nil
+}
+
+// RedactingProvider replaces secrets in the content and diffs of another
+// provider with Redacted, so they are neither embedded nor sent to the chat
+// model. Everything downstream, including cache keys and quoted-code line
+// lookup, sees the redacted text.
+type RedactingProvider struct {
+	ContentProvider
+	patterns []*regexp.Regexp
+}
+
+// NewRedactingProvider wraps p to redact matches of patterns, or returns p
+// unchanged when there are none.
+func NewRedactingProvider(p ContentProvider, patterns []string) (ContentProvider, error) {
+	res, err := compileRedactPatterns(patterns)
+	if err != nil || len(res) == 0 || p == nil {
+		return p, err
+	}
+	return &RedactingProvider{ContentProvider: p, patterns: res}, nil
+}
+
+func (p *RedactingProvider) setContextLines(n int) { SetDiffContextLines(p.ContentProvider, n) }
+
+func (p *RedactingProvider) setFollowExternalSymlinks(follow bool) {
+	SetFollowExternalSymlinks(p.ContentProvider, follow)
+}
+
+func (p *RedactingProvider) GetContent(path string) (string, error) {
+	content, err := p.ContentProvider.GetContent(path)
+	return p.redact(content, false), err
+}
+
+func (p *RedactingProvider) GetDiff(path string) (string, error) {
+	diff, err := p.ContentProvider.GetDiff(path)
+	return p.redact(diff, true), err
+}
+
+// redact replaces every match with Redacted. A match spanning lines, such as
+// a private key block, keeps its line breaks so line numbers still line up,
+// and in a diff each continuation line keeps its +, -, or space marker.
+func (p *RedactingProvider) redact(s string, diff bool) string {
+	for _, re := range p.patterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			lines := strings.Split(match, "\n")
+			for i, line := range lines {
+				marker := ""
+				if diff && i > 0 && line != "" && strings.ContainsRune("+- ", rune(line[0])) {
+					marker = line[:1]
+				}
+				if line != "" {
+					lines[i] = marker + Redacted
+				}
+			}
+			return strings.Join(lines, "\n")
+		})
+	}
+	return s
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tgenz1213/archguard/internal/index"
+	"github.com/tgenz1213/archguard/internal/llm"
+)
+
+// Select analyzes file against adr alone and writes the system prompt, the
+// prompt, the raw response, and the parsed result to w, to reproduce a single
+// analysis (check --select). Retrieval, scope, ignore directives, and the
+// cache are bypassed; the code sent is what check would send for the file,
+// except that files over llm.max_tokens are sent truncated rather than in
+// chunks.
+func (e *Engine) Select(ctx context.Context, adr *index.ADR, file string, w io.Writer) (*llm.AnalysisResult, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	content, mode, err := e.fetchContext(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	system := systemPrompt(e.Config.ForPath(file))
+
+	fmt.Fprintf(w, "ADR:     %s (%s, %s)\n", adr.ID, adr.Title, adr.RelPath)
+	fmt.Fprintf(w, "File:    %s (context: %s)\n", file, mode)
+	fmt.Fprintf(w, "\n=== System prompt ===\n%s\n", system)
+	fmt.Fprintf(w, "\n=== Prompt ===\n%s\n", llm.GetAnalyzeDriftPrompt(adr.Content, content, file))
+
+	e.llmCalls.Add(1)
+	res, err := llm.AnalyzeDriftWithOptions(ctx, e.Provider, adr.Content, content, file, system, e.retryOptions())
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(w, "\n=== Raw response ===\n%s\n", res.Raw)
+
+	parsed := *res
+	parsed.Raw = ""
+	data, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(w, "\n=== Parsed result ===\n%s\n", data)
+	if res.Violation {
+		line, approximate := e.fileLineNumber(file, res.QuotedCode)
+		switch {
+		case line == 0:
+			fmt.Fprintf(w, "Quoted code not found in %s\n", file)
+		case approximate:
+			fmt.Fprintf(w, "Quoted code is at %s:%d (approximate)\n", file, line)
+		default:
+			fmt.Fprintf(w, "Quoted code is at %s:%d\n", file, line)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IgnoreDirective is the comment text that suppresses the ADR with the given
+// ID for a whole file. It must appear within the first 2000 bytes analyzed.
+func IgnoreDirective(adrID string) string {
+	return "archguard-ignore: " + adrID
+}
+
+// AddIgnoreDirective inserts an IgnoreDirective comment for adrID at the top
+// of file, after a shebang line, using the file's line comment syntax. A file
+// that already has the directive near its top is left unchanged.
+func AddIgnoreDirective(file, adrID string) error {
+	style, ok := commentStyles[languageForPath(file)]
+	if !ok || len(style.line) == 0 {
+		return fmt.Errorf("no known comment syntax for %s", file)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	content := string(data)
+	header := content
+	if len(header) > 2000 {
+		header = header[:2000]
+	}
+	if strings.Contains(header, IgnoreDirective(adrID)) {
+		return nil
+	}
+
+	var prefix string
+	if strings.HasPrefix(content, "#!") {
+		var shebang string
+		shebang, content, _ = strings.Cut(content, "\n")
+		prefix = shebang + "\n"
+	}
+	// The blank line keeps the directive from becoming a Go package comment.
+	directive := style.line[0] + " " + IgnoreDirective(adrID) + "\n\n"
+	return os.WriteFile(file, []byte(prefix+directive+content), info.Mode().Perm())
+}
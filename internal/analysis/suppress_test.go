@@ -0,0 +1,41 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddIgnoreDirective(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content, want string
+	}{
+		{"main.go", "package main\n", "// archguard-ignore: 0003\n\npackage main\n"},
+		{"run.sh", "#!/bin/sh\necho hi\n", "#!/bin/sh\n# archguard-ignore: 0003\n\necho hi\n"},
+		{"q.sql", "SELECT 1;\n", "-- archguard-ignore: 0003\n\nSELECT 1;\n"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// The second call finds the directive and leaves the file alone.
+		for range 2 {
+			if err := AddIgnoreDirective(path, "0003"); err != nil {
+				t.Fatalf("%s: AddIgnoreDirective failed: %v", tt.name, err)
+			}
+		}
+		if got, _ := os.ReadFile(path); string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddIgnoreDirective(notes, "0003"); err == nil {
+		t.Error("expected an error for a file without a known comment syntax")
+	}
+}
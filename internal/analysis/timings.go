@@ -0,0 +1,73 @@
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Timing phases recorded by the engine.
+const (
+	PhaseContext   = "context"
+	PhaseEmbedding = "embedding"
+	PhaseSearch    = "vector search"
+	PhaseCacheIO   = "cache io"
+	PhaseLLM       = "llm chat"
+)
+
+// Timings aggregates wall-clock durations per phase across concurrent workers.
+// A nil *Timings records nothing, so callers need no enabled check.
+type Timings struct {
+	mu     sync.Mutex
+	totals map[string]time.Duration
+	counts map[string]int
+}
+
+// NewTimings returns an empty Timings.
+func NewTimings() *Timings {
+	return &Timings{
+		totals: make(map[string]time.Duration),
+		counts: make(map[string]int),
+	}
+}
+
+// Track starts timing phase and returns a func that records the elapsed time,
+// meant for use as: defer t.Track(PhaseLLM)()
+func (t *Timings) Track(phase string) func() {
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		t.mu.Lock()
+		t.totals[phase] += elapsed
+		t.counts[phase]++
+		t.mu.Unlock()
+	}
+}
+
+// Print writes one line per phase, slowest first. Durations are summed across
+// workers, so they can exceed the run's wall-clock time.
+func (t *Timings) Print(w io.Writer) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phases := make([]string, 0, len(t.totals))
+	for phase := range t.totals {
+		phases = append(phases, phase)
+	}
+	sort.Slice(phases, func(i, j int) bool { return t.totals[phases[i]] > t.totals[phases[j]] })
+
+	fmt.Fprintln(w, "Timings (summed across workers):")
+	for _, phase := range phases {
+		total, count := t.totals[phase], t.counts[phase]
+		fmt.Fprintf(w, "  %-14s %10s total  %5d calls  %10s avg\n", phase,
+			total.Round(time.Millisecond), count, (total / time.Duration(count)).Round(time.Microsecond))
+	}
+}
@@ -0,0 +1,35 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTimings_TrackAndPrint(t *testing.T) {
+	timings := NewTimings()
+	timings.Track(PhaseLLM)()
+	timings.Track(PhaseLLM)()
+	timings.Track(PhaseEmbedding)()
+
+	var sb strings.Builder
+	timings.Print(&sb)
+	out := sb.String()
+
+	if !strings.Contains(out, PhaseLLM) || !strings.Contains(out, PhaseEmbedding) {
+		t.Fatalf("expected both phases in output, got:\n%s", out)
+	}
+	if timings.counts[PhaseLLM] != 2 {
+		t.Errorf("expected 2 llm calls, got %d", timings.counts[PhaseLLM])
+	}
+}
+
+func TestTimings_NilIsNoop(t *testing.T) {
+	var timings *Timings
+	timings.Track(PhaseLLM)()
+
+	var sb strings.Builder
+	timings.Print(&sb)
+	if sb.Len() != 0 {
+		t.Errorf("expected no output from nil Timings, got %q", sb.String())
+	}
+}
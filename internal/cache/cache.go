@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/tgenz1213/archguard/internal/llm"
 )
@@ -16,7 +18,12 @@ type Cache struct {
 }
 
 func NewCache(projectRoot string) (*Cache, error) {
-	cacheDir := filepath.Join(projectRoot, ".archguard", "cache")
+	return NewCacheWithDir(filepath.Join(projectRoot, ".archguard", "cache"))
+}
+
+// NewCacheWithDir creates a cache rooted at an explicit directory, which may be
+// shared between checkouts or CI runners.
+func NewCacheWithDir(cacheDir string) (*Cache, error) {
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create cache dir: %w", err)
 	}
@@ -41,26 +48,197 @@ func (c *Cache) Get(key string) (*llm.AnalysisResult, bool, error) {
 	return &res, true, nil
 }
 
+// Put writes the result to a uniquely named temp file and renames it into place,
+// so concurrent writers sharing the cache never observe a partially written entry.
 func (c *Cache) Put(key string, res *llm.AnalysisResult) error {
-	path := filepath.Join(c.Dir, key+".json")
 	data, err := json.Marshal(res)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return writeAtomic(c.Dir, key, data)
 }
 
-func ComputeAnalysisKey(modelName, adrContent, fileContent, systemPrompt, userPromptTemplate string) string {
+// embeddingDir holds cached embeddings, kept apart from analysis entries so
+// Entries and Prune only ever see analysis results.
+const embeddingDir = "embeddings"
+
+// GetEmbedding returns the cached vector for a key from ComputeEmbeddingKey.
+func (c *Cache) GetEmbedding(key string) ([]float32, bool, error) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, embeddingDir, key+".json"))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var vec []float32
+	if err := json.Unmarshal(data, &vec); err != nil {
+		return nil, false, err
+	}
+	return vec, true, nil
+}
+
+// PutEmbedding stores a vector under a key from ComputeEmbeddingKey.
+func (c *Cache) PutEmbedding(key string, vec []float32) error {
+	dir := filepath.Join(c.Dir, embeddingDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(vec)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(dir, key, data)
+}
+
+// writeAtomic writes data to dir/key.json via a temp file and rename.
+func writeAtomic(dir, key string, data []byte) error {
+	path := filepath.Join(dir, key+".json")
+	tmp, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Entry describes a single cached analysis result on disk.
+type Entry struct {
+	Key     string
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Entries lists all cached analysis results.
+func (c *Cache) Entries() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".json") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{
+			Key:     strings.TrimSuffix(de.Name(), ".json"),
+			Path:    filepath.Join(c.Dir, de.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	return entries, nil
+}
+
+// PruneStats summarizes the entries removed (or, in a dry run, removable) by Prune.
+type PruneStats struct {
+	Scanned int
+	Removed int
+	Bytes   int64
+}
+
+// Prune deletes every entry for which stale returns true. When dryRun is set,
+// nothing is deleted and the stats report what would be freed.
+func (c *Cache) Prune(stale func(Entry) bool, dryRun bool) (PruneStats, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return PruneStats{}, err
+	}
+
+	stats := PruneStats{Scanned: len(entries)}
+	for _, entry := range entries {
+		if !stale(entry) {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+				return stats, fmt.Errorf("failed to remove cache entry %s: %w", entry.Key, err)
+			}
+		}
+		stats.Removed++
+		stats.Bytes += entry.Size
+	}
+	return stats, nil
+}
+
+// ComputeEmbeddingKey derives the embedding cache key. Embeddings depend only
+// on the embedding model and the text, so they survive changes to ADRs,
+// prompts, or the chat model that invalidate analysis entries.
+func ComputeEmbeddingKey(embedModel, text string) string {
+	textSum := sha256.Sum256([]byte(text))
 	h := sha256.New()
-	h.Write([]byte(modelName))
-	h.Write([]byte("||"))
-	h.Write([]byte(adrContent))
-	h.Write([]byte("||"))
-	h.Write([]byte(fileContent))
+	h.Write([]byte(embedModel))
 	h.Write([]byte("||"))
-	h.Write([]byte(systemPrompt))
-	h.Write([]byte("||"))
-	h.Write([]byte(userPromptTemplate))
+	h.Write(textSum[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// KeyComponent is one input of an analysis cache key, as shown by
+// check --explain-cache.
+type KeyComponent struct {
+	Name  string
+	Value string
+}
+
+// analysisKeyInputs lists the raw inputs of an analysis cache key in the
+// order they are hashed, so the key and its explanation cannot drift apart.
+func analysisKeyInputs(modelName, adrContent, fileContent, systemPrompt, userPromptTemplate string) []KeyComponent {
+	return []KeyComponent{
+		{"model", modelName},
+		{"adr content", adrContent},
+		{"file content", fileContent},
+		{"system prompt", systemPrompt},
+		{"prompt template", userPromptTemplate},
+	}
+}
+
+// AnalysisKeyComponents describes the inputs ComputeAnalysisKey hashes: the
+// model name verbatim, and a short SHA-256 and byte length of each text, so
+// the output of two runs can be compared to see which input changed.
+func AnalysisKeyComponents(modelName, adrContent, fileContent, systemPrompt, userPromptTemplate string) []KeyComponent {
+	components := analysisKeyInputs(modelName, adrContent, fileContent, systemPrompt, userPromptTemplate)
+	for i := range components[1:] {
+		c := &components[i+1]
+		sum := sha256.Sum256([]byte(c.Value))
+		c.Value = fmt.Sprintf("sha256:%s (%d bytes)", hex.EncodeToString(sum[:6]), len(c.Value))
+	}
+	return components
+}
+
+func ComputeAnalysisKey(modelName, adrContent, fileContent, systemPrompt, userPromptTemplate string) string {
+	h := sha256.New()
+	for i, c := range analysisKeyInputs(modelName, adrContent, fileContent, systemPrompt, userPromptTemplate) {
+		if i > 0 {
+			h.Write([]byte("||"))
+		}
+		h.Write([]byte(c.Value))
+	}
 	sum := h.Sum(nil)
 	return hex.EncodeToString(sum)
 }
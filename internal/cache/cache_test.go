@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tgenz1213/archguard/internal/llm"
+)
+
+func TestNewCacheWithDir_CreatesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shared", "cache")
+
+	c, err := NewCacheWithDir(dir)
+	if err != nil {
+		t.Fatalf("NewCacheWithDir failed: %v", err)
+	}
+	if c.Dir != dir {
+		t.Errorf("expected Dir %q, got %q", dir, c.Dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("expected cache directory to exist: %v", err)
+	}
+}
+
+func TestCache_PutGet_ConcurrentWriters(t *testing.T) {
+	c, err := NewCacheWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewCacheWithDir failed: %v", err)
+	}
+
+	const key = "shared-key"
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res := &llm.AnalysisResult{Reasoning: fmt.Sprintf("writer %d", i)}
+			if err := c.Put(key, res); err != nil {
+				t.Errorf("Put failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	res, found, err := c.Get(key)
+	if err != nil || !found {
+		t.Fatalf("expected cache hit, got found=%v err=%v", found, err)
+	}
+	if !strings.HasPrefix(res.Reasoning, "writer ") {
+		t.Errorf("unexpected cached reasoning %q", res.Reasoning)
+	}
+
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("temp file %s was not cleaned up", e.Name())
+		}
+	}
+}
+
+func TestCache_Prune(t *testing.T) {
+	c, err := NewCacheWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewCacheWithDir failed: %v", err)
+	}
+	for _, key := range []string{"live", "stale-a", "stale-b"} {
+		if err := c.Put(key, &llm.AnalysisResult{Reasoning: key}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	stale := func(e Entry) bool { return strings.HasPrefix(e.Key, "stale") }
+
+	stats, err := c.Prune(stale, true)
+	if err != nil {
+		t.Fatalf("dry-run Prune failed: %v", err)
+	}
+	if stats.Scanned != 3 || stats.Removed != 2 || stats.Bytes == 0 {
+		t.Errorf("unexpected dry-run stats: %+v", stats)
+	}
+	if entries, _ := c.Entries(); len(entries) != 3 {
+		t.Fatalf("dry run must not delete entries, have %d", len(entries))
+	}
+
+	if _, err := c.Prune(stale, false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	entries, err := c.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Key != "live" {
+		t.Errorf("expected only the live entry to remain, got %+v", entries)
+	}
+}
+
+func TestCache_EmbeddingRoundTrip(t *testing.T) {
+	c, err := NewCacheWithDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := ComputeEmbeddingKey("nomic-embed-text", "package main")
+	if key == ComputeEmbeddingKey("other-model", "package main") {
+		t.Error("expected embedding key to depend on the model")
+	}
+
+	if _, found, err := c.GetEmbedding(key); err != nil || found {
+		t.Fatalf("expected a miss on an empty cache, got found=%v err=%v", found, err)
+	}
+	want := []float32{0.25, -1, 3.5}
+	if err := c.PutEmbedding(key, want); err != nil {
+		t.Fatalf("PutEmbedding failed: %v", err)
+	}
+	got, found, err := c.GetEmbedding(key)
+	if err != nil || !found || !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetEmbedding = %v, %v, %v; want %v", got, found, err, want)
+	}
+
+	entries, err := c.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected embeddings to be kept out of analysis entries, got %d", len(entries))
+	}
+}
+
+func TestComputeAnalysisKey_MatchesComponents(t *testing.T) {
+	sum := sha256.Sum256([]byte("model||adr||file||system||template"))
+	if got, want := ComputeAnalysisKey("model", "adr", "file", "system", "template"), hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("expected the key to hash the components in order, got %s want %s", got, want)
+	}
+
+	components := AnalysisKeyComponents("model", "adr", "file", "system", "template")
+	if len(components) != 5 || components[0].Value != "model" {
+		t.Fatalf("expected five components starting with the model, got %+v", components)
+	}
+	adr := sha256.Sum256([]byte("adr"))
+	if want := "sha256:" + hex.EncodeToString(adr[:6]) + " (3 bytes)"; components[1].Value != want {
+		t.Errorf("expected the ADR content digested, got %q", components[1].Value)
+	}
+}
@@ -4,19 +4,31 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/tgenz1213/archguard/internal/analysis"
+	"github.com/tgenz1213/archguard/internal/cache"
 	"github.com/tgenz1213/archguard/internal/config"
 	"github.com/tgenz1213/archguard/internal/git"
+	"github.com/tgenz1213/archguard/internal/github"
 	"github.com/tgenz1213/archguard/internal/index"
 	"github.com/tgenz1213/archguard/internal/llm"
+	pkgllm "github.com/tgenz1213/archguard/pkg/llm"
+	"gopkg.in/yaml.v3"
 )
 
 type ExitCode int
@@ -33,24 +45,68 @@ const (
 const defaultADRPath = "./docs/arch"
 const configFilename = "archguard.yaml"
 
+// defaultBaselineFile receives violations accepted with check --interactive
+// when --baseline is not given.
+const defaultBaselineFile = "archguard-baseline.jsonl"
+
+// NoBannerEnv, when set to a non-empty value, suppresses the banner like
+// --quiet.
+const NoBannerEnv = "ARCHGUARD_NO_BANNER"
+
+// ProgramName is the command name shown in usage. Wrappers that ship the CLI
+// under another name can override it before calling Execute.
+var ProgramName = "archguard"
+
 // Execute parses the command-line arguments, normalizes paths relative to the git root,
 // and routes execution to the appropriate command handler.
 func Execute(providerFactory func(*config.Config) llm.Provider) (ExitCode, error) {
-	fmt.Println("ArchGuard - Architectural Drift Detector")
+	// --quiet is accepted by every command, so it is removed before the
+	// subcommand flag sets see it.
+	var quiet bool
+	os.Args, quiet = removeFlag(os.Args, "quiet")
+
+	// schema writes machine-readable output, so it runs before the banner and
+	// does not require a git repository.
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		return runSchema()
+	}
 
-	repoRoot, err := git.GetRepoRoot()
-	if err != nil {
-		return ExitError, fmt.Errorf("%v (ArchGuard must be run inside a git repository)", err)
+	if showBanner(os.Args[1:], quiet) {
+		fmt.Println("ArchGuard - Architectural Drift Detector")
+	}
+
+	// compare only reads two report files, so it does not need a repository.
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		return runCompare(os.Args[2:])
 	}
 
+	// --no-git treats the current directory as the root of a plain source
+	// tree, e.g. an extracted tarball in a CI stage.
 	cwd, _ := os.Getwd()
+	repoRoot := cwd
+	if len(os.Args) < 3 || !hasFlag(os.Args[2:], "no-git") {
+		root, err := git.GetRepoRoot()
+		if err != nil {
+			return ExitError, fmt.Errorf("%v (ArchGuard must be run inside a git repository, or pass --no-git)", err)
+		}
+		repoRoot = root
+	}
+
 	repoRoot = filepath.Clean(repoRoot)
 	cwd = filepath.Clean(cwd)
 
+	// scanRoot is the directory ArchGuard was invoked from, relative to the
+	// repo root; check limits its scan to this subtree unless --repo-wide.
+	scanRoot := ""
 	if !strings.EqualFold(cwd, repoRoot) {
+		if rel, err := filepath.Rel(repoRoot, cwd); err == nil && rel != "." {
+			scanRoot = filepath.ToSlash(rel)
+		}
+
 		for i := 2; i < len(os.Args); i++ {
 			arg := os.Args[i]
-			if !strings.HasPrefix(arg, "-") {
+			// Absolute paths (e.g. ADRs in a sibling checkout) are already unambiguous.
+			if !strings.HasPrefix(arg, "-") && !filepath.IsAbs(arg) {
 				absPath := filepath.Join(cwd, arg)
 				relPath, err := filepath.Rel(repoRoot, absPath)
 				if err == nil {
@@ -77,11 +133,13 @@ func Execute(providerFactory func(*config.Config) llm.Provider) (ExitCode, error
 	command := os.Args[1]
 	switch command {
 	case "init":
-		if err := runInit(); err != nil {
+		if err := runInit(scanRoot); err != nil {
 			return ExitError, err
 		}
 		return ExitSuccess, nil
-	case "check", "index":
+	case "report":
+		return runReport()
+	case "check", "index", "cache", "audit", "new", "config", "lint-adrs":
 	default:
 		printUsage()
 		return ExitUsage, fmt.Errorf("unknown command: %s", command)
@@ -91,49 +149,271 @@ func Execute(providerFactory func(*config.Config) llm.Provider) (ExitCode, error
 	if err != nil {
 		return ExitConfig, fmt.Errorf("error loading config: %v", err)
 	}
+	// Advisory only, since some models accept a configurable dimension; warn
+	// before any command builds an index with the wrong one.
+	if err := cfg.VectorStore.CheckEmbeddingDim(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
 
 	if cfg.ProjectName == "" {
 		cfg.ProjectName = filepath.Base(repoRoot)
 	}
 
-	indexFile := ".archguard/index.json"
+	indexFile := config.StatePath("index.json")
 	if cfg.IndexFile != "" {
 		indexFile = cfg.IndexFile
 	}
 
+	// The tokenizer reads its vocabulary cache location from the environment;
+	// set it once here instead of from the engine's concurrent workers.
+	if dir := cfg.LLM.TiktokenCacheDir; dir != "" {
+		os.Setenv("TIKTOKEN_CACHE_DIR", dir)
+	}
+
+	if command == "cache" {
+		return runCache(cfg, os.Args[2:])
+	}
+	if command == "new" {
+		return runNew(cfg, os.Args[2:])
+	}
+	if command == "config" {
+		return runConfig(cfg, os.Args[2:])
+	}
+
+	if h := cfg.LLM.HTTP; h.MaxIdleConnsPerHost < 0 || h.IdleConnTimeoutMs < 0 {
+		return ExitConfig, fmt.Errorf("invalid llm.http: max_idle_conns_per_host and idle_conn_timeout_ms must be 0 or more")
+	}
+	llm.SetTransportOptions(llm.TransportOptions{
+		MaxIdleConnsPerHost: cfg.LLM.HTTP.MaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.LLM.HTTP.IdleConnTimeoutMs) * time.Millisecond,
+		DisableHTTP2:        cfg.LLM.HTTP.DisableHTTP2,
+	})
+
 	var provider llm.Provider
 	if providerFactory != nil {
 		provider = providerFactory(cfg)
 	} else {
-		switch cfg.LLM.Provider {
-		case "openai":
-			apiKey := os.Getenv("ARCHGUARD_API_KEY")
-			if apiKey == "" {
-				fmt.Println("Warning: ARCHGUARD_API_KEY is not set. OpenAI provider may fail.")
+		chatProvider, err := newProvider(cfg.LLM.Provider, cfg.LLM.BaseURL, cfg, false)
+		if err != nil {
+			return ExitConfig, err
+		}
+		provider = chatProvider
+		if cfg.LLM.Stream {
+			if streamer, ok := chatProvider.(llm.StreamSetter); ok {
+				streamer.SetStreaming(cfg.LLM.StreamIdleTimeout())
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: llm.stream is not supported by the %s provider; responses are not streamed.\n", cfg.LLM.Provider)
 			}
-			provider = llm.NewOpenAIProvider(apiKey, cfg.LLM.Model, cfg.VectorStore.Model)
-		case "ollama":
-			provider = llm.NewOllamaProvider(cfg.LLM.BaseURL, cfg.LLM.Model, cfg.VectorStore.Model, cfg.LLM.Temperature)
-		case "gemini":
-			apiKey := os.Getenv("ARCHGUARD_API_KEY")
-			if apiKey == "" {
-				fmt.Println("Warning: ARCHGUARD_API_KEY is not set. Gemini provider requires an API key.")
+		}
+
+		// vector_store.provider selects a distinct embedding backend, e.g. local
+		// Ollama embeddings alongside a hosted chat model.
+		embedName := cfg.VectorStore.Provider
+		if embedName != "" && embedName != cfg.LLM.Provider {
+			embedProvider, err := newProvider(embedName, cfg.VectorStore.BaseURL, cfg, true)
+			if err != nil {
+				return ExitConfig, fmt.Errorf("vector_store: %w", err)
 			}
-			provider = llm.NewGeminiProvider(apiKey, cfg.LLM.Model, cfg.VectorStore.Model)
-		default:
-			return ExitConfig, fmt.Errorf("unknown provider: %s", cfg.LLM.Provider)
+			provider = llm.NewSplitProvider(chatProvider, embedProvider)
 		}
 	}
 
 	if command == "check" {
-		return runCheck(cfg, provider, indexFile, os.Args[2:])
+		return runCheck(cfg, provider, indexFile, scanRoot, os.Args[2:])
+	}
+	if command == "audit" {
+		return runAudit(cfg, provider, indexFile, os.Args[2:])
+	}
+	if command == "lint-adrs" {
+		return runLintADRs(cfg, provider, indexFile, os.Args[2:])
+	}
+
+	indexFlags := flag.NewFlagSet("index", flag.ContinueOnError)
+	var flagParseOutput bytes.Buffer
+	indexFlags.SetOutput(&flagParseOutput)
+	strict := indexFlags.Bool("strict", false, "Fail when an ADR scope matches no tracked files")
+	adrDir := indexFlags.String("adr-dir", "", "Index ADRs from this directory instead of analysis.adr_path")
+	noGit := indexFlags.Bool("no-git", false, "Run outside a git repository; skips the scope check against tracked files")
+	validateOnly := indexFlags.Bool("validate-only", false, "Report which ADRs would be indexed or skipped, without embedding or writing the index")
+	if err := indexFlags.Parse(os.Args[2:]); err != nil {
+		if details := strings.TrimSpace(flagParseOutput.String()); details != "" {
+			return ExitUsage, fmt.Errorf("error parsing flags: %v\n%s", err, details)
+		}
+		return ExitUsage, fmt.Errorf("error parsing flags: %v", err)
+	}
+	if *adrDir != "" {
+		cfg.Analysis.ADRPath = *adrDir
+	}
+	if *validateOnly {
+		return runValidateADRs(context.Background(), cfg, *noGit)
+	}
+	return runIndex(context.Background(), cfg, provider, indexFile, *strict, *noGit, os.Stdout)
+}
+
+// hasFlag reports whether args contain the boolean flag name in either the
+// -name or --name form, before flag sets are parsed.
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if arg == "-"+name || arg == "--"+name || arg == "-"+name+"=true" || arg == "--"+name+"=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFlag returns args without any occurrence of the boolean flag name
+// before "--", and whether it was set.
+func removeFlag(args []string, name string) ([]string, bool) {
+	var kept []string
+	set := false
+	for i, arg := range args {
+		if arg == "--" {
+			return append(kept, args[i:]...), set
+		}
+		if hasFlag([]string{arg}, name) {
+			set = true
+			continue
+		}
+		if arg == "-"+name+"=false" || arg == "--"+name+"=false" {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept, set
+}
+
+// showBanner reports whether to print the banner for the command line args
+// (without the program name). It is left out under --quiet or NoBannerEnv, and
+// for output meant to be parsed: the version, config show's YAML, and check
+// --format jsonl or markdown.
+func showBanner(args []string, quiet bool) bool {
+	if quiet || os.Getenv(NoBannerEnv) != "" {
+		return false
+	}
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "schema", "config", "-v", "--version":
+		return false
+	}
+	for i, arg := range args[1:] {
+		if arg == "--" {
+			break
+		}
+		format, ok := "", false
+		switch {
+		case arg == "-format" || arg == "--format":
+			if i+2 < len(args) {
+				format, ok = args[i+2], true
+			}
+		case strings.HasPrefix(arg, "-"):
+			format, ok = strings.CutPrefix(strings.TrimLeft(arg, "-"), "format=")
+		}
+		if ok && format != "text" {
+			return false
+		}
+	}
+	return true
+}
+
+// newProvider constructs the named LLM provider and applies llm.headers to it
+// when it supports extra headers. embedding marks a vector_store.provider
+// distinct from llm.provider, which uses the vector_store key and headers.
+func newProvider(name, baseURL string, cfg *config.Config, embedding bool) (llm.Provider, error) {
+	provider, err := buildProvider(name, baseURL, cfg, embedding)
+	if err != nil {
+		return nil, err
+	}
+	headers := cfg.LLM.Headers
+	if embedding {
+		headers = cfg.EmbeddingHeaders()
+	}
+	if setter, ok := provider.(llm.HeaderSetter); ok && len(headers) > 0 {
+		setter.SetHeaders(headers)
+	}
+	return provider, nil
+}
+
+// buildProvider constructs the named LLM provider. Chat requests use llm.model
+// and embedding requests use vector_store.model. Names that are not built in
+// are resolved through pkg/llm's Register.
+func buildProvider(name, baseURL string, cfg *config.Config, embedding bool) (llm.Provider, error) {
+	switch name {
+	case "openai":
+		apiKey, err := providerAPIKey(cfg, embedding, "OpenAI provider may fail.")
+		if err != nil {
+			return nil, err
+		}
+		return llm.NewOpenAIProvider(apiKey, cfg.LLM.Model, cfg.VectorStore.Model), nil
+	case "ollama":
+		return llm.NewOllamaProvider(baseURL, cfg.LLM.Model, cfg.VectorStore.Model, cfg.LLM.Temperature), nil
+	case "gemini":
+		apiKey, err := providerAPIKey(cfg, embedding, "Gemini provider requires an API key.")
+		if err != nil {
+			return nil, err
+		}
+		return llm.NewGeminiProvider(apiKey, cfg.LLM.Model, cfg.VectorStore.Model), nil
+	default:
+		if factory, ok := pkgllm.Lookup(name); ok {
+			return factory(cfg), nil
+		}
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+}
+
+// providerAPIKey resolves the API key for a hosted provider, printing a
+// warning that names the source it checked when the key is empty.
+func providerAPIKey(cfg *config.Config, embedding bool, consequence string) (string, error) {
+	resolve := cfg.LLM.ResolveAPIKey
+	if embedding {
+		resolve = func() (string, string, error) { return cfg.VectorStore.ResolveAPIKey(&cfg.LLM) }
+	}
+	apiKey, source, err := resolve()
+	if err != nil {
+		return "", err
+	}
+	if apiKey == "" {
+		fmt.Printf("Warning: no API key found in %s. %s\n", source, consequence)
+	}
+	return apiKey, nil
+}
+
+// runSchema prints the JSON Schema for archguard.yaml to stdout.
+func runSchema() (ExitCode, error) {
+	data, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		return ExitError, fmt.Errorf("failed to encode schema: %v", err)
 	}
-	return runIndex(context.Background(), cfg, provider, indexFile)
+	fmt.Println(string(data))
+	return ExitSuccess, nil
+}
+
+// runConfig prints the effective config: archguard.yaml with extends, config.d
+// fragments, and environment overrides applied, and secrets redacted.
+func runConfig(cfg *config.Config, args []string) (ExitCode, error) {
+	if len(args) != 1 || args[0] != "show" {
+		return ExitUsage, fmt.Errorf("usage: archguard config show")
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		return ExitError, fmt.Errorf("failed to encode config: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		return ExitError, fmt.Errorf("failed to encode config: %v", err)
+	}
+	return ExitSuccess, nil
 }
 
 // runInit initializes a new ArchGuard project by prompting the user for configuration
 // preferences and creating the necessary directory structure and config files.
-func runInit() error {
+// scanRoot is the invocation directory relative to the repo root (the current
+// directory by now), used to resolve a relative ADR path the way the user typed it.
+func runInit(scanRoot string) error {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	fmt.Printf("Enter ADR directory path [%s]: ", defaultADRPath)
@@ -144,6 +424,13 @@ func runInit() error {
 	adrPath := strings.TrimSpace(scanner.Text())
 	if adrPath == "" {
 		adrPath = defaultADRPath
+	} else if !filepath.IsAbs(adrPath) && scanRoot != "" {
+		adrPath = filepath.Join(scanRoot, adrPath)
+	}
+	adrPath = filepath.ToSlash(adrPath)
+
+	if filepath.IsAbs(adrPath) || strings.HasPrefix(adrPath, "../") {
+		fmt.Println("Note: the ADR directory is outside this repository. Make sure it is present (e.g. as a sibling checkout) wherever ArchGuard runs, including CI.")
 	}
 
 	createdDir := false
@@ -246,8 +533,8 @@ analysis:
 }
 
 // ensureGitignore ensures the .archguard/ directory is ignored by git to prevent
-// local caches and indexes from being committed.
-func ensureGitignore() error {
+// local caches and indexes from being committed. It is safe to run repeatedly.
+func ensureGitignore() (err error) {
 	const gitignorePath = ".gitignore"
 	const archguardEntry = ".archguard/"
 
@@ -256,11 +543,8 @@ func ensureGitignore() error {
 		return err
 	}
 
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		if strings.TrimSpace(line) == archguardEntry {
-			return nil
-		}
+	if ignoresArchguard(string(content)) {
+		return nil
 	}
 
 	f, err := os.OpenFile(gitignorePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -274,13 +558,19 @@ func ensureGitignore() error {
 		}
 	}()
 
+	// Match the file's existing line endings.
+	newline := "\n"
+	if bytes.Contains(content, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
 	if len(content) > 0 && content[len(content)-1] != '\n' {
-		if _, err := f.WriteString("\n"); err != nil {
+		if _, err := f.WriteString(newline); err != nil {
 			return err
 		}
 	}
 
-	if _, err := f.WriteString(archguardEntry + "\n"); err != nil {
+	if _, err := f.WriteString(archguardEntry + newline); err != nil {
 		return err
 	}
 
@@ -288,6 +578,19 @@ func ensureGitignore() error {
 	return nil
 }
 
+// ignoresArchguard reports whether gitignore content already has a line
+// ignoring the .archguard directory, with or without leading or trailing
+// slashes and regardless of CRLF line endings.
+func ignoresArchguard(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		entry := strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if strings.Trim(entry, "/") == ".archguard" {
+			return true
+		}
+	}
+	return false
+}
+
 const adrTemplateContent = `---
 title: "[Short, Descriptive Title]"
 status: "[Accepted | Proposed | Superseded]"
@@ -309,16 +612,160 @@ scope: "[Optional: glob pattern, e.g., **/*.go]"
 [Describe the expected outcomes, both positive and negative.]
 `
 
+// runNew writes the next numbered ADR, e.g. 0007-my-decision-title.md, to
+// analysis.adr_path. It starts from the directory's ADR_TEMPLATE.md when
+// present and the built-in template otherwise.
+func runNew(cfg *config.Config, args []string) (ExitCode, error) {
+	newFlags := flag.NewFlagSet("new", flag.ContinueOnError)
+	var flagParseOutput bytes.Buffer
+	newFlags.SetOutput(&flagParseOutput)
+	status := newFlags.String("status", "Proposed", "Initial status of the ADR")
+	if err := newFlags.Parse(args); err != nil {
+		if details := strings.TrimSpace(flagParseOutput.String()); details != "" {
+			return ExitUsage, fmt.Errorf("error parsing flags: %v\n%s", err, details)
+		}
+		return ExitUsage, fmt.Errorf("error parsing flags: %v", err)
+	}
+	title := strings.TrimSpace(strings.Join(newFlags.Args(), " "))
+	if title == "" {
+		return ExitUsage, fmt.Errorf("usage: archguard new \"My Decision Title\" [--status STATUS]")
+	}
+	slug := index.Slug(title)
+	if slug == "" {
+		return ExitUsage, fmt.Errorf("title %q has no letters or digits to build a file name from", title)
+	}
+
+	adrPath := cfg.Analysis.ADRPath
+	if adrPath == "" {
+		adrPath = defaultADRPath
+	}
+	if err := os.MkdirAll(adrPath, 0755); err != nil {
+		return ExitError, fmt.Errorf("failed to create ADR directory: %v", err)
+	}
+
+	number, err := nextADRNumber(adrPath)
+	if err != nil {
+		return ExitError, err
+	}
+
+	template := adrTemplateContent
+	if data, err := os.ReadFile(filepath.Join(adrPath, "ADR_TEMPLATE.md")); err == nil {
+		template = string(data)
+	}
+
+	path := filepath.Join(adrPath, fmt.Sprintf("%04d-%s.md", number, slug))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return ExitError, fmt.Errorf("failed to create ADR: %v", err)
+	}
+	if _, err := f.WriteString(fillADRTemplate(template, title, *status)); err != nil {
+		f.Close()
+		return ExitError, fmt.Errorf("failed to write ADR: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return ExitError, fmt.Errorf("failed to write ADR: %v", err)
+	}
+
+	fmt.Printf("Created %s\n", filepath.ToSlash(path))
+	fmt.Println("Fill in the Context and Decision sections, then run: archguard index")
+	return ExitSuccess, nil
+}
+
+// nextADRNumber returns one more than the highest numeric ID among the
+// markdown files under dir, using the same "<id>-title.md" convention as
+// index.ParseADR.
+func nextADRNumber(dir string) (int, error) {
+	highest := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+		if n, err := strconv.Atoi(strings.Split(info.Name(), "-")[0]); err == nil && n > highest {
+			highest = n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan ADR directory: %v", err)
+	}
+	return highest + 1, nil
+}
+
+// fillADRTemplate sets the title and status frontmatter fields, replaces the
+// "[ADR Title]" heading placeholder, and clears a placeholder scope so the
+// new ADR parses and indexes as-is.
+func fillADRTemplate(template, title, status string) string {
+	lines := strings.Split(template, "\n")
+	inFrontmatter := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" {
+			if inFrontmatter {
+				break
+			}
+			inFrontmatter = i == 0
+			continue
+		}
+		if !inFrontmatter {
+			break
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "title:"):
+			lines[i] = "title: " + strconv.Quote(title)
+		case strings.HasPrefix(trimmed, "status:"):
+			lines[i] = "status: " + strconv.Quote(status)
+		case strings.HasPrefix(trimmed, "scope:") && strings.Contains(trimmed, "[Optional"):
+			lines[i] = `scope: ""`
+		}
+	}
+	return strings.ReplaceAll(strings.Join(lines, "\n"), "[ADR Title]", title)
+}
+
 // runCheck executes the architectural drift analysis against a set of files
-// based on the provided flags and ADR index.
-func runCheck(cfg *config.Config, provider llm.Provider, indexFile string, args []string) (ExitCode, error) {
+// based on the provided flags and ADR index. Without a path argument, files
+// outside scanRoot (the invocation directory) are skipped unless --repo-wide.
+func runCheck(cfg *config.Config, provider llm.Provider, indexFile, scanRoot string, args []string) (ExitCode, error) {
 	checkFlags := flag.NewFlagSet("check", flag.ContinueOnError)
 	var flagParseOutput bytes.Buffer
 	checkFlags.SetOutput(&flagParseOutput)
 	staged := checkFlags.Bool("staged", false, "Scan staged files only")
+	working := checkFlags.Bool("working", false, "Scan staged and unstaged changes together, as they are on disk")
 	all := checkFlags.Bool("all", false, "Scan all tracked files")
-	debug := checkFlags.Bool("debug", false, "Enable debug logging")
+	rev := checkFlags.String("rev", "", "Scan all files as of the given commit (read-only audit)")
+	last := checkFlags.Int("last", 0, "Scan files changed in the last N commits (HEAD~N..HEAD)")
+	adrDir := checkFlags.String("adr-dir", "", "Check against ADRs in this directory instead of analysis.adr_path")
+	commitMsg := checkFlags.String("commit-msg", "", "Check a commit message file against ADRs scoped to COMMIT_MSG (for commit-msg hooks)")
+	yesReally := checkFlags.Bool("yes-really", false, "Skip the analysis.max_files confirmation")
+	sorted := checkFlags.Bool("sorted", false, "Print results sorted by file path")
+	maxViolations := checkFlags.Int("max-violations", 0, "Print at most N violations (all are still counted); 0 prints all")
+	explainPass := checkFlags.Bool("explain-pass", false, "Show why each file was or was not flagged")
+	explainCache := checkFlags.Bool("explain-cache", false, "Print the inputs hashed into each analysis cache key and whether it was a hit or miss")
+	changedLinesOnly := checkFlags.Bool("changed-lines-only", false, "Report only violations on lines added or modified by the diff")
+	parallelADRs := checkFlags.Bool("parallel-adrs", false, "Analyze each file's matched ADRs concurrently, within analysis.max_concurrency")
+	selectSpec := checkFlags.String("select", "", "Analyze one file against one ADR, printing the prompt and raw response (ADR:FILE, e.g. 0007:internal/db/conn.go)")
+	tags := checkFlags.String("tags", "", "Check only ADRs with at least one of these comma-separated frontmatter tags")
+	color := checkFlags.String("color", "auto", "Color output: auto (only on a terminal, unless NO_COLOR is set), always, or never")
+	timings := checkFlags.Bool("timings", false, "Print aggregate time spent per analysis phase")
+	githubPR := checkFlags.Bool("github-pr", false, "Post violations as inline review comments on the GitHub pull request (needs GITHUB_TOKEN)")
+	format := checkFlags.String("format", "text", "Output format: text, jsonl for one JSON violation per line, or markdown for a shareable report")
+	metricsPath := checkFlags.String("metrics", "", "Write run metrics to this file in Prometheus textfile format")
+	baselinePath := checkFlags.String("baseline", "", "Show violations found in this --format jsonl report as KNOWN; only new ones fail the run")
+	requireCoverage := checkFlags.Bool("require-coverage", false, "Fail if any scanned file matches no ADR in retrieval, listing those files")
+	interactive := checkFlags.Bool("interactive", false, "After the run, step through new violations to accept into the baseline, ignore in the file, or fix later")
+	noUntracked := checkFlags.Bool("no-untracked", false, "Do not scan untracked files in uncommitted mode")
+	repoWide := checkFlags.Bool("repo-wide", false, "Scan the whole repository even when run from a subdirectory")
+	onlyChangedADRs := checkFlags.Bool("only-changed-adrs", false, "Check all files against only the ADRs added or changed since the previous index")
+	noGit := checkFlags.Bool("no-git", false, "Scan a plain source tree without git: walk the filesystem instead of tracked files and send full content")
+	debug := checkFlags.Bool("debug", false, "Enable debug logging (same as -vvv)")
 	ci := checkFlags.Bool("ci", false, "Enable CI-safe mode (Warn-Open behavior)")
+	var verbosity int
+	checkFlags.Var(&verbosityFlag{level: &verbosity, step: 1}, "v", "Increase verbosity (repeatable: -v, -vv, -vvv)")
+	checkFlags.Var(&verbosityFlag{level: &verbosity, step: 1}, "verbose", "Increase verbosity (repeatable)")
+	checkFlags.Var(&verbosityFlag{level: &verbosity, step: 2}, "vv", "Verbosity level 2: include ADR similarity scores")
+	checkFlags.Var(&verbosityFlag{level: &verbosity, step: 3}, "vvv", "Verbosity level 3: equivalent to --debug")
 
 	if err := checkFlags.Parse(args); err != nil {
 		if details := strings.TrimSpace(flagParseOutput.String()); details != "" {
@@ -329,76 +776,874 @@ func runCheck(cfg *config.Config, provider llm.Provider, indexFile string, args
 
 	files := checkFlags.Args()
 
-	store, err := index.NewVectorStore(cfg)
-	if err != nil {
-		return ExitIndexError, fmt.Errorf("failed to initialize vector store: %v", err)
+	if *last < 0 {
+		return ExitUsage, fmt.Errorf("invalid --last %d (expected a number of commits)", *last)
+	}
+	if *last > 0 && (*rev != "" || *staged || *working || *all || *noGit || *commitMsg != "" || len(files) > 0) {
+		return ExitUsage, fmt.Errorf("--last cannot be combined with other scan modes or a path argument")
+	}
+	if *rev != "" && (*staged || *working || len(files) > 0) {
+		return ExitUsage, fmt.Errorf("--rev cannot be combined with --staged, --working, or a path argument")
+	}
+	if *commitMsg != "" && (*rev != "" || *staged || *working || *all || len(files) > 0) {
+		return ExitUsage, fmt.Errorf("--commit-msg cannot be combined with other scan modes or a path argument")
+	}
+	if *staged && *working {
+		return ExitUsage, fmt.Errorf("--staged and --working are mutually exclusive")
+	}
+	if *noGit && (*staged || *working || *rev != "") {
+		return ExitUsage, fmt.Errorf("--no-git cannot be combined with --staged, --working, or --rev, which read changes from git")
+	}
+	if *onlyChangedADRs && *commitMsg != "" {
+		return ExitUsage, fmt.Errorf("--only-changed-adrs cannot be combined with --commit-msg")
+	}
+	if *onlyChangedADRs && !*staged && !*working && *rev == "" && len(files) == 0 {
+		*all = true
+	}
+	var selectADR, selectFile string
+	if *selectSpec != "" {
+		var ok bool
+		selectADR, selectFile, ok = strings.Cut(*selectSpec, ":")
+		if !ok || selectADR == "" || selectFile == "" {
+			return ExitUsage, fmt.Errorf("invalid --select %q (expected ADR:FILE, e.g. 0007:internal/db/conn.go)", *selectSpec)
+		}
+		if *commitMsg != "" || *all || *onlyChangedADRs || *requireCoverage || len(files) > 0 {
+			return ExitUsage, fmt.Errorf("--select cannot be combined with --commit-msg, --all, --only-changed-adrs, --require-coverage, or a path argument")
+		}
+	}
+	if err := validateColor(*color); err != nil {
+		return ExitUsage, err
+	}
+	if *format != "text" && *format != "jsonl" && *format != "markdown" {
+		return ExitUsage, fmt.Errorf("invalid --format %q (expected text, jsonl, or markdown)", *format)
+	}
+	if *interactive {
+		if *format != "text" || *ci || *commitMsg != "" || *selectSpec != "" {
+			return ExitUsage, fmt.Errorf("--interactive cannot be combined with --format %s, --ci, --commit-msg, or --select", *format)
+		}
+		if !isInteractive() {
+			return ExitUsage, fmt.Errorf("--interactive needs a terminal")
+		}
 	}
 
-	var providers []index.Provider
-	providers = append(providers, index.NewLocalProvider(cfg.Analysis.ADRPath, cfg.Analysis.AcceptedStatuses))
+	var reviewClient *github.ReviewClient
+	if *githubPR {
+		var err error
+		reviewClient, err = github.NewReviewClientFromEnv()
+		if err != nil {
+			return ExitConfig, fmt.Errorf("--github-pr: %v", err)
+		}
+	}
 
-	if cfg.Analysis.Confluence.Enabled {
-		providers = append(providers, index.NewConfluenceProvider(
-			cfg.Analysis.Confluence.Domain,
-			cfg.Analysis.Confluence.SpaceID,
-			cfg.Analysis.Confluence.Username,
-			cfg.Analysis.Confluence.Token,
-			cfg.Analysis.AcceptedStatuses,
-		))
+	var baseline []analysis.ViolationRecord
+	if *baselinePath != "" {
+		f, err := os.Open(*baselinePath)
+		if err != nil {
+			return ExitConfig, fmt.Errorf("--baseline: %v", err)
+		}
+		baseline, err = analysis.ReadViolationRecords(f)
+		f.Close()
+		if err != nil {
+			return ExitConfig, fmt.Errorf("--baseline %s: %v", *baselinePath, err)
+		}
 	}
-	adrProvider := index.NewCompositeProvider(providers...)
 
-	validADRs, err := adrProvider.GetADRs(context.Background())
-	if err != nil {
-		return ExitIndexError, fmt.Errorf("failed to fetch ADRs: %v", err)
+	// In jsonl and markdown mode stdout carries only violation records or the
+	// report; progress, warnings, and the summary go to out, which is stderr.
+	var jsonl, markdown io.Writer
+	var out io.Writer = os.Stdout
+	switch *format {
+	case "jsonl":
+		jsonl, out = os.Stdout, os.Stderr
+	case "markdown":
+		markdown, out = os.Stdout, os.Stderr
 	}
 
-	currentHash, err := store.CalculateHash(validADRs, cfg.VectorStore.Model)
-	if err != nil {
-		return ExitIndexError, fmt.Errorf("failed to calculate index hash: %v", err)
+	if *adrDir != "" {
+		cfg.Analysis.ADRPath = *adrDir
 	}
 
-	if err := store.Load(indexFile, cfg.VectorStore.Model, cfg.VectorStore.EmbeddingDim, currentHash); err != nil {
-		fmt.Printf("Index metadata mismatch or missing index. Triggering index rebuild: %v\n", err)
-		if _, err := runIndex(context.Background(), cfg, provider, indexFile); err != nil {
-			return ExitIndexError, fmt.Errorf("index rebuild failed: %v", err)
+	if err := checkProviderReachable(context.Background(), cfg, provider); err != nil {
+		return ExitConfig, err
+	}
+
+	var store index.VectorStore
+	var validADRs []index.ADR
+	var err error
+	if *selectSpec != "" {
+		// --select bypasses retrieval, so the index is not needed.
+		adrProvider := newADRProvider(cfg)
+		index.SetOutput(adrProvider, out)
+		validADRs, err = adrProvider.GetADRs(context.Background())
+		if err != nil {
+			return ExitIndexError, fmt.Errorf("failed to fetch ADRs: %v", err)
+		}
+	} else {
+		store, validADRs, err = loadIndex(cfg, provider, indexFile, *noGit, out)
+		if err != nil {
+			return ExitIndexError, err
 		}
+	}
 
-		// Reload the index after a successful rebuild to ensure the latest state is in memory.
-		currentHash, _ = store.CalculateHash(validADRs, cfg.VectorStore.Model)
-		if err := store.Load(indexFile, cfg.VectorStore.Model, cfg.VectorStore.EmbeddingDim, currentHash); err != nil {
-			return ExitIndexError, fmt.Errorf("failed to load rebuilt index: %v", err)
+	if *onlyChangedADRs {
+		changed, err := restrictToChangedADRs(store, indexFile, out)
+		if err != nil {
+			return ExitIndexError, fmt.Errorf("--only-changed-adrs: %v", err)
 		}
+		if changed == 0 {
+			fmt.Fprintln(out, "No ADRs were added or changed since the previous index; nothing to check.")
+			return ExitSuccess, nil
+		}
+	}
+
+	engineTags := splitList(*tags)
+	if len(engineTags) > 0 && !slices.ContainsFunc(searchedADRs(store, validADRs), func(adr index.ADR) bool { return adr.HasAnyTag(engineTags) }) {
+		fmt.Fprintf(out, "Warning: no ADR has any of the tags %s; nothing will be checked.\n", strings.Join(engineTags, ", "))
+	}
+
+	if len(validADRs) == 0 {
+		fmt.Fprintln(out, "WARNING: The ADR index is empty. No files can be checked, so a clean result does NOT mean the codebase is compliant.")
+		fmt.Fprintf(out, "         Add ADRs to %q (or adjust analysis.accepted_statuses) and run 'archguard index'.\n", cfg.Analysis.ADRPath)
 	}
 
 	var contentProvider analysis.ContentProvider
-	if len(files) > 0 {
+	if *commitMsg != "" {
+		contentProvider = &analysis.CommitMessageProvider{Path: *commitMsg}
+	} else if *noGit {
+		contentProvider, err = fileSystemProviderFor(files)
+		if err != nil {
+			return ExitUsage, err
+		}
+	} else if *rev != "" {
+		contentProvider = &analysis.RevisionProvider{Rev: *rev}
+	} else if *last > 0 {
+		base, err := lastCommitsBase(*last)
+		if err != nil {
+			return ExitUsage, err
+		}
+		contentProvider = &analysis.RangeProvider{Base: base}
+	} else if len(files) > 0 {
 		target := files[0]
 		if target == "." {
 			contentProvider = &analysis.AllProvider{}
+		} else if analysis.IsGlob(target) {
+			contentProvider = &analysis.GlobProvider{Pattern: target}
 		} else {
 			contentProvider = &analysis.SingleFileProvider{Path: target}
 		}
+	} else if *working {
+		contentProvider = &analysis.WorkingProvider{}
 	} else if *staged {
 		contentProvider = &analysis.StagedProvider{}
 	} else if *all {
 		contentProvider = &analysis.AllProvider{}
 	} else {
-		contentProvider = &analysis.UncommittedProvider{}
+		contentProvider, err = contentProviderForMode(cfg.Analysis.DefaultMode)
+		if err != nil {
+			return ExitConfig, err
+		}
+	}
+
+	if p, ok := contentProvider.(*analysis.UncommittedProvider); ok {
+		p.SkipUntracked = *noUntracked
+	}
+	analysis.SetDiffContextLines(contentProvider, cfg.Analysis.DiffContextLines)
+	analysis.SetFollowExternalSymlinks(contentProvider, cfg.Analysis.FollowSymlinks)
+
+	if *selectSpec != "" {
+		return runSelect(cfg, provider, contentProvider, validADRs, selectADR, selectFile)
+	}
+
+	if scanRoot != "" && len(files) == 0 && *commitMsg == "" && !*repoWide {
+		fmt.Fprintf(out, "Scanning %s/ only (run with --repo-wide to scan the whole repository).\n", scanRoot)
+		contentProvider = &analysis.SubtreeProvider{ContentProvider: contentProvider, Root: scanRoot}
 	}
 
 	if *debug {
-		fmt.Println("[DEBUG] Mode Enabled")
+		fmt.Fprintln(out, "[DEBUG] Mode Enabled")
 	}
 
 	engine := analysis.NewEngine(cfg, store, provider, contentProvider, *debug, *ci)
-	if err := engine.Run(context.Background()); err != nil {
+	if verbosity > engine.Verbosity {
+		engine.Verbosity = verbosity
+	}
+	engine.SkipFileLimit = *yesReally
+	engine.SortOutput = *sorted || cfg.Analysis.SortOutput
+	engine.MaxViolations = *maxViolations
+	engine.ExplainPass = *explainPass
+	engine.ExplainCache = *explainCache
+	engine.ChangedLinesOnly = *changedLinesOnly
+	engine.ParallelADRs = *parallelADRs
+	engine.Baseline = baseline
+	engine.JSONL = jsonl
+	engine.Out = out
+	// Every check except a commit-msg hook is saved for `archguard report`.
+	saveLastRun := *commitMsg == ""
+	engine.CollectViolations = reviewClient != nil || markdown != nil || saveLastRun || *interactive
+	engine.ScopedOnly = *commitMsg != ""
+	engine.Tags = engineTags
+	engine.Color = useColor(*color)
+	if *timings {
+		engine.Timings = analysis.NewTimings()
+	}
+	if !*ci && isInteractive() {
+		engine.Confirm = confirmPrompt
+	}
+	summary, err := engine.Run(context.Background())
+	if summary != nil {
+		printRunSummary(out, summary, len(validADRs))
+		if *requireCoverage && len(summary.Uncovered) > 0 {
+			fmt.Fprintf(out, "\n%d file(s) matched no ADR (--require-coverage):\n", len(summary.Uncovered))
+			for _, file := range summary.Uncovered {
+				fmt.Fprintf(out, "  %s\n", file)
+			}
+		}
+		if saveLastRun {
+			lastRun := analysis.NewLastRun(summary, len(validADRs), args, err)
+			if err := lastRun.Write(config.StatePath(analysis.LastRunFile)); err != nil {
+				fmt.Fprintf(out, "Warning: failed to save %s: %v\n", config.StatePath(analysis.LastRunFile), err)
+			}
+		}
+		if markdown != nil {
+			if err := summary.WriteMarkdown(markdown, len(validADRs), github.BlobURLFromEnv()); err != nil {
+				fmt.Fprintf(out, "Warning: failed to write Markdown report: %v\n", err)
+			}
+		}
+		if *metricsPath != "" {
+			if err := summary.WriteMetricsFile(*metricsPath, len(validADRs)); err != nil {
+				fmt.Fprintf(out, "Warning: failed to write metrics: %v\n", err)
+			}
+		}
+	}
+	if *interactive && summary != nil {
+		target := *baselinePath
+		if target == "" {
+			target = defaultBaselineFile
+		}
+		if err := triageViolations(summary.Records, os.Stdin, out, target); err != nil {
+			fmt.Fprintf(out, "Warning: %v\n", err)
+		}
+	}
+	if reviewClient != nil && summary != nil {
+		// A failed post is reported but does not change the check result.
+		if n, err := reviewClient.PostReview(context.Background(), summary.NewRecords()); err != nil {
+			fmt.Fprintf(out, "Warning: failed to post GitHub review: %v\n", err)
+		} else if n > 0 {
+			fmt.Fprintf(out, "Posted %d new violation(s) to pull request #%d.\n", n, reviewClient.PR())
+		}
+	}
+	if err != nil {
 		return exitCodeForAnalysisError(err), fmt.Errorf("analysis failed: %v", err)
 	}
-	fmt.Println("No architectural violations found.")
+	if *requireCoverage && summary != nil && len(summary.Uncovered) > 0 {
+		return ExitDriftDetected, fmt.Errorf("%d file(s) are not covered by any ADR", len(summary.Uncovered))
+	}
+	if summary != nil && summary.Known > 0 {
+		fmt.Fprintf(out, "No new architectural violations found (%d known from the baseline).\n", summary.Known)
+		return ExitSuccess, nil
+	}
+	fmt.Fprintln(out, "No architectural violations found.")
+	return ExitSuccess, nil
+}
+
+// triageViolations steps through the new violations in records (check
+// --interactive), asking for each whether to accept it into the baseline at
+// baselinePath, ignore its ADR in the file with an archguard-ignore
+// directive, or leave it to fix later. Decisions are written out once every
+// violation has been seen or the user quits; the rest are left as they are.
+func triageViolations(records []analysis.ViolationRecord, in io.Reader, out io.Writer, baselinePath string) error {
+	var pending []analysis.ViolationRecord
+	for _, rec := range records {
+		if !rec.Baseline {
+			pending = append(pending, rec)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var accepted []analysis.ViolationRecord
+	type directive struct{ file, adrID string }
+	var ignored []directive
+	seen := make(map[directive]bool)
+
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintf(out, "\nTriaging %d violation(s).\n", len(pending))
+triage:
+	for i, rec := range pending {
+		fmt.Fprintf(out, "\n[%d/%d] %s:%d  %s (%s)\n", i+1, len(pending), rec.File, rec.Line, rec.ADRTitle, rec.ADRID)
+		fmt.Fprintf(out, "  %s\n", rec.Reasoning)
+		if rec.Code != "" {
+			for _, line := range strings.Split(strings.TrimRight(rec.Code, "\n"), "\n") {
+				fmt.Fprintf(out, "    | %s\n", line)
+			}
+		}
+		for {
+			fmt.Fprint(out, "(a)ccept into baseline, (i)gnore ADR in this file, (f)ix later, (q)uit: ")
+			if !scanner.Scan() {
+				fmt.Fprintln(out)
+				break triage
+			}
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "a", "accept":
+				accepted = append(accepted, rec)
+			case "i", "ignore":
+				d := directive{rec.File, rec.ADRID}
+				if !seen[d] {
+					seen[d] = true
+					ignored = append(ignored, d)
+				}
+			case "f", "fix", "":
+			case "q", "quit":
+				break triage
+			default:
+				continue
+			}
+			break
+		}
+	}
+
+	var errs []error
+	if len(accepted) > 0 {
+		added, err := analysis.MergeViolationRecords(baselinePath, accepted)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to update baseline %s: %v", baselinePath, err))
+		} else {
+			fmt.Fprintf(out, "Added %d violation(s) to %s; pass --baseline %s to treat them as known.\n", added, baselinePath, baselinePath)
+		}
+	}
+	for _, d := range ignored {
+		if err := analysis.AddIgnoreDirective(d.file, d.adrID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to add archguard-ignore: %s to %s: %v", d.adrID, d.file, err))
+			continue
+		}
+		fmt.Fprintf(out, "Added archguard-ignore: %s to %s\n", d.adrID, d.file)
+	}
+	return errors.Join(errs...)
+}
+
+// lastCommitsBase resolves --last n to HEAD~n, explaining when the history
+// is too short, e.g. in a shallow CI clone.
+func lastCommitsBase(n int) (string, error) {
+	base := fmt.Sprintf("HEAD~%d", n)
+	if git.CommitExists(base) {
+		return base, nil
+	}
+	msg := fmt.Sprintf("--last %d: %s does not exist", n, base)
+	if count, err := git.CountCommits("HEAD"); err == nil {
+		msg += fmt.Sprintf("; only %d commit(s) are available", count)
+	}
+	if git.IsShallowRepository() {
+		msg += fmt.Sprintf(". This is a shallow clone: fetch more history with `git fetch --deepen=%d` (or fetch-depth: 0 in actions/checkout)", n)
+	}
+	return "", errors.New(msg)
+}
+
+// runSelect analyzes file against the ADR with the given ID alone, printing
+// the prompt and raw response for reproducing a single analysis.
+func runSelect(cfg *config.Config, provider llm.Provider, content analysis.ContentProvider, adrs []index.ADR, id, file string) (ExitCode, error) {
+	var matches []*index.ADR
+	for i := range adrs {
+		if adrs[i].ID == id {
+			matches = append(matches, &adrs[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return ExitUsage, fmt.Errorf("--select: no ADR with ID %q in %s", id, cfg.Analysis.ADRPath)
+	case 1:
+	default:
+		var paths []string
+		for _, adr := range matches {
+			paths = append(paths, adr.RelPath)
+		}
+		return ExitUsage, fmt.Errorf("--select: ADR ID %q is used by %s", id, strings.Join(paths, ", "))
+	}
+
+	engine := analysis.NewEngine(cfg, nil, provider, content, false, false)
+	res, err := engine.Select(context.Background(), matches[0], file, os.Stdout)
+	if err != nil {
+		return ExitError, fmt.Errorf("--select: %v", err)
+	}
+	if res.Violation {
+		return ExitDriftDetected, nil
+	}
+	return ExitSuccess, nil
+}
+
+// runAudit analyzes every tracked file in a slow, rate-limited pass that
+// checkpoints after each file, so a first audit of a large repository can be
+// interrupted and resumed. It ends by writing a report of all violations.
+func runAudit(cfg *config.Config, provider llm.Provider, indexFile string, args []string) (ExitCode, error) {
+	auditFlags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	var flagParseOutput bytes.Buffer
+	auditFlags.SetOutput(&flagParseOutput)
+	rpm := auditFlags.Int("rpm", 60, "Maximum provider requests per minute; 0 disables the limit")
+	restart := auditFlags.Bool("restart", false, "Discard saved progress and audit every file again")
+	reportPath := auditFlags.String("report", config.StatePath("audit-report.txt"), "Where to write the final report")
+	debug := auditFlags.Bool("debug", false, "Enable debug logging")
+	color := auditFlags.String("color", "auto", "Color output: auto (only on a terminal, unless NO_COLOR is set), always, or never")
+	if err := auditFlags.Parse(args); err != nil {
+		if details := strings.TrimSpace(flagParseOutput.String()); details != "" {
+			return ExitUsage, fmt.Errorf("error parsing flags: %v\n%s", err, details)
+		}
+		return ExitUsage, fmt.Errorf("error parsing flags: %v", err)
+	}
+	if err := validateColor(*color); err != nil {
+		return ExitUsage, err
+	}
+
+	if *restart {
+		if err := os.Remove(config.StatePath(analysis.AuditStateFile)); err != nil && !os.IsNotExist(err) {
+			return ExitError, fmt.Errorf("failed to remove audit state: %v", err)
+		}
+	}
+	if *rpm > 0 {
+		provider = llm.NewThrottledProvider(provider, *rpm)
+	}
+
+	if err := checkProviderReachable(context.Background(), cfg, provider); err != nil {
+		return ExitConfig, err
+	}
+
+	store, adrs, err := loadIndex(cfg, provider, indexFile, false, os.Stdout)
+	if err != nil {
+		return ExitIndexError, err
+	}
+	indexHash, err := store.CalculateHash(adrs, cfg.VectorStore.Model)
+	if err != nil {
+		return ExitIndexError, fmt.Errorf("failed to calculate index hash: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	contentProvider := &analysis.AllProvider{}
+	analysis.SetDiffContextLines(contentProvider, cfg.Analysis.DiffContextLines)
+	analysis.SetFollowExternalSymlinks(contentProvider, cfg.Analysis.FollowSymlinks)
+	engine := analysis.NewEngine(cfg, store, provider, contentProvider, *debug, false)
+	engine.Color = useColor(*color)
+	engine.IndexHash = indexHash
+	state, err := engine.Audit(ctx, config.StatePath(analysis.AuditStateFile))
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("\nAudit interrupted after %d files; progress saved to %s. Run 'archguard audit' again to resume.\n", len(state.Files), config.StatePath(analysis.AuditStateFile))
+		return ExitError, fmt.Errorf("audit interrupted")
+	}
+	if err != nil {
+		return ExitError, fmt.Errorf("audit failed: %v", err)
+	}
+
+	f, err := os.Create(*reportPath)
+	if err != nil {
+		return ExitError, fmt.Errorf("failed to create audit report: %v", err)
+	}
+	if err := state.WriteReport(f); err != nil {
+		f.Close()
+		return ExitError, fmt.Errorf("failed to write audit report: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return ExitError, fmt.Errorf("failed to write audit report: %v", err)
+	}
+
+	fmt.Printf("\nAudit complete: %d files analyzed, %d violations. Report written to %s.\n", len(state.Files), state.Violations(), *reportPath)
+	if state.Violations() > 0 {
+		return ExitDriftDetected, &analysis.DriftDetectedError{Count: state.Violations()}
+	}
+	return ExitSuccess, nil
+}
+
+// checkProviderReachable makes one tiny embedding request before scanning, so
+// an unreachable provider (wrong base_url, Ollama not running) fails the run
+// with a single clear error instead of one per file.
+func checkProviderReachable(ctx context.Context, cfg *config.Config, provider llm.Provider) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if _, err := provider.CreateEmbedding(ctx, "archguard connectivity check"); err != nil {
+		return fmt.Errorf("cannot reach LLM provider at %s: %v", embeddingEndpoint(cfg), err)
+	}
+	return nil
+}
+
+// embeddingEndpoint describes where embedding requests are sent, for errors.
+func embeddingEndpoint(cfg *config.Config) string {
+	name, baseURL := cfg.LLM.Provider, cfg.LLM.BaseURL
+	if cfg.VectorStore.Provider != "" && cfg.VectorStore.Provider != cfg.LLM.Provider {
+		name, baseURL = cfg.VectorStore.Provider, cfg.VectorStore.BaseURL
+	}
+	if baseURL != "" {
+		return baseURL
+	}
+	switch name {
+	case "openai":
+		return "https://api.openai.com"
+	case "ollama":
+		return "http://localhost:11434"
+	case "gemini":
+		return "https://generativelanguage.googleapis.com"
+	}
+	return fmt.Sprintf("the default %s endpoint", name)
+}
+
+// loadIndex loads the ADR index, rebuilding it first when it is missing or out
+// of date, and returns the store along with the current ADRs.
+func loadIndex(cfg *config.Config, provider llm.Provider, indexFile string, noGit bool, out io.Writer) (index.VectorStore, []index.ADR, error) {
+	store, err := index.NewVectorStore(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize vector store: %v", err)
+	}
+
+	index.SetOutput(store, out)
+	adrProvider := newADRProvider(cfg)
+	index.SetOutput(adrProvider, out)
+
+	validADRs, err := adrProvider.GetADRs(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch ADRs: %v", err)
+	}
+
+	currentHash, err := store.CalculateHash(validADRs, cfg.VectorStore.Model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to calculate index hash: %v", err)
+	}
+
+	if err := store.Load(indexFile, cfg.VectorStore.Model, cfg.VectorStore.EmbeddingDim, currentHash); err != nil {
+		fmt.Fprintf(out, "Index metadata mismatch or missing index. Triggering index rebuild: %v\n", err)
+		if local, ok := store.(*index.LocalStore); ok {
+			for _, change := range local.ChangedADRs(validADRs) {
+				fmt.Fprintf(out, "  %s\n", change)
+			}
+		}
+		if _, err := runIndex(context.Background(), cfg, provider, indexFile, false, noGit, out); err != nil {
+			return nil, nil, fmt.Errorf("index rebuild failed: %v", err)
+		}
+
+		// Reload the index after a successful rebuild to ensure the latest state is in memory.
+		currentHash, _ = store.CalculateHash(validADRs, cfg.VectorStore.Model)
+		if err := store.Load(indexFile, cfg.VectorStore.Model, cfg.VectorStore.EmbeddingDim, currentHash); err != nil {
+			return nil, nil, fmt.Errorf("failed to load rebuilt index: %v", err)
+		}
+	}
+	return store, validADRs, nil
+}
+
+// printRunSummary prints the closing report shown after every check run.
+func printRunSummary(out io.Writer, s *analysis.RunSummary, adrs int) {
+	result := "PASS"
+	if s.Violations > 0 {
+		result = "FAIL"
+	}
+	violations := fmt.Sprintf("%d violations", s.Violations)
+	if s.Known > 0 {
+		violations = fmt.Sprintf("%d new violations, %d known", s.Violations, s.Known)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Summary: %s | %d files scanned, %d skipped | %d ADRs indexed | %s | cache hit rate %.0f%% (%d/%d) | %s\n",
+		result, s.FilesScanned, s.FilesSkipped, adrs, violations,
+		s.CacheHitRate()*100, s.CacheHits, s.CacheHits+s.LLMCalls, s.Duration.Round(time.Millisecond))
+}
+
+// searchedADRs returns the ADRs a check retrieves from: the loaded local
+// index, which --only-changed-adrs may have narrowed, or else the ADRs read
+// from analysis.adr_path, which other backends store on index.
+func searchedADRs(store index.VectorStore, validADRs []index.ADR) []index.ADR {
+	if local, ok := store.(*index.LocalStore); ok {
+		return local.ADRs
+	}
+	return validADRs
+}
+
+// restrictToChangedADRs narrows the loaded local index to the ADRs added or
+// changed relative to the index it replaced, and returns how many remain.
+func restrictToChangedADRs(store index.VectorStore, indexFile string, out io.Writer) (int, error) {
+	local, ok := store.(*index.LocalStore)
+	if !ok {
+		return 0, fmt.Errorf("only supported with the local index, not pgvector")
+	}
+	prev, err := index.ReadLocalIndex(indexFile + index.PreviousIndexSuffix)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("no previous index to compare against; edit an ADR and run 'archguard index' (or check) first")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read previous index: %v", err)
+	}
+
+	changed := make(map[string]bool)
+	for _, path := range prev.ChangedADRPaths(local.ADRs) {
+		changed[path] = true
+	}
+	var kept []index.ADR
+	for _, adr := range local.ADRs {
+		if changed[adr.RelPath] {
+			fmt.Fprintf(out, "Checking against changed ADR %s (%s)\n", adr.ID, adr.RelPath)
+			kept = append(kept, adr)
+		}
+	}
+	local.ADRs = kept
+	return len(kept), nil
+}
+
+// runCompare diffs two reports written by `check --format jsonl` and fails
+// with the drift exit code when the new one has violations the old one did
+// not, so existing debt is tolerated while regressions are not.
+func runCompare(args []string) (ExitCode, error) {
+	if len(args) != 2 {
+		return ExitUsage, fmt.Errorf("usage: archguard compare OLD.jsonl NEW.jsonl")
+	}
+	var reports [2][]analysis.ViolationRecord
+	for i, path := range args {
+		f, err := os.Open(path)
+		if err != nil {
+			return ExitError, fmt.Errorf("failed to open report: %v", err)
+		}
+		reports[i], err = analysis.ReadViolationRecords(f)
+		f.Close()
+		if err != nil {
+			return ExitError, fmt.Errorf("failed to read report %s: %v", path, err)
+		}
+	}
+
+	diff := analysis.CompareReports(reports[0], reports[1])
+	sections := []struct {
+		title   string
+		records []analysis.ViolationRecord
+	}{
+		{"New", diff.Added},
+		{"Fixed", diff.Removed},
+		{"Unchanged", diff.Unchanged},
+	}
+	for _, section := range sections {
+		if len(section.records) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s (%d):\n", section.title, len(section.records))
+		for _, rec := range section.records {
+			fmt.Printf("  %s:%d %s (ADR %s)\n", rec.File, rec.Line, rec.ADRTitle, rec.ADRID)
+			if rec.Code != "" {
+				fmt.Printf("    Code: %s\n", rec.Code)
+			}
+		}
+	}
+
+	fmt.Printf("\nCompare: %d new, %d fixed, %d unchanged\n", len(diff.Added), len(diff.Removed), len(diff.Unchanged))
+	if len(diff.Added) > 0 {
+		return ExitDriftDetected, &analysis.DriftDetectedError{Count: len(diff.Added)}
+	}
+	return ExitSuccess, nil
+}
+
+// runLintADRs asks the model whether ADRs with similar embeddings contradict
+// each other, to keep a growing decision log consistent.
+func runLintADRs(cfg *config.Config, provider llm.Provider, indexFile string, args []string) (ExitCode, error) {
+	lintFlags := flag.NewFlagSet("lint-adrs", flag.ContinueOnError)
+	var flagParseOutput bytes.Buffer
+	lintFlags.SetOutput(&flagParseOutput)
+	threshold := lintFlags.Float64("threshold", analysis.DefaultConflictThreshold, "Compare ADR pairs at least this similar (0-1)")
+	noGit := lintFlags.Bool("no-git", false, "Run outside a git repository")
+	debug := lintFlags.Bool("debug", false, "Enable debug logging")
+	if err := lintFlags.Parse(args); err != nil {
+		if details := strings.TrimSpace(flagParseOutput.String()); details != "" {
+			return ExitUsage, fmt.Errorf("error parsing flags: %v\n%s", err, details)
+		}
+		return ExitUsage, fmt.Errorf("error parsing flags: %v", err)
+	}
+
+	store, adrs, err := loadIndex(cfg, provider, indexFile, *noGit, os.Stdout)
+	if err != nil {
+		return ExitIndexError, err
+	}
+	// The local index already holds every ADR's embedding; other stores are
+	// embedded again through the cache.
+	if local, ok := store.(*index.LocalStore); ok {
+		adrs = local.ADRs
+	}
+
+	engine := analysis.NewEngine(cfg, store, provider, nil, *debug, false)
+	conflicts, checked, err := engine.LintADRs(context.Background(), adrs, *threshold)
+	for _, c := range conflicts {
+		fmt.Printf("\n[CONFLICT] %s (%s) vs %s (%s), similarity %.2f\n", c.A.Title, c.A.RelPath, c.B.Title, c.B.RelPath, c.Score)
+		fmt.Printf("    Reasoning: %s\n", c.Reasoning)
+		if c.RuleA != "" {
+			fmt.Printf("    %s: %s\n", c.A.ID, c.RuleA)
+		}
+		if c.RuleB != "" {
+			fmt.Printf("    %s: %s\n", c.B.ID, c.RuleB)
+		}
+	}
+	fmt.Printf("\nLint: %d ADRs, %d similar pair(s) compared, %d potential conflict(s)\n", len(adrs), checked, len(conflicts))
+	if err != nil {
+		return ExitError, fmt.Errorf("lint-adrs failed: %v", err)
+	}
+	if len(conflicts) > 0 {
+		return ExitDriftDetected, fmt.Errorf("found %d potential ADR conflict(s)", len(conflicts))
+	}
+	return ExitSuccess, nil
+}
+
+// runReport prints the violations and summary saved by the last check, without
+// scanning again.
+func runReport() (ExitCode, error) {
+	run, err := analysis.ReadLastRun(config.StatePath(analysis.LastRunFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ExitError, fmt.Errorf("no saved run found; run 'archguard check' first")
+	}
+	if err != nil {
+		return ExitError, fmt.Errorf("failed to read %s: %v", config.StatePath(analysis.LastRunFile), err)
+	}
+
+	fmt.Printf("Last run: archguard check %s (%s)\n", strings.Join(run.Args, " "), run.FinishedAt.Local().Format(time.RFC1123))
+	file := ""
+	for _, rec := range run.Violations {
+		if rec.File != file {
+			file = rec.File
+			fmt.Printf("\n%s\n", file)
+		}
+		if rec.Baseline {
+			fmt.Printf("    [KNOWN] %s [Line %d] (in baseline)\n", rec.ADRTitle, rec.Line)
+			continue
+		}
+		if rec.LineApproximate {
+			fmt.Printf("    [VIOLATION] %s [Line %d, approximate]\n", rec.ADRTitle, rec.Line)
+		} else {
+			fmt.Printf("    [VIOLATION] %s [Line %d]\n", rec.ADRTitle, rec.Line)
+		}
+		fmt.Printf("    Reasoning: %s\n", rec.Reasoning)
+		if rec.Rule != "" {
+			fmt.Printf("    Rule: %s\n", rec.Rule)
+		}
+		if rec.Code != "" {
+			fmt.Printf("    Code: %s\n", rec.Code)
+		}
+	}
+	printRunSummary(os.Stdout, run.Summary(), run.ADRsIndexed)
+
+	if run.Error != "" {
+		return ExitError, fmt.Errorf("the last run failed: %s", run.Error)
+	}
+	if n := run.Summary().Violations; n > 0 {
+		return ExitDriftDetected, &analysis.DriftDetectedError{Count: n}
+	}
 	return ExitSuccess, nil
 }
 
+// fileSystemProviderFor picks the --no-git content provider for the optional
+// path argument: the whole tree, a glob, a directory, or a single file.
+func fileSystemProviderFor(files []string) (analysis.ContentProvider, error) {
+	if len(files) == 0 || files[0] == "." {
+		return &analysis.FileSystemProvider{}, nil
+	}
+	target := files[0]
+	if analysis.IsGlob(target) {
+		return &analysis.FileSystemProvider{Pattern: target}, nil
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &analysis.SubtreeProvider{ContentProvider: &analysis.FileSystemProvider{}, Root: filepath.ToSlash(filepath.Clean(target))}, nil
+	}
+	return &analysis.SingleFileProvider{Path: target}, nil
+}
+
+// splitList splits a comma-separated flag value, dropping blank entries.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// contentProviderForMode resolves the analysis.default_mode config value to the
+// ContentProvider used when no explicit mode flag or path is given.
+func contentProviderForMode(mode string) (analysis.ContentProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "uncommitted":
+		return &analysis.UncommittedProvider{}, nil
+	case "staged":
+		return &analysis.StagedProvider{}, nil
+	case "working":
+		return &analysis.WorkingProvider{}, nil
+	case "all":
+		return &analysis.AllProvider{}, nil
+	default:
+		return nil, fmt.Errorf("invalid analysis.default_mode %q (expected uncommitted, staged, working, or all)", mode)
+	}
+}
+
+// isInteractive reports whether stdin is attached to a terminal.
+func isInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// validateColor rejects a --color value other than auto, always, or never.
+func validateColor(mode string) error {
+	switch mode {
+	case "auto", "always", "never":
+		return nil
+	}
+	return fmt.Errorf("invalid --color %q (expected auto, always, or never)", mode)
+}
+
+// useColor resolves --color. auto colors output only when stdout is a
+// terminal and neither NO_COLOR (https://no-color.org) nor TERM=dumb is set.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmPrompt asks a yes/no question on stdin.
+func confirmPrompt(prompt string) bool {
+	fmt.Print(prompt)
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		return false
+	}
+	return strings.ToLower(strings.TrimSpace(scanner.Text())) == "y"
+}
+
+// verbosityFlag is a repeatable boolean-style flag that adds step to level each
+// time it appears, so "-v -v" and "-vv" both yield level 2.
+type verbosityFlag struct {
+	level *int
+	step  int
+}
+
+func (f *verbosityFlag) String() string {
+	if f.level == nil {
+		return "0"
+	}
+	return strconv.Itoa(*f.level)
+}
+
+func (f *verbosityFlag) Set(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid verbosity value %q", value)
+	}
+	if enabled {
+		*f.level += f.step
+	}
+	return nil
+}
+
+func (f *verbosityFlag) IsBoolFlag() bool { return true }
+
 func exitCodeForAnalysisError(err error) ExitCode {
 	var driftErr *analysis.DriftDetectedError
 	if errors.As(err, &driftErr) {
@@ -408,14 +1653,158 @@ func exitCodeForAnalysisError(err error) ExitCode {
 }
 
 // runIndex scans the ADR directory and builds a vector index for subsequent drift analysis.
-func runIndex(ctx context.Context, cfg *config.Config, provider llm.Provider, indexFile string) (ExitCode, error) {
+// Without git (noGit), scopes cannot be checked against tracked files.
+func runIndex(ctx context.Context, cfg *config.Config, provider llm.Provider, indexFile string, strict, noGit bool, out io.Writer) (ExitCode, error) {
 	store, err := index.NewVectorStore(cfg)
 	if err != nil {
 		return ExitIndexError, fmt.Errorf("failed to initialize vector store: %w", err)
 	}
 
+	index.SetOutput(store, out)
+	adrProvider := newADRProvider(cfg)
+	index.SetOutput(adrProvider, out)
+
+	adrs, err := adrProvider.GetADRs(ctx)
+	if err != nil {
+		return ExitIndexError, fmt.Errorf("failed to fetch ADRs: %w", err)
+	}
+	validators := []func([]index.ADR) error{validateIDs}
+	if !noGit {
+		validators = append(validators, validateScopes)
+	}
+	for _, validate := range validators {
+		if err := validate(adrs); err != nil {
+			if strict {
+				return ExitIndexError, err
+			}
+			fmt.Fprintf(out, "Warning: %v\n", err)
+		}
+	}
+
+	if err := store.BuildIndex(ctx, cfg.VectorStore.Model, cfg.VectorStore.EmbeddingDim, provider, adrProvider); err != nil {
+		return ExitIndexError, fmt.Errorf("failed to build index: %w", err)
+	}
+
+	if err := store.Save(indexFile); err != nil {
+		return ExitIndexError, fmt.Errorf("failed to save index: %w", err)
+	}
+	fmt.Fprintln(out, "ADR Index updated successfully.")
+	return ExitSuccess, nil
+}
+
+// runValidateADRs parses the ADRs as runIndex would and reports which would
+// be indexed and which skipped, and why, then runs the ID and scope checks.
+// Nothing is embedded and the index is not written. It fails with
+// ExitIndexError when an ADR does not parse or a check finds a problem;
+// ADRs skipped for their status are expected and do not fail it.
+func runValidateADRs(ctx context.Context, cfg *config.Config, noGit bool) (ExitCode, error) {
+	entries, err := index.PreviewADRs(ctx, newADRProvider(cfg))
+	if err != nil {
+		return ExitIndexError, fmt.Errorf("failed to fetch ADRs: %w", err)
+	}
+
+	var adrs []index.ADR
+	var skipped []index.PreviewEntry
+	parseErrors := 0
+	for _, entry := range entries {
+		switch {
+		case entry.ADR == nil:
+			parseErrors++
+			skipped = append(skipped, entry)
+		case entry.Skipped != "":
+			skipped = append(skipped, entry)
+		default:
+			adrs = append(adrs, *entry.ADR)
+		}
+	}
+
+	fmt.Printf("Would index %d ADR(s):\n", len(adrs))
+	for _, adr := range adrs {
+		fmt.Printf("  %s  %s (%s, %s)\n", adr.ID, adr.Title, adr.RelPath, adr.Status)
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("Would skip %d:\n", len(skipped))
+		for _, entry := range skipped {
+			name := entry.Path
+			if entry.ADR != nil {
+				name = entry.ADR.RelPath
+			}
+			fmt.Printf("  %s: %s\n", name, entry.Skipped)
+		}
+	}
+
+	problems := parseErrors
+	validators := []func([]index.ADR) error{validateIDs}
+	if !noGit {
+		validators = append(validators, validateScopes)
+	}
+	for _, validate := range validators {
+		if err := validate(adrs); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			problems++
+		}
+	}
+	if len(adrs) == 0 {
+		fmt.Println("Warning: no ADR would be indexed.")
+	}
+	if problems > 0 {
+		return ExitIndexError, fmt.Errorf("ADR validation failed: %d problem(s)", problems)
+	}
+	fmt.Println("All ADRs are valid.")
+	return ExitSuccess, nil
+}
+
+// validateIDs reports ADRs sharing an ID, e.g. when two branches both claimed
+// the next number. archguard-ignore directives name ADRs by ID, so duplicates
+// make suppression ambiguous.
+func validateIDs(adrs []index.ADR) error {
+	dups := index.DuplicateIDs(adrs)
+	if len(dups) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(dups))
+	for id := range dups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var lines []string
+	for _, id := range ids {
+		lines = append(lines, fmt.Sprintf("  %s: %s", id, strings.Join(dups[id], ", ")))
+	}
+	return fmt.Errorf("%d ADR ID(s) are used by more than one ADR:\n%s", len(dups), strings.Join(lines, "\n"))
+}
+
+// validateScopes reports ADRs whose scope glob matches no tracked file, which
+// almost always means the scope is mistyped.
+func validateScopes(adrs []index.ADR) error {
+	files, err := git.GetAllTrackedFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list tracked files: %v", err)
+	}
+	// ADRs scoped to the virtual commit message file are checked via --commit-msg.
+	files = append(files, analysis.CommitMessagePath)
+
+	unmatched := index.UnmatchedScopes(adrs, files)
+	if len(unmatched) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, adr := range unmatched {
+		lines = append(lines, fmt.Sprintf("  %s (%s): scope %q", adr.Title, adr.RelPath, strings.Join(adr.Scope, ", ")))
+	}
+	return fmt.Errorf("%d ADR scope(s) match no tracked files:\n%s", len(unmatched), strings.Join(lines, "\n"))
+}
+
+// newADRProvider aggregates the configured ADR sources: the local ADR directory
+// (or policy documents, with analysis.policy_mode) and, when enabled, a
+// Confluence space.
+func newADRProvider(cfg *config.Config) index.Provider {
 	var providers []index.Provider
-	providers = append(providers, index.NewLocalProvider(cfg.Analysis.ADRPath, cfg.Analysis.AcceptedStatuses))
+	if cfg.Analysis.PolicyMode {
+		providers = append(providers, index.NewPolicyProvider(cfg.Analysis.ADRPath, cfg.Analysis.AcceptedStatuses))
+	} else {
+		providers = append(providers, index.NewLocalProvider(cfg.Analysis.ADRPath, cfg.Analysis.AcceptedStatuses))
+	}
 
 	if cfg.Analysis.Confluence.Enabled {
 		providers = append(providers, index.NewConfluenceProvider(
@@ -426,25 +1815,91 @@ func runIndex(ctx context.Context, cfg *config.Config, provider llm.Provider, in
 			cfg.Analysis.AcceptedStatuses,
 		))
 	}
-	adrProvider := index.NewCompositeProvider(providers...)
+	return index.NewCompositeProvider(providers...)
+}
 
-	if err := store.BuildIndex(ctx, cfg.VectorStore.Model, cfg.VectorStore.EmbeddingDim, provider, adrProvider); err != nil {
-		return ExitIndexError, fmt.Errorf("failed to build index: %w", err)
+// runCache dispatches cache maintenance subcommands.
+func runCache(cfg *config.Config, args []string) (ExitCode, error) {
+	if len(args) == 0 || args[0] != "prune" {
+		return ExitUsage, fmt.Errorf("usage: archguard cache prune [--max-age DAYS] [--show]")
 	}
 
-	if err := store.Save(indexFile); err != nil {
-		return ExitIndexError, fmt.Errorf("failed to save index: %w", err)
+	pruneFlags := flag.NewFlagSet("cache prune", flag.ContinueOnError)
+	var flagParseOutput bytes.Buffer
+	pruneFlags.SetOutput(&flagParseOutput)
+	maxAge := pruneFlags.Int("max-age", 0, "Delete entries older than this many days instead of unreachable entries")
+	show := pruneFlags.Bool("show", false, "Dry run: report what would be removed without deleting")
+
+	if err := pruneFlags.Parse(args[1:]); err != nil {
+		if details := strings.TrimSpace(flagParseOutput.String()); details != "" {
+			return ExitUsage, fmt.Errorf("error parsing flags: %v\n%s", err, details)
+		}
+		return ExitUsage, fmt.Errorf("error parsing flags: %v", err)
 	}
-	fmt.Println("ADR Index updated successfully.")
+
+	engine := analysis.NewEngine(cfg, nil, nil, &analysis.AllProvider{}, false, false)
+	if engine.Cache == nil {
+		return ExitError, fmt.Errorf("failed to open analysis cache")
+	}
+
+	var stale func(cache.Entry) bool
+	if *maxAge > 0 {
+		cutoff := time.Now().AddDate(0, 0, -*maxAge)
+		stale = func(e cache.Entry) bool { return e.ModTime.Before(cutoff) }
+	} else {
+		adrs, err := newADRProvider(cfg).GetADRs(context.Background())
+		if err != nil {
+			return ExitIndexError, fmt.Errorf("failed to fetch ADRs: %v", err)
+		}
+		live, err := engine.LiveCacheKeys(adrs)
+		if err != nil {
+			return ExitError, fmt.Errorf("failed to compute live cache keys: %v", err)
+		}
+		stale = func(e cache.Entry) bool { return !live[e.Key] }
+	}
+
+	stats, err := engine.Cache.Prune(stale, *show)
+	if err != nil {
+		return ExitError, err
+	}
+
+	verb := "Removed"
+	if *show {
+		verb = "Would remove"
+	}
+	fmt.Printf("%s %d of %d cache entries (%s).\n", verb, stats.Removed, stats.Scanned, formatBytes(stats.Bytes))
 	return ExitSuccess, nil
 }
 
+// formatBytes renders a byte count in human-readable binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func printUsage() {
-	fmt.Println("Usage: archguard <command> [arguments]")
+	fmt.Printf("Usage: %s <command> [arguments]\n", ProgramName)
 	fmt.Println("\nCommands:")
 	fmt.Println("  init     Initialize ArchGuard in the current repository (local setup)")
 	fmt.Println("  check    Check for architectural violations")
-	fmt.Println("  index    Rebuild the ADR index")
+	fmt.Println("  index    Rebuild the ADR index (--strict fails on scopes matching no files, --adr-dir overrides analysis.adr_path)")
+	fmt.Println("  new      Create the next numbered ADR from the template (new \"Title\" [--status S])")
+	fmt.Println("  audit    Scan all tracked files in a rate-limited, resumable pass and write a report")
+	fmt.Println("  cache    Maintain the analysis cache (cache prune [--max-age DAYS] [--show])")
+	fmt.Println("  lint-adrs Ask the LLM whether similar ADRs contradict each other (--threshold 0.8)")
+	fmt.Println("  report   Print the violations and summary of the last check without scanning again")
+	fmt.Println("  compare  Compare two --format jsonl reports (compare OLD NEW); fails on new violations")
+	fmt.Println("  config   Print the effective config with secrets redacted (config show)")
+	fmt.Println("  schema   Print a JSON Schema for archguard.yaml (for editor completion)")
 	fmt.Println("\nGlobal Flags:")
 	fmt.Println("  -v, --version  Print version information")
+	fmt.Printf("  --quiet        Do not print the banner (or set %s)\n", NoBannerEnv)
 }
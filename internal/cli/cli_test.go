@@ -1,11 +1,24 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/tgenz1213/archguard/internal/analysis"
+	"github.com/tgenz1213/archguard/internal/config"
+	"github.com/tgenz1213/archguard/internal/index"
+	"github.com/tgenz1213/archguard/internal/llm"
+	pkgllm "github.com/tgenz1213/archguard/pkg/llm"
 )
 
 func TestExitCodeForAnalysisError(t *testing.T) {
@@ -30,3 +43,406 @@ func TestExitCodeForAnalysisError(t *testing.T) {
 		}
 	})
 }
+
+func TestContentProviderForMode(t *testing.T) {
+	cases := []struct {
+		mode string
+		want analysis.ContentProvider
+	}{
+		{"", &analysis.UncommittedProvider{}},
+		{"uncommitted", &analysis.UncommittedProvider{}},
+		{"staged", &analysis.StagedProvider{}},
+		{"working", &analysis.WorkingProvider{}},
+		{"ALL", &analysis.AllProvider{}},
+	}
+
+	for _, c := range cases {
+		got, err := contentProviderForMode(c.mode)
+		if err != nil {
+			t.Fatalf("contentProviderForMode(%q) returned error: %v", c.mode, err)
+		}
+		if fmt.Sprintf("%T", got) != fmt.Sprintf("%T", c.want) {
+			t.Errorf("contentProviderForMode(%q) = %T, want %T", c.mode, got, c.want)
+		}
+	}
+
+	if _, err := contentProviderForMode("everything"); err == nil {
+		t.Error("expected error for unknown mode, got nil")
+	}
+}
+
+func TestVerbosityFlag_Accumulates(t *testing.T) {
+	var level int
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.Var(&verbosityFlag{level: &level, step: 1}, "v", "")
+	fs.Var(&verbosityFlag{level: &level, step: 2}, "vv", "")
+
+	if err := fs.Parse([]string{"-v", "-vv"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if level != 3 {
+		t.Errorf("expected verbosity 3, got %d", level)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		512:             "512 B",
+		2048:            "2.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for n, want := range cases {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestCheckProviderReachable(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LLM.Provider = "ollama"
+	cfg.LLM.BaseURL = "http://ollama.internal:11434"
+
+	down := &llm.MockProvider{EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+		return nil, errors.New("connection refused")
+	}}
+	err := checkProviderReachable(context.Background(), cfg, down)
+	if err == nil || !strings.Contains(err.Error(), "cannot reach LLM provider at http://ollama.internal:11434: connection refused") {
+		t.Errorf("expected a single clear connectivity error, got %v", err)
+	}
+
+	if err := checkProviderReachable(context.Background(), cfg, &llm.MockProvider{}); err != nil {
+		t.Errorf("expected reachable provider to pass, got %v", err)
+	}
+}
+
+func TestRunNew_WritesNextNumberedADR(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"0001-use-go.md", "0009-no-orms.md", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Analysis: config.Analysis{ADRPath: dir}}
+	code, err := runNew(cfg, []string{"Use gRPC (internal) for services!"})
+	if err != nil || code != ExitSuccess {
+		t.Fatalf("runNew returned %d, %v", code, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "0010-use-grpc-internal-for-services.md"))
+	if err != nil {
+		t.Fatalf("expected new ADR file: %v", err)
+	}
+	adr, err := index.ParseADRContent(data, "0010", "0010-use-grpc-internal-for-services.md")
+	if err != nil {
+		t.Fatalf("new ADR does not parse: %v", err)
+	}
+	if adr.Title != "Use gRPC (internal) for services!" || adr.Status != "Proposed" || len(adr.Scope) != 0 {
+		t.Errorf("unexpected frontmatter: title=%q status=%q scope=%q", adr.Title, adr.Status, adr.Scope)
+	}
+	if !strings.Contains(adr.Content, "# Use gRPC (internal) for services!") {
+		t.Errorf("expected heading with title, got:\n%s", adr.Content)
+	}
+
+	if code, _ := runNew(cfg, nil); code != ExitUsage {
+		t.Errorf("expected usage error without a title, got %d", code)
+	}
+}
+
+func TestEnsureGitignore_Idempotent(t *testing.T) {
+	cases := map[string]string{
+		"crlf":           "node_modules/\r\n.archguard/\r\n",
+		"no slash":       "bin\n.archguard\n",
+		"anchored":       "/.archguard/",
+		"missing (crlf)": "node_modules/\r\ndist/",
+		"empty":          "",
+	}
+	for name, initial := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if initial != "" {
+				if err := os.WriteFile(".gitignore", []byte(initial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 2; i++ {
+				if err := ensureGitignore(); err != nil {
+					t.Fatalf("ensureGitignore failed: %v", err)
+				}
+			}
+			data, err := os.ReadFile(".gitignore")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(data)
+			if ignoresArchguard(initial) {
+				if got != initial {
+					t.Errorf("expected .gitignore unchanged, got %q", got)
+				}
+				return
+			}
+			want := initial + ".archguard/\n"
+			if strings.Contains(initial, "\r\n") {
+				want = initial + "\r\n.archguard/\r\n"
+			}
+			if got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestHasFlag(t *testing.T) {
+	cases := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"--no-git"}, true},
+		{[]string{"--all", "-no-git"}, true},
+		{[]string{"--no-git=true"}, true},
+		{[]string{"--no-git=false"}, false},
+		{[]string{"--", "--no-git"}, false},
+		{[]string{"src/no-git"}, false},
+	}
+	for _, c := range cases {
+		if got := hasFlag(c.args, "no-git"); got != c.want {
+			t.Errorf("hasFlag(%q) = %v, want %v", c.args, got, c.want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	if !useColor("always") || useColor("never") {
+		t.Error("expected always and never to be honored")
+	}
+	// Test output is not a terminal.
+	if useColor("auto") {
+		t.Error("expected auto to disable color when stdout is not a terminal")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if !useColor("always") {
+		t.Error("expected always to override NO_COLOR")
+	}
+	if err := validateColor("sometimes"); err == nil {
+		t.Error("expected an invalid --color value to be rejected")
+	}
+}
+
+func TestLastCommitsBase(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Chdir(t.TempDir())
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "one"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "--allow-empty", "-m", "two"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	if base, err := lastCommitsBase(1); err != nil || base != "HEAD~1" {
+		t.Errorf("lastCommitsBase(1) = %q, %v; want HEAD~1", base, err)
+	}
+	_, err := lastCommitsBase(5)
+	if err == nil || !strings.Contains(err.Error(), "HEAD~5 does not exist; only 2 commit(s) are available") {
+		t.Errorf("expected an error naming the available history, got %v", err)
+	}
+}
+
+func TestRestrictToChangedADRs(t *testing.T) {
+	indexFile := filepath.Join(t.TempDir(), "index.json")
+	store := index.NewLocalStore(1)
+	store.ADRs = []index.ADR{
+		{ID: "0001", RelPath: "0001-use-go.md", Content: "Use Go."},
+		{ID: "0007", RelPath: "0007-use-grpc.md", Content: "Use gRPC."},
+	}
+
+	if _, err := restrictToChangedADRs(store, indexFile, io.Discard); err == nil {
+		t.Fatal("expected an error without a previous index")
+	}
+
+	prev := index.NewLocalStore(1)
+	prev.ADRs = []index.ADR{
+		{ID: "0001", RelPath: "0001-use-go.md", Content: "Use Go."},
+		{ID: "0007", RelPath: "0007-use-grpc.md", Content: "Use REST."},
+	}
+	if err := prev.Save(indexFile + index.PreviousIndexSuffix); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	n, err := restrictToChangedADRs(store, indexFile, &out)
+	if err != nil {
+		t.Fatalf("restrictToChangedADRs failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Checking against changed ADR 0007") {
+		t.Errorf("expected the changed ADR to be listed on the given writer, got %q", out.String())
+	}
+	if n != 1 || len(store.ADRs) != 1 || store.ADRs[0].ID != "0007" {
+		t.Errorf("expected only ADR 0007 to remain, got %d: %+v", n, store.ADRs)
+	}
+	if searched := searchedADRs(store, prev.ADRs); len(searched) != 1 || searched[0].ID != "0007" {
+		t.Errorf("expected --tags to be checked against the narrowed index, got %+v", searched)
+	}
+}
+
+func TestRunValidateADRs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("0001-use-go.md", "---\ntitle: Use Go\nstatus: Accepted\n---\n## Decision\nUse Go.\n")
+	write("0002-draft.md", "---\ntitle: Draft\nstatus: Proposed\n---\n## Decision\nMaybe.\n")
+
+	cfg := &config.Config{Analysis: config.Analysis{ADRPath: dir, AcceptedStatuses: []string{"Accepted"}}}
+	if code, err := runValidateADRs(context.Background(), cfg, true); code != ExitSuccess {
+		t.Fatalf("expected a status skip not to fail validation, got %d, %v", code, err)
+	}
+
+	write("0003-broken.md", "---\ntitle: [unterminated\n---\n")
+	if code, _ := runValidateADRs(context.Background(), cfg, true); code != ExitIndexError {
+		t.Errorf("expected exit %d for an ADR that does not parse, got %d", ExitIndexError, code)
+	}
+}
+
+func TestTriageViolations(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "db.go")
+	if err := os.WriteFile(file, []byte("package db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	baseline := filepath.Join(dir, "baseline.jsonl")
+	records := []analysis.ViolationRecord{
+		{File: file, Line: 1, ADRID: "0001", ADRTitle: "No raw SQL", Code: "db.Exec(q)"},
+		{File: file, Line: 2, ADRID: "0002", ADRTitle: "Use contexts", Code: "db.Query(q)"},
+		{File: file, Line: 3, ADRID: "0003", ADRTitle: "Known", Baseline: true},
+		{File: file, Line: 4, ADRID: "0004", ADRTitle: "Later"},
+		{File: file, Line: 5, ADRID: "0005", ADRTitle: "Unseen"},
+	}
+
+	// "x" is not an option and is asked again; "q" leaves 0005 untouched.
+	var out strings.Builder
+	in := strings.NewReader("a\nx\ni\nf\nq\n")
+	if err := triageViolations(records, in, &out, baseline); err != nil {
+		t.Fatalf("triageViolations failed: %v", err)
+	}
+
+	f, err := os.Open(baseline)
+	if err != nil {
+		t.Fatalf("expected a baseline file: %v", err)
+	}
+	accepted, err := analysis.ReadViolationRecords(f)
+	f.Close()
+	if err != nil || len(accepted) != 1 || accepted[0].ADRID != "0001" {
+		t.Errorf("expected only 0001 in the baseline, got %+v (%v)", accepted, err)
+	}
+	data, _ := os.ReadFile(file)
+	if string(data) != "// archguard-ignore: 0002\n\npackage db\n" {
+		t.Errorf("expected an ignore directive for 0002, got %q", data)
+	}
+	if strings.Contains(out.String(), "Known") {
+		t.Error("expected baseline violations not to be triaged")
+	}
+
+	// Accepting the same violation again does not duplicate it.
+	if err := triageViolations(records[:1], strings.NewReader("a\n"), &out, baseline); err != nil {
+		t.Fatal(err)
+	}
+	f, _ = os.Open(baseline)
+	accepted, _ = analysis.ReadViolationRecords(f)
+	f.Close()
+	if len(accepted) != 1 {
+		t.Errorf("expected the baseline to keep 1 record, got %d", len(accepted))
+	}
+}
+
+func TestBuildProvider_BuiltIns(t *testing.T) {
+	t.Setenv(config.DefaultAPIKeyEnv, "test-key")
+	cfg := &config.Config{
+		LLM:         config.LLMConfig{Model: "gemini-1.5-flash"},
+		VectorStore: config.VectorStore{Model: "text-embedding-004"},
+	}
+	for name, want := range map[string]string{
+		"openai": "*llm.OpenAIProvider",
+		"ollama": "*llm.OllamaProvider",
+		"gemini": "*llm.GeminiProvider",
+	} {
+		p, err := buildProvider(name, "", cfg, false)
+		if err != nil {
+			t.Fatalf("buildProvider(%q) failed: %v", name, err)
+		}
+		if got := fmt.Sprintf("%T", p); got != want {
+			t.Errorf("buildProvider(%q) = %s, want %s", name, got, want)
+		}
+	}
+	if _, err := buildProvider("nope", "", cfg, false); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+
+	pkgllm.Register("cli-test-gateway", func(*pkgllm.Config) pkgllm.Provider { return &llm.MockProvider{} })
+	if p, err := buildProvider("cli-test-gateway", "", cfg, false); err != nil {
+		t.Errorf("expected the registered provider to be built, got %v", err)
+	} else if _, ok := p.(*llm.MockProvider); !ok {
+		t.Errorf("expected the registered provider, got %T", p)
+	}
+}
+
+func TestShowBanner(t *testing.T) {
+	t.Setenv(NoBannerEnv, "")
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"check", "--all"}, true},
+		{[]string{"check", "--format", "text"}, true},
+		{[]string{"check", "--format", "jsonl"}, false},
+		{[]string{"check", "-format=markdown"}, false},
+		{[]string{"check", "--", "--format=jsonl"}, true},
+		{[]string{"config", "show"}, false},
+		{[]string{"--version"}, false},
+	}
+	for _, tt := range tests {
+		if got := showBanner(tt.args, false); got != tt.want {
+			t.Errorf("showBanner(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+	if showBanner([]string{"check"}, true) {
+		t.Error("expected --quiet to suppress the banner")
+	}
+	t.Setenv(NoBannerEnv, "1")
+	if showBanner([]string{"check"}, false) {
+		t.Errorf("expected %s to suppress the banner", NoBannerEnv)
+	}
+}
+
+func TestExecute_NoCommand(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	for _, argv := range [][]string{{"archguard"}, {"archguard", "--quiet"}} {
+		os.Args = argv
+		code, err := Execute(nil)
+		if code != ExitUsage || err == nil || !strings.Contains(err.Error(), "no command provided") {
+			t.Errorf("%v: expected a usage error, got %d, %v", argv, code, err)
+		}
+	}
+}
+
+func TestRemoveFlag(t *testing.T) {
+	args, set := removeFlag([]string{"archguard", "check", "--quiet", "--all", "--", "--quiet"}, "quiet")
+	if !set || !slices.Equal(args, []string{"archguard", "check", "--all", "--", "--quiet"}) {
+		t.Errorf("removeFlag = %q, %v", args, set)
+	}
+	if _, set := removeFlag([]string{"archguard", "check", "-quiet=false"}, "quiet"); set {
+		t.Error("expected -quiet=false to leave the flag unset")
+	}
+}
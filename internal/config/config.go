@@ -2,8 +2,16 @@ package config
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,7 +21,54 @@ type Config struct {
 	LLM         LLMConfig   `yaml:"llm"`
 	VectorStore VectorStore `yaml:"vector_store"`
 	Analysis    Analysis    `yaml:"analysis"`
+	Cache       Cache       `yaml:"cache"`
 	IndexFile   string      `yaml:"index_file"` // Optional, defaults to .archguard/index.json
+	Overrides   []Override  `yaml:"overrides"`  // Path-scoped settings for monorepos; see ForPath
+}
+
+// Override applies a subset of settings (see Overridable) to files matching
+// Path, e.g. a larger llm.max_tokens for services/legacy/**.
+type Override struct {
+	Path string `yaml:"path"`
+
+	// resolved is the whole config with this block's settings merged on top.
+	resolved *Config
+}
+
+// Overridable lists the settings an overrides block may change, by section.
+// Settings baked into providers or the index at startup, such as models and
+// endpoints, apply to the whole run and cannot vary per path.
+var Overridable = map[string][]string{
+	"llm":          {"max_tokens", "system_prompt"},
+	"vector_store": {"similarity_threshold"},
+	"analysis":     {"exclude_patterns", "context_strategy", "strictness", "on_truncation"},
+}
+
+// ForPath returns the config that applies to a repository-relative file path:
+// the last overrides block whose path glob matches, or c itself.
+func (c *Config) ForPath(path string) *Config {
+	for i := len(c.Overrides) - 1; i >= 0; i-- {
+		o := c.Overrides[i]
+		if o.resolved == nil {
+			continue
+		}
+		if ok, _ := doublestar.Match(filepath.ToSlash(o.Path), filepath.ToSlash(path)); ok {
+			return o.resolved
+		}
+	}
+	return c
+}
+
+// Resolved returns every config a file may be analyzed with: c and one per
+// overrides block. Callers use it to validate settings up front.
+func (c *Config) Resolved() []*Config {
+	all := []*Config{c}
+	for _, o := range c.Overrides {
+		if o.resolved != nil {
+			all = append(all, o.resolved)
+		}
+	}
+	return all
 }
 
 type LLMConfig struct {
@@ -23,15 +78,101 @@ type LLMConfig struct {
 	MaxTokens    int     `yaml:"max_tokens"`
 	Temperature  float64 `yaml:"temperature"`
 	SystemPrompt string  `yaml:"system_prompt"`
+	RetryBaseMs  int     `yaml:"retry_base_ms"` // Initial retry backoff, defaults to 2000
+	RetryMaxMs   int     `yaml:"retry_max_ms"`  // Upper bound for a single backoff, defaults to 30000
+	MaxRetries   *int    `yaml:"max_retries"`   // Retries per chat request, defaults to 3; 0 disables retries
+
+	Stream              bool `yaml:"stream"`                 // Stream chat responses (openai, ollama)
+	StreamIdleTimeoutMs int  `yaml:"stream_idle_timeout_ms"` // Abandon a stream after this long without a chunk, defaults to 60000
+
+	OnParseFailure string `yaml:"on_parse_failure"` // retry | skip | fail; handling of empty or non-JSON chat responses
+
+	InputCostPerMTok  float64 `yaml:"input_cost_per_mtok"`  // USD per million prompt tokens, for analysis.max_cost_usd
+	OutputCostPerMTok float64 `yaml:"output_cost_per_mtok"` // USD per million response tokens, for analysis.max_cost_usd
+
+	APIKey     string `yaml:"api_key"`      // Literal key, or a "file:PATH" or "env:NAME" reference; see ResolveAPIKey
+	APIKeyFile string `yaml:"api_key_file"` // File holding the key, e.g. a mounted Kubernetes secret
+	APIKeyEnv  string `yaml:"api_key_env"`  // Environment variable holding the key, instead of ARCHGUARD_API_KEY
+
+	Headers map[string]string `yaml:"headers"` // Extra HTTP headers sent with every provider request, e.g. for an LLM gateway
+	HTTP    HTTPConfig        `yaml:"http"`    // Connection pool shared by the built-in providers
+
+	TiktokenCacheDir string `yaml:"tiktoken_cache_dir"` // Pre-downloaded tokenizer vocabularies for air-gapped environments
+}
+
+// HTTPConfig tunes the HTTP transport shared by the built-in providers.
+type HTTPConfig struct {
+	MaxIdleConnsPerHost int  `yaml:"max_idle_conns_per_host"` // Idle connections kept per provider host, defaults to 32
+	IdleConnTimeoutMs   int  `yaml:"idle_conn_timeout_ms"`    // How long an idle connection is kept open, defaults to 90000
+	DisableHTTP2        bool `yaml:"disable_http2"`           // Use HTTP/1.1 only, e.g. behind a proxy that mishandles HTTP/2
 }
 
 type VectorStore struct {
-	Provider             string  `yaml:"provider"`
+	Provider             string  `yaml:"provider"` // Embedding provider; defaults to llm.provider
 	Model                string  `yaml:"model"`
+	BaseURL              string  `yaml:"base_url"` // Used when the embedding provider differs from llm.provider
 	EmbeddingDim         int     `yaml:"embedding_dim"`
 	SimilarityThreshold  float64 `yaml:"similarity_threshold"`
+	Metric               string  `yaml:"metric"`  // cosine | dot | euclidean; defaults to cosine. Sets how similarity_threshold is read
+	Backend              string  `yaml:"backend"` // local | pgvector | a name registered through pkg/index; defaults by connection_string
 	ConnectionString     string  `yaml:"connection_string"`
 	EmbeddingConcurrency int     `yaml:"embedding_concurrency"`
+	MultiVector          bool    `yaml:"multi_vector"`      // Embed each ADR section separately; Search uses the best match
+	EmbedFileHeader      bool    `yaml:"embed_file_header"` // Prefix file embeddings with "Path: ...\nLanguage: ...\n"
+
+	// Credentials for a vector_store.provider that differs from llm.provider;
+	// each falls back to its llm counterpart when unset.
+	APIKey     string            `yaml:"api_key"`      // Literal key, or a "file:PATH" or "env:NAME" reference
+	APIKeyFile string            `yaml:"api_key_file"` // File holding the embedding provider's key
+	APIKeyEnv  string            `yaml:"api_key_env"`  // Environment variable holding the embedding provider's key
+	Headers    map[string]string `yaml:"headers"`      // Extra HTTP headers for embedding requests, instead of llm.headers
+}
+
+// knownEmbeddingDims maps common embedding models to their default output
+// dimension. Some models accept a smaller dimension, so a mismatch is only a
+// warning.
+var knownEmbeddingDims = map[string]int{
+	"nomic-embed-text":       768,
+	"mxbai-embed-large":      1024,
+	"all-minilm":             384,
+	"bge-m3":                 1024,
+	"bge-large":              1024,
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+	"text-embedding-004":     768,
+	"embedding-001":          768,
+	"gemini-embedding-001":   3072,
+}
+
+// KnownEmbeddingDim returns the default output dimension of model, ignoring
+// an Ollama tag ("nomic-embed-text:v1.5") and a path prefix
+// ("models/text-embedding-004").
+func KnownEmbeddingDim(model string) (int, bool) {
+	name := model[strings.LastIndex(model, "/")+1:]
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	dim, ok := knownEmbeddingDims[strings.ToLower(name)]
+	return dim, ok
+}
+
+// CheckEmbeddingDim reports an embedding_dim that contradicts the known
+// dimension of the configured model. An unset dimension or unknown model
+// passes.
+func (v *VectorStore) CheckEmbeddingDim() error {
+	if v.EmbeddingDim == 0 {
+		return nil
+	}
+	known, ok := KnownEmbeddingDim(v.Model)
+	if !ok || known == v.EmbeddingDim {
+		return nil
+	}
+	return fmt.Errorf("vector_store.embedding_dim is %d, but %s produces %d-dimensional embeddings by default", v.EmbeddingDim, v.Model, known)
+}
+
+type Cache struct {
+	Dir string `yaml:"dir"` // Optional, defaults to .archguard/cache; may be an absolute path on shared storage
 }
 
 type Confluence struct {
@@ -43,17 +184,186 @@ type Confluence struct {
 }
 
 type Analysis struct {
-	ADRPath          string     `yaml:"adr_path"`
-	AcceptedStatuses []string   `yaml:"accepted_statuses"`
-	ExcludePatterns  []string   `yaml:"exclude_patterns"`
-	MaxConcurrency   int        `yaml:"max_concurrency"`
-	Confluence       Confluence `yaml:"confluence"`
+	ADRPath           string     `yaml:"adr_path"`
+	AcceptedStatuses  []string   `yaml:"accepted_statuses"`
+	ExcludePatterns   []string   `yaml:"exclude_patterns"`
+	MaxConcurrency    int        `yaml:"max_concurrency"`
+	ConcurrencyRampMs int        `yaml:"concurrency_ramp_ms"`      // Spread the start of the first max_concurrency files over this long; 0 starts them at once
+	DefaultMode       string     `yaml:"default_mode"`             // uncommitted | staged | working | all; used when no mode flag is given
+	MaxFiles          int        `yaml:"max_files"`                // Confirmation required above this many files; 0 disables the check
+	SortOutput        bool       `yaml:"sort_output"`              // Print per-file results sorted by path instead of completion order
+	ContextStrategy   string     `yaml:"context_strategy"`         // auto | diff | full | diff-then-full; see analysis.Context* constants
+	Strictness        string     `yaml:"strictness"`               // lenient | balanced | strict; selects a built-in system prompt unless llm.system_prompt is set
+	DiffContextLines  int        `yaml:"diff_context_lines"`       // Unified diff context sent with changes; 0 uses 100
+	OnTruncation      string     `yaml:"on_truncation"`            // analyze | warn | error | chunk; what to do when a file exceeds llm.max_tokens
+	FollowSymlinks    bool       `yaml:"follow_external_symlinks"` // Read symlinks that resolve outside the repository
+	PolicyMode        bool       `yaml:"policy_mode"`              // Treat adr_path as policy documents whose "## " sections are individual rules
+	MaxTokensBudget   int        `yaml:"max_tokens_budget"`        // Stop the run once estimated LLM chat tokens would exceed this; 0 disables
+	MaxCostUSD        float64    `yaml:"max_cost_usd"`             // Stop the run once estimated LLM chat cost would exceed this; 0 disables
+	StripComments     bool       `yaml:"strip_comments"`           // Drop whole-line comments from the text embedded for retrieval
+	FocusDiffTokens   int        `yaml:"focus_diff_tokens"`        // Diffs above this many tokens send each ADR only its most similar hunks; 0 disables
+	RedactPatterns    []string   `yaml:"redact_patterns"`          // Regexes replaced with [REDACTED] before embedding and analysis; "default" adds common secret shapes
+	Confluence        Confluence `yaml:"confluence"`
 }
 
-func LoadConfig(path string) (*Config, error) {
+// DefaultAPIKeyEnv is the environment variable read for the provider API key
+// when no llm.api_key* setting is configured.
+const DefaultAPIKeyEnv = "ARCHGUARD_API_KEY"
+
+// StreamIdleTimeout returns how long a streamed chat response may go without
+// a chunk: llm.stream_idle_timeout_ms, or 60 seconds when unset.
+func (l *LLMConfig) StreamIdleTimeout() time.Duration {
+	if l.StreamIdleTimeoutMs > 0 {
+		return time.Duration(l.StreamIdleTimeoutMs) * time.Millisecond
+	}
+	return 60 * time.Second
+}
+
+// ResolveAPIKey returns the provider API key and a description of where it was
+// looked up, for warnings. Sources are tried in order: llm.api_key,
+// llm.api_key_file, llm.api_key_env, then ARCHGUARD_API_KEY. The first one
+// configured is used even if it yields an empty key, so a broken secret mount
+// is not silently replaced by a stale environment variable. Keys read from
+// files are trimmed of surrounding whitespace.
+func (l *LLMConfig) ResolveAPIKey() (key, source string, err error) {
+	if key, source, ok, err := resolveAPIKey("llm", l.APIKey, l.APIKeyFile, l.APIKeyEnv); ok {
+		return key, source, err
+	}
+	return os.Getenv(DefaultAPIKeyEnv), DefaultAPIKeyEnv, nil
+}
+
+// ResolveAPIKey returns the API key for a vector_store.provider that differs
+// from llm.provider, in the same order as LLMConfig.ResolveAPIKey:
+// vector_store.api_key, api_key_file, then api_key_env. When none of them is
+// set the chat provider's key is used.
+func (v *VectorStore) ResolveAPIKey(chat *LLMConfig) (key, source string, err error) {
+	if key, source, ok, err := resolveAPIKey("vector_store", v.APIKey, v.APIKeyFile, v.APIKeyEnv); ok {
+		return key, source, err
+	}
+	return chat.ResolveAPIKey()
+}
+
+// EmbeddingHeaders returns the extra headers for a vector_store.provider that
+// differs from llm.provider: vector_store.headers, or llm.headers when unset.
+func (c *Config) EmbeddingHeaders() map[string]string {
+	if c.VectorStore.Headers != nil {
+		return c.VectorStore.Headers
+	}
+	return c.LLM.Headers
+}
+
+// resolveAPIKey reads the first configured of a section's api_key,
+// api_key_file, and api_key_env settings. ok is false when none is set.
+func resolveAPIKey(section, apiKey, apiKeyFile, apiKeyEnv string) (key, source string, ok bool, err error) {
+	switch {
+	case strings.HasPrefix(apiKey, "file:"):
+		key, source, err = readAPIKeyFile(strings.TrimPrefix(apiKey, "file:"), section+".api_key")
+	case strings.HasPrefix(apiKey, "env:"):
+		name := strings.TrimPrefix(apiKey, "env:")
+		key, source = os.Getenv(name), name
+	case apiKey != "":
+		key, source = apiKey, section+".api_key"
+	case apiKeyFile != "":
+		key, source, err = readAPIKeyFile(apiKeyFile, section+".api_key_file")
+	case apiKeyEnv != "":
+		key, source = os.Getenv(apiKeyEnv), apiKeyEnv
+	default:
+		return "", "", false, nil
+	}
+	return key, source, true, err
+}
+
+func readAPIKeyFile(path, setting string) (string, string, error) {
+	source := fmt.Sprintf("%s (%s)", setting, path)
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return "", source, fmt.Errorf("failed to read API key from %s: %w", setting, err)
+	}
+	return strings.TrimSpace(string(data)), source, nil
+}
+
+// HomeEnv names the environment variable that moves ArchGuard's writable state
+// (cache, index, audit progress, last run) out of the repository, e.g. to a
+// scratch volume when CI checks out the repository read-only.
+const HomeEnv = "ARCHGUARD_HOME"
+
+// StatePath joins elem onto the state directory: $ARCHGUARD_HOME when set,
+// otherwise .archguard in the repository root. Config fragments are not state
+// and stay in the repository.
+func StatePath(elem ...string) string {
+	dir := os.Getenv(HomeEnv)
+	if dir == "" {
+		dir = ".archguard"
+	}
+	return filepath.Join(append([]string{dir}, elem...)...)
+}
+
+// FragmentDir is the directory, relative to archguard.yaml, whose *.yaml
+// files are merged on top of it in lexical order.
+const FragmentDir = ".archguard/config.d"
+
+func LoadConfig(path string) (*Config, error) {
+	raw, err := loadRaw(path, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if !isURL(path) {
+		if raw, err = mergeFragments(raw, filepath.Join(filepath.Dir(path), FragmentDir)); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := decodeRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, _ := raw["overrides"].([]interface{})
+	delete(raw, "overrides")
+	for i, block := range blocks {
+		settings, ok := block.(map[string]interface{})
+		if !ok || cfg.Overrides[i].Path == "" {
+			return nil, fmt.Errorf("overrides[%d]: each override needs a path glob and settings", i)
+		}
+		settings = copyRaw(settings)
+		delete(settings, "path")
+		if err := checkOverridable(settings); err != nil {
+			return nil, fmt.Errorf("overrides[%d] (%s): %w", i, cfg.Overrides[i].Path, err)
+		}
+		resolved, err := decodeRaw(mergeRaw(copyRaw(raw), settings))
+		if err != nil {
+			return nil, fmt.Errorf("overrides[%d] (%s): %w", i, cfg.Overrides[i].Path, err)
+		}
+		cfg.Overrides[i].resolved = resolved
+	}
+
+	return cfg, nil
+}
+
+// mergeFragments merges each *.yaml file in dir on top of raw, in lexical
+// order, so later fragments win. Fragments may use extends. A missing dir
+// leaves raw unchanged.
+func mergeFragments(raw map[string]interface{}, dir string) (map[string]interface{}, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fragment, err := loadRaw(path, map[string]bool{})
+		if err != nil {
+			return nil, fmt.Errorf("config fragment %s: %w", path, err)
+		}
+		raw = mergeRaw(raw, fragment)
+	}
+	return raw, nil
+}
+
+// decodeRaw converts a merged raw config into a Config and applies defaults.
+func decodeRaw(raw map[string]interface{}) (*Config, error) {
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge config file: %w", err)
 	}
 
 	var cfg Config
@@ -71,3 +381,114 @@ func LoadConfig(path string) (*Config, error) {
 
 	return &cfg, nil
 }
+
+// checkOverridable rejects override settings outside Overridable.
+func checkOverridable(settings map[string]interface{}) error {
+	for section, v := range settings {
+		keys, ok := v.(map[string]interface{})
+		if !ok || Overridable[section] == nil {
+			return fmt.Errorf("%s cannot be set per path", section)
+		}
+		for key := range keys {
+			if !slices.Contains(Overridable[section], key) {
+				return fmt.Errorf("%s.%s cannot be set per path", section, key)
+			}
+		}
+	}
+	return nil
+}
+
+// copyRaw deep-copies the maps in a raw config, since mergeRaw modifies its
+// base in place.
+func copyRaw(raw map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		if m, ok := v.(map[string]interface{}); ok {
+			v = copyRaw(m)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// loadRaw reads the config at location and, if it sets extends, merges it on
+// top of its base. Maps merge key by key with local values winning; lists and
+// scalars in the local file replace the base value outright, and empty (null)
+// local values leave the base untouched. seen guards against extends cycles.
+func loadRaw(location string, seen map[string]bool) (map[string]interface{}, error) {
+	if seen[location] {
+		return nil, fmt.Errorf("config extends cycle at %s", location)
+	}
+	seen[location] = true
+
+	data, err := readLocation(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", location, err)
+	}
+
+	parent, _ := raw["extends"].(string)
+	delete(raw, "extends")
+	if parent == "" {
+		return raw, nil
+	}
+
+	if !isURL(parent) && !filepath.IsAbs(parent) {
+		if isURL(location) {
+			parent = location[:strings.LastIndex(location, "/")+1] + parent
+		} else {
+			parent = filepath.Join(filepath.Dir(location), parent)
+		}
+	}
+
+	base, err := loadRaw(parent, seen)
+	if err != nil {
+		return nil, err
+	}
+	return mergeRaw(base, raw), nil
+}
+
+// mergeRaw deep-merges override into base and returns base.
+func mergeRaw(base, override map[string]interface{}) map[string]interface{} {
+	for k, v := range override {
+		if v == nil {
+			if _, ok := base[k]; ok {
+				continue
+			}
+		}
+		if vm, ok := v.(map[string]interface{}); ok {
+			if bm, ok := base[k].(map[string]interface{}); ok {
+				base[k] = mergeRaw(bm, vm)
+				continue
+			}
+		}
+		base[k] = v
+	}
+	return base
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+func readLocation(location string) ([]byte, error) {
+	if !isURL(location) {
+		return os.ReadFile(location)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,345 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig_Extends(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.yaml"), `
+llm:
+  provider: openai
+  model: gpt-4o
+  max_tokens: 8000
+analysis:
+  exclude_patterns: ["vendor/**", "go.sum"]
+  max_concurrency: 5
+`)
+	writeFile(t, filepath.Join(dir, "archguard.yaml"), `
+extends: base.yaml
+llm:
+  model: gpt-4o-mini
+analysis:
+  exclude_patterns: ["docs/**"]
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "archguard.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.LLM.Provider != "openai" || cfg.LLM.MaxTokens != 8000 {
+		t.Errorf("expected base llm settings to be inherited, got %+v", cfg.LLM)
+	}
+	if cfg.LLM.Model != "gpt-4o-mini" {
+		t.Errorf("expected local model to win, got %q", cfg.LLM.Model)
+	}
+	if cfg.Analysis.MaxConcurrency != 5 {
+		t.Errorf("expected inherited max_concurrency 5, got %d", cfg.Analysis.MaxConcurrency)
+	}
+	if want := []string{"docs/**"}; !reflect.DeepEqual(cfg.Analysis.ExcludePatterns, want) {
+		t.Errorf("expected local list to replace base list, got %v", cfg.Analysis.ExcludePatterns)
+	}
+}
+
+func TestLoadConfig_Fragments(t *testing.T) {
+	dir := t.TempDir()
+	fragments := filepath.Join(dir, FragmentDir)
+	if err := os.MkdirAll(fragments, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "archguard.yaml"), `
+llm:
+  provider: ollama
+  model: llama3
+analysis:
+  exclude_patterns: ["docs/**"]
+  max_concurrency: 5
+`)
+	writeFile(t, filepath.Join(fragments, "20-provider.yaml"), "llm:\n  model: gpt-4o\n")
+	writeFile(t, filepath.Join(fragments, "10-excludes.yaml"), "analysis:\n  exclude_patterns: [\"vendor/**\"]\nllm:\n  model: ignored\n")
+	writeFile(t, filepath.Join(fragments, "README.md"), "not a fragment")
+
+	cfg, err := LoadConfig(filepath.Join(dir, "archguard.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.LLM.Provider != "ollama" || cfg.Analysis.MaxConcurrency != 5 {
+		t.Errorf("expected settings absent from fragments to be kept, got %+v", cfg)
+	}
+	if cfg.LLM.Model != "gpt-4o" {
+		t.Errorf("expected the lexically last fragment to win, got %q", cfg.LLM.Model)
+	}
+	if want := []string{"vendor/**"}; !reflect.DeepEqual(cfg.Analysis.ExcludePatterns, want) {
+		t.Errorf("expected fragment list to replace base list, got %v", cfg.Analysis.ExcludePatterns)
+	}
+
+	writeFile(t, filepath.Join(fragments, "30-broken.yaml"), "llm: [")
+	if _, err := LoadConfig(filepath.Join(dir, "archguard.yaml")); err == nil || !strings.Contains(err.Error(), "30-broken.yaml") {
+		t.Errorf("expected an error naming the broken fragment, got %v", err)
+	}
+}
+
+func TestLoadConfig_ExtendsURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("vector_store:\n  similarity_threshold: 0.7\n"))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "archguard.yaml")
+	writeFile(t, path, "extends: "+srv.URL+"/base.yaml\nproject_name: demo\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.VectorStore.SimilarityThreshold != 0.7 || cfg.ProjectName != "demo" {
+		t.Errorf("unexpected merged config: %+v", cfg)
+	}
+}
+
+func TestLoadConfig_ExtendsCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yaml"), "extends: b.yaml\n")
+	writeFile(t, filepath.Join(dir, "b.yaml"), "extends: a.yaml\n")
+
+	_, err := LoadConfig(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected extends cycle error, got %v", err)
+	}
+}
+
+func TestSchema_DocumentsEveryField(t *testing.T) {
+	var walk func(path string, node map[string]interface{})
+	walk = func(path string, node map[string]interface{}) {
+		if path != "" {
+			if _, ok := node["description"]; !ok {
+				t.Errorf("schema property %s has no description; add it to schemaDocs", path)
+			}
+		}
+		props, _ := node["properties"].(map[string]interface{})
+		for name, child := range props {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			walk(childPath, child.(map[string]interface{}))
+		}
+	}
+	walk("", Schema())
+}
+
+func TestSchema_ProviderSuggestsBuiltIns(t *testing.T) {
+	llm := Schema()["properties"].(map[string]interface{})["llm"].(map[string]interface{})
+	provider := llm["properties"].(map[string]interface{})["provider"].(map[string]interface{})
+	if !reflect.DeepEqual(provider["examples"], []string{"openai", "ollama", "gemini"}) {
+		t.Errorf("unexpected llm.provider examples: %v", provider["examples"])
+	}
+	// Registered providers must validate too.
+	if _, ok := provider["enum"]; ok {
+		t.Errorf("llm.provider should not be restricted to an enum: %v", provider["enum"])
+	}
+}
+
+func TestLoadConfig_PathOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "archguard.yaml")
+	writeFile(t, path, `llm:
+  provider: openai
+  max_tokens: 8000
+analysis:
+  exclude_patterns: ["vendor/**"]
+  strictness: balanced
+overrides:
+  - path: "services/legacy/**"
+    llm:
+      max_tokens: 32000
+    analysis:
+      exclude_patterns: ["services/legacy/generated/**"]
+  - path: "services/legacy/core/**"
+    analysis:
+      strictness: strict
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if got := cfg.ForPath("cmd/main.go"); got != cfg {
+		t.Error("expected files outside every override to use the root config")
+	}
+	legacy := cfg.ForPath("services/legacy/api.go")
+	if legacy.LLM.MaxTokens != 32000 || legacy.LLM.Provider != "openai" || legacy.Analysis.Strictness != "balanced" {
+		t.Errorf("expected override merged on top of the root config, got %+v", legacy.LLM)
+	}
+	if !reflect.DeepEqual(legacy.Analysis.ExcludePatterns, []string{"services/legacy/generated/**"}) {
+		t.Errorf("expected override list to replace the root list, got %v", legacy.Analysis.ExcludePatterns)
+	}
+	if core := cfg.ForPath("services/legacy/core/db.go"); core.Analysis.Strictness != "strict" || core.LLM.MaxTokens != 8000 {
+		t.Errorf("expected the last matching block to apply, got strictness %q, max_tokens %d", core.Analysis.Strictness, core.LLM.MaxTokens)
+	}
+	if n := len(cfg.Resolved()); n != 3 {
+		t.Errorf("expected root plus 2 resolved overrides, got %d", n)
+	}
+}
+
+func TestLoadConfig_OverrideRejectsRunWideSettings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "archguard.yaml")
+	writeFile(t, path, "overrides:\n  - path: \"svc/**\"\n    llm:\n      model: gpt-4o\n")
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "llm.model cannot be set per path") {
+		t.Fatalf("expected per-path model override to be rejected, got %v", err)
+	}
+}
+
+func TestResolveAPIKey_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "openai")
+	writeFile(t, keyFile, "  sk-from-file\n")
+	t.Setenv(DefaultAPIKeyEnv, "sk-default")
+	t.Setenv("OPENAI_API_KEY", "sk-custom")
+
+	tests := []struct {
+		name string
+		llm  LLMConfig
+		want string
+	}{
+		{"default env", LLMConfig{}, "sk-default"},
+		{"api_key_env", LLMConfig{APIKeyEnv: "OPENAI_API_KEY"}, "sk-custom"},
+		{"api_key_file over api_key_env", LLMConfig{APIKeyFile: keyFile, APIKeyEnv: "OPENAI_API_KEY"}, "sk-from-file"},
+		{"literal api_key over api_key_file", LLMConfig{APIKey: "sk-literal", APIKeyFile: keyFile}, "sk-literal"},
+		{"file reference", LLMConfig{APIKey: "file:" + keyFile}, "sk-from-file"},
+		{"env reference", LLMConfig{APIKey: "env:OPENAI_API_KEY"}, "sk-custom"},
+		{"configured source wins when empty", LLMConfig{APIKeyEnv: "ARCHGUARD_TEST_UNSET_KEY"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := tt.llm.ResolveAPIKey()
+			if err != nil {
+				t.Fatalf("ResolveAPIKey failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	missing := LLMConfig{APIKeyFile: filepath.Join(dir, "missing")}
+	if _, _, err := missing.ResolveAPIKey(); err == nil || !strings.Contains(err.Error(), "llm.api_key_file") {
+		t.Errorf("expected an error naming llm.api_key_file, got %v", err)
+	}
+}
+
+func TestVectorStoreResolveAPIKey(t *testing.T) {
+	t.Setenv(DefaultAPIKeyEnv, "sk-chat")
+	t.Setenv("EMBED_API_KEY", "sk-embed")
+	chat := &LLMConfig{}
+
+	if got, _, _ := (&VectorStore{}).ResolveAPIKey(chat); got != "sk-chat" {
+		t.Errorf("expected the llm key without vector_store key settings, got %q", got)
+	}
+	if got, source, _ := (&VectorStore{APIKeyEnv: "EMBED_API_KEY"}).ResolveAPIKey(chat); got != "sk-embed" || source != "EMBED_API_KEY" {
+		t.Errorf("expected vector_store.api_key_env to be used, got %q from %s", got, source)
+	}
+	missing := &VectorStore{APIKeyFile: filepath.Join(t.TempDir(), "missing")}
+	if _, _, err := missing.ResolveAPIKey(chat); err == nil || !strings.Contains(err.Error(), "vector_store.api_key_file") {
+		t.Errorf("expected an error naming vector_store.api_key_file, got %v", err)
+	}
+
+	cfg := &Config{LLM: LLMConfig{Headers: map[string]string{"X-Tenant-ID": "team-a"}}}
+	if got := cfg.EmbeddingHeaders(); got["X-Tenant-ID"] != "team-a" {
+		t.Errorf("expected llm.headers without vector_store.headers, got %v", got)
+	}
+	cfg.VectorStore.Headers = map[string]string{"X-Embed": "1"}
+	if got := cfg.EmbeddingHeaders(); len(got) != 1 || got["X-Embed"] != "1" {
+		t.Errorf("expected vector_store.headers to replace llm.headers, got %v", got)
+	}
+}
+
+func TestCheckEmbeddingDim(t *testing.T) {
+	tests := []struct {
+		model   string
+		dim     int
+		wantErr bool
+	}{
+		{"nomic-embed-text", 768, false},
+		{"nomic-embed-text:v1.5", 1536, true},
+		{"text-embedding-3-small", 768, true},
+		{"models/text-embedding-004", 768, false},
+		{"text-embedding-3-large", 0, false},
+		{"my-custom-embedder", 512, false},
+	}
+	for _, tt := range tests {
+		v := VectorStore{Model: tt.model, EmbeddingDim: tt.dim}
+		err := v.CheckEmbeddingDim()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s with dim %d: expected error %v, got %v", tt.model, tt.dim, tt.wantErr, err)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		LLM: LLMConfig{
+			APIKey:  "sk-secret",
+			Headers: map[string]string{"Authorization": "Bearer secret", "X-Tenant-ID": "team-a"},
+		},
+		VectorStore: VectorStore{
+			ConnectionString: "postgres://app:hunter2@db:5432/archguard",
+			APIKey:           "sk-embed-secret",
+			Headers:          map[string]string{"X-Api-Key": "secret"},
+		},
+		Analysis: Analysis{Confluence: Confluence{Token: "secret-token"}},
+	}
+
+	out := cfg.Redacted()
+	if out.LLM.APIKey != redactedValue || out.Analysis.Confluence.Token != redactedValue {
+		t.Errorf("expected api_key and confluence token redacted, got %q and %q", out.LLM.APIKey, out.Analysis.Confluence.Token)
+	}
+	if out.LLM.Headers["Authorization"] != redactedValue || out.LLM.Headers["X-Tenant-ID"] != "team-a" {
+		t.Errorf("expected only the Authorization header redacted, got %v", out.LLM.Headers)
+	}
+	if out.VectorStore.APIKey != redactedValue || out.VectorStore.Headers["X-Api-Key"] != redactedValue {
+		t.Errorf("expected the vector_store key and headers redacted, got %q and %v", out.VectorStore.APIKey, out.VectorStore.Headers)
+	}
+	if strings.Contains(out.VectorStore.ConnectionString, "hunter2") || !strings.Contains(out.VectorStore.ConnectionString, "app:") {
+		t.Errorf("expected the password redacted from the connection string, got %q", out.VectorStore.ConnectionString)
+	}
+	if cfg.LLM.APIKey != "sk-secret" || cfg.LLM.Headers["Authorization"] != "Bearer secret" {
+		t.Error("Redacted modified the original config")
+	}
+
+	ref := &Config{LLM: LLMConfig{APIKey: "env:OPENAI_API_KEY"}}
+	if got := ref.Redacted().LLM.APIKey; got != "env:OPENAI_API_KEY" {
+		t.Errorf("expected key references kept, got %q", got)
+	}
+}
+
+func TestStatePath(t *testing.T) {
+	t.Setenv(HomeEnv, "")
+	if got, want := StatePath("index.json"), filepath.Join(".archguard", "index.json"); got != want {
+		t.Errorf("expected %q by default, got %q", want, got)
+	}
+
+	home := t.TempDir()
+	t.Setenv(HomeEnv, home)
+	if got, want := StatePath("cache"), filepath.Join(home, "cache"); got != want {
+		t.Errorf("expected %q under ARCHGUARD_HOME, got %q", want, got)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in Redacted output.
+const redactedValue = "REDACTED"
+
+// Redacted returns a copy of c that is safe to print: API keys, tokens,
+// credential-bearing headers, and database passwords are replaced. Key
+// references ("env:NAME", "file:PATH") name where a secret lives rather than
+// the secret itself, so they are kept.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.LLM.APIKey = redactAPIKey(c.LLM.APIKey)
+	out.LLM.Headers = redactHeaders(c.LLM.Headers)
+	out.VectorStore.APIKey = redactAPIKey(c.VectorStore.APIKey)
+	out.VectorStore.Headers = redactHeaders(c.VectorStore.Headers)
+	out.VectorStore.ConnectionString = redactConnectionString(c.VectorStore.ConnectionString)
+	if out.Analysis.Confluence.Token != "" {
+		out.Analysis.Confluence.Token = redactedValue
+	}
+	return &out
+}
+
+// redactAPIKey hides a literal API key; file: and env: references are kept.
+func redactAPIKey(key string) string {
+	if key != "" && !strings.HasPrefix(key, "env:") && !strings.HasPrefix(key, "file:") {
+		return redactedValue
+	}
+	return key
+}
+
+// redactHeaders returns a copy of headers with credential-like values hidden.
+func redactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	out := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if sensitiveHeader(name) {
+			value = redactedValue
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// sensitiveHeader reports whether a header name suggests it carries a
+// credential, e.g. Authorization or X-Api-Key.
+func sensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"auth", "key", "token", "secret", "cookie", "password"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactConnectionString hides the password in a postgres:// URL. Key/value
+// DSNs are redacted whole when they contain a password.
+func redactConnectionString(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+		return u.String()
+	}
+	if strings.Contains(strings.ToLower(s), "password=") {
+		return redactedValue
+	}
+	return s
+}
+
+// MarshalYAML prints an overrides block with the effective value of each
+// setting it may change, since the block's own settings are not kept after
+// loading.
+func (o Override) MarshalYAML() (interface{}, error) {
+	block := struct {
+		Path     string                 `yaml:"path"`
+		Settings map[string]interface{} `yaml:",inline"`
+	}{Path: o.Path, Settings: map[string]interface{}{}}
+	if o.resolved == nil {
+		return block, nil
+	}
+
+	data, err := yaml.Marshal(o.resolved)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for section, keys := range Overridable {
+		values, _ := raw[section].(map[string]interface{})
+		picked := map[string]interface{}{}
+		for _, key := range keys {
+			if v, ok := values[key]; ok {
+				picked[key] = v
+			}
+		}
+		if len(picked) > 0 {
+			block.Settings[section] = picked
+		}
+	}
+	return block, nil
+}
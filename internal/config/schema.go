@@ -0,0 +1,188 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// fieldDoc describes a config key for the JSON Schema. Keys are dotted yaml
+// paths, e.g. "llm.provider".
+type fieldDoc struct {
+	Description string
+	Enum        []string
+	Examples    []string // Suggested values that, unlike Enum, do not restrict the field
+}
+
+// providers are the built-in provider names. Providers registered through
+// pkg/llm are valid too, so they are suggested rather than enforced.
+var providers = []string{"openai", "ollama", "gemini"}
+
+var schemaDocs = map[string]fieldDoc{
+	"version":      {Description: "Config file format version."},
+	"project_name": {Description: "Project name used to namespace shared indexes; defaults to the repository directory name."},
+	"index_file":   {Description: "Path of the local ADR index. Defaults to .archguard/index.json, or index.json in $ARCHGUARD_HOME."},
+	"overrides":    {Description: "Path-scoped settings for monorepos; the last block whose path matches a file applies to it."},
+
+	"overrides[].path": {Description: "Glob of repository-relative files the block applies to, e.g. services/payments/**."},
+
+	"llm":                              {Description: "Chat model used to judge code against ADRs."},
+	"llm.provider":                     {Description: "Chat provider: openai, ollama, gemini, or a name registered through pkg/llm.", Examples: providers},
+	"llm.model":                        {Description: "Chat model name, e.g. gpt-4o or llama3."},
+	"llm.base_url":                     {Description: "Provider endpoint override, e.g. a remote Ollama host."},
+	"llm.max_tokens":                   {Description: "Token budget for code sent per request. Defaults to 8000."},
+	"llm.temperature":                  {Description: "Sampling temperature for chat requests."},
+	"llm.system_prompt":                {Description: "Custom system prompt; overrides analysis.strictness."},
+	"llm.retry_base_ms":                {Description: "Initial retry backoff in milliseconds. Defaults to 2000."},
+	"llm.retry_max_ms":                 {Description: "Upper bound for a single retry backoff in milliseconds, including a server Retry-After hint. Defaults to 30000."},
+	"llm.on_parse_failure":             {Description: "What to do when a chat response is empty or not valid JSON: retry then fail the check, skip the check with a warning, or fail it at once.", Enum: []string{"retry", "skip", "fail"}},
+	"llm.api_key":                      {Description: "Provider API key, or a reference: file:/run/secrets/openai or env:OPENAI_API_KEY. Takes precedence over api_key_file and api_key_env."},
+	"llm.api_key_file":                 {Description: "File containing the provider API key, trimmed of whitespace. Takes precedence over api_key_env."},
+	"llm.api_key_env":                  {Description: "Environment variable holding the provider API key. Defaults to ARCHGUARD_API_KEY."},
+	"llm.headers":                      {Description: "Extra HTTP headers sent with every chat and embedding request, e.g. X-Tenant-ID for an LLM gateway."},
+	"llm.http":                         {Description: "HTTP connection pool shared by the built-in providers' chat and embedding requests; connections are kept alive and reused across a scan."},
+	"llm.http.max_idle_conns_per_host": {Description: "Idle connections kept open per provider host. Defaults to 32; raise it above analysis.max_concurrency for very parallel scans."},
+	"llm.http.idle_conn_timeout_ms":    {Description: "How long an idle connection is kept open before it is closed. Defaults to 90000."},
+	"llm.http.disable_http2":           {Description: "Use HTTP/1.1 only instead of negotiating HTTP/2 over TLS, e.g. behind a proxy that mishandles HTTP/2."},
+	"llm.max_retries":                  {Description: "Retries after a failed chat request before the check fails; 0 fails at once. Defaults to 3. Unparseable responses are retried only under on_parse_failure: retry."},
+	"llm.stream":                       {Description: "Stream chat responses (openai and ollama). A request is abandoned and retried only when no chunk arrives for stream_idle_timeout_ms, so long analyses that are still producing tokens are not cut off."},
+	"llm.stream_idle_timeout_ms":       {Description: "With stream: true, how long a response may go without a chunk before it is retried. Defaults to 60000."},
+	"llm.input_cost_per_mtok":          {Description: "Price in USD per million prompt tokens, used to estimate spend for analysis.max_cost_usd."},
+	"llm.output_cost_per_mtok":         {Description: "Price in USD per million response tokens, used to estimate spend for analysis.max_cost_usd."},
+	"llm.tiktoken_cache_dir":           {Description: "Directory of pre-downloaded tokenizer vocabularies (TIKTOKEN_CACHE_DIR) for air-gapped environments."},
+
+	"vector_store":                       {Description: "Embedding model and ADR index storage."},
+	"vector_store.provider":              {Description: "Embedding provider; defaults to llm.provider. Accepts the same names as llm.provider.", Examples: providers},
+	"vector_store.model":                 {Description: "Embedding model name, e.g. text-embedding-3-small."},
+	"vector_store.base_url":              {Description: "Embedding endpoint, used when vector_store.provider differs from llm.provider."},
+	"vector_store.embedding_dim":         {Description: "Expected embedding dimension; 0 infers it from the first embedding."},
+	"vector_store.similarity_threshold":  {Description: "Retrieval cutoff for an ADR to be checked against a file: the minimum score for cosine (-1 to 1) and dot, the maximum distance for euclidean, which must be positive."},
+	"vector_store.metric":                {Description: "How file and ADR embeddings are compared; use the metric the embedding model was trained for. Defaults to cosine.", Enum: []string{"cosine", "dot", "euclidean"}},
+	"vector_store.backend":               {Description: "Where the ADR index is stored and searched: the local JSON file, pgvector, or a backend registered through pkg/index. Defaults to pgvector when connection_string is set, and local otherwise.", Examples: []string{"local", "pgvector"}},
+	"vector_store.connection_string":     {Description: "pgvector connection string; when set, the index is stored in Postgres. Overridden by ARCHGUARD_DB_URL."},
+	"vector_store.embedding_concurrency": {Description: "Parallel embedding requests while indexing. Defaults to 5."},
+	"vector_store.multi_vector":          {Description: "Also embed each ## section of an ADR and match files against the best-scoring vector. Local index only."},
+	"vector_store.embed_file_header":     {Description: "Prefix the text embedded for each file with its path and language, so files are also matched by their location and role."},
+	"vector_store.api_key":               {Description: "API key for a vector_store.provider that differs from llm.provider, or a file:/env: reference as for llm.api_key. Without vector_store.api_key, api_key_file, and api_key_env the llm key is used."},
+	"vector_store.api_key_file":          {Description: "File containing the embedding provider's API key, trimmed of whitespace. Takes precedence over vector_store.api_key_env."},
+	"vector_store.api_key_env":           {Description: "Environment variable holding the embedding provider's API key."},
+	"vector_store.headers":               {Description: "Extra HTTP headers sent with embedding requests when vector_store.provider differs from llm.provider. Defaults to llm.headers."},
+
+	"analysis":                          {Description: "What to analyze and how."},
+	"analysis.adr_path":                 {Description: "Directory containing ADR markdown files. Files under it are always excluded from analysis."},
+	"analysis.accepted_statuses":        {Description: "ADR statuses that are enforced, e.g. Accepted."},
+	"analysis.exclude_patterns":         {Description: "Glob patterns of files never analyzed; merged with .archguardignore."},
+	"analysis.max_concurrency":          {Description: "Number of files analyzed in parallel. Defaults to 5."},
+	"analysis.concurrency_ramp_ms":      {Description: "Milliseconds over which parallelism grows from 1 to max_concurrency at the start of a run, so a cold local model server is not hit with every request at once. 0 starts at full parallelism."},
+	"analysis.default_mode":             {Description: "Files checked when no mode flag is given.", Enum: []string{"uncommitted", "staged", "working", "all"}},
+	"analysis.max_files":                {Description: "Ask for confirmation above this many files; 0 disables the check."},
+	"analysis.sort_output":              {Description: "Print results sorted by file path instead of completion order."},
+	"analysis.context_strategy":         {Description: "What code is sent to the LLM for changed files.", Enum: []string{"auto", "diff", "full", "diff-then-full", "functions"}},
+	"analysis.strictness":               {Description: "Built-in system prompt sensitivity.", Enum: []string{"lenient", "balanced", "strict"}},
+	"analysis.diff_context_lines":       {Description: "Lines of unchanged code around each change in diffs sent to the LLM. Defaults to 100."},
+	"analysis.follow_external_symlinks": {Description: "Analyze symlinked files whose target is outside the repository; by default they are skipped."},
+	"analysis.max_tokens_budget":        {Description: "Stop the run once the estimated prompt and response tokens of LLM analysis calls would exceed this many; 0 disables the budget."},
+	"analysis.max_cost_usd":             {Description: "Stop the run once the estimated cost in USD of LLM analysis calls would exceed this, priced with llm.input_cost_per_mtok and llm.output_cost_per_mtok; 0 disables the budget."},
+	"analysis.redact_patterns":          {Description: "Regular expressions whose matches in file content and diffs are replaced with [REDACTED] before embedding and analysis, so secrets are not sent to the provider. The entry \"default\" adds patterns for AWS keys, JWTs, private keys, and GitHub and Slack tokens."},
+	"analysis.focus_diff_tokens":        {Description: "When a diff is larger than this many tokens, rank its hunks by similarity to each matched ADR's title and Decision section and send only the most relevant ones, up to this many tokens. 0 sends the whole diff."},
+	"analysis.strip_comments":           {Description: "Remove comment-only lines, such as license headers, from the text embedded to find relevant ADRs. The LLM still sees the full code."},
+	"analysis.policy_mode":              {Description: "Read adr_path (a file or directory) as policy documents in which every \"## \" section is a separate rule."},
+	"analysis.on_truncation":            {Description: "What to do when a file exceeds llm.max_tokens: analyze the truncated content, warn, fail the run, or analyze it in chunks.", Enum: []string{"analyze", "warn", "error", "chunk"}},
+
+	"analysis.confluence":          {Description: "Read ADRs from a Confluence space instead of adr_path."},
+	"analysis.confluence.enabled":  {Description: "Enable the Confluence ADR source."},
+	"analysis.confluence.domain":   {Description: "Confluence domain, e.g. mycompany.atlassian.net."},
+	"analysis.confluence.space_id": {Description: "Confluence space holding the ADR pages."},
+	"analysis.confluence.username": {Description: "Confluence account email."},
+	"analysis.confluence.token":    {Description: "Confluence API token."},
+
+	"cache":     {Description: "Analysis result cache."},
+	"cache.dir": {Description: "Cache directory; defaults to .archguard/cache, or cache in $ARCHGUARD_HOME. May point at shared storage."},
+}
+
+// Schema returns a JSON Schema for archguard.yaml. Properties are derived from
+// the yaml tags on Config, so new fields appear automatically; descriptions and
+// enums come from schemaDocs.
+func Schema() map[string]interface{} {
+	schema := structSchema(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "ArchGuard configuration"
+	props := schema["properties"].(map[string]interface{})
+	props["extends"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Base config path (relative to this file) or http(s) URL; local values are merged on top.",
+	}
+
+	// Override blocks accept only the Overridable subset of each section.
+	item := props["overrides"].(map[string]interface{})["items"].(map[string]interface{})
+	itemProps := item["properties"].(map[string]interface{})
+	for section, keys := range Overridable {
+		full := props[section].(map[string]interface{})
+		sub := map[string]interface{}{}
+		for _, key := range keys {
+			sub[key] = full["properties"].(map[string]interface{})[key]
+		}
+		itemProps[section] = map[string]interface{}{
+			"type":                 "object",
+			"description":          full["description"],
+			"properties":           sub,
+			"additionalProperties": false,
+		}
+	}
+	item["required"] = []string{"path"}
+	return schema
+}
+
+func structSchema(t reflect.Type, prefix string) map[string]interface{} {
+	props := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		props[name] = typeSchema(field.Type, path)
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func typeSchema(t reflect.Type, path string) map[string]interface{} {
+	var s map[string]interface{}
+	switch t.Kind() {
+	case reflect.Pointer:
+		// Pointers distinguish an explicit zero from an unset value.
+		return typeSchema(t.Elem(), path)
+	case reflect.Map:
+		s = map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), path+"{}")}
+	case reflect.Struct:
+		s = structSchema(t, path)
+	case reflect.Slice:
+		s = map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), path+"[]")}
+	case reflect.Bool:
+		s = map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		s = map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		s = map[string]interface{}{"type": "number"}
+	default:
+		s = map[string]interface{}{"type": "string"}
+	}
+
+	if doc, ok := schemaDocs[path]; ok {
+		s["description"] = doc.Description
+		if len(doc.Enum) > 0 {
+			s["enum"] = doc.Enum
+		}
+		if len(doc.Examples) > 0 {
+			s["examples"] = doc.Examples
+		}
+	}
+	return s
+}
@@ -16,11 +16,73 @@ func GetUncommittedFiles() ([]string, error) {
 	return runGitLines("diff", "--name-only", "--diff-filter=ACMR")
 }
 
+// GetUntrackedFiles returns new files not yet added to the index, honoring .gitignore
+func GetUntrackedFiles() ([]string, error) {
+	return runGitLines("ls-files", "--others", "--exclude-standard")
+}
+
 // GetAllTrackedFiles returns all files tracked by git
 func GetAllTrackedFiles() ([]string, error) {
 	return runGitLines("ls-files")
 }
 
+// GetFilesAtRev returns all files in the tree of the given revision
+func GetFilesAtRev(rev string) ([]string, error) {
+	return runGitLines("ls-tree", "-r", "--name-only", rev)
+}
+
+// GetChangedFilesBetween returns files added, copied, modified, or renamed
+// between two commits.
+func GetChangedFilesBetween(base, head string) ([]string, error) {
+	return runGitLines("diff", "--name-only", "--diff-filter=ACMR", base, head)
+}
+
+// GetRangeDiff returns the diff of a file between two commits.
+func GetRangeDiff(base, head, path string, contextLines int) (string, error) {
+	cmd := exec.Command("git", "diff", unified(contextLines), base, head, "--", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff of %s between %s and %s: %w", path, base, head, err)
+	}
+	return string(out), nil
+}
+
+// CommitExists reports whether rev resolves to a commit.
+func CommitExists(rev string) bool {
+	return exec.Command("git", "rev-parse", "--verify", "--quiet", rev+"^{commit}").Run() == nil
+}
+
+// CountCommits returns the number of commits reachable from rev.
+func CountCommits(rev string) (int, error) {
+	out, err := exec.Command("git", "rev-list", "--count", rev).Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits of %s: %w", rev, err)
+	}
+	var n int
+	if _, err := fmt.Sscan(string(out), &n); err != nil {
+		return 0, fmt.Errorf("failed to count commits of %s: %w", rev, err)
+	}
+	return n, nil
+}
+
+// IsShallowRepository reports whether the repository is a shallow clone,
+// whose history stops before the first commit.
+func IsShallowRepository() bool {
+	out, err := exec.Command("git", "rev-parse", "--is-shallow-repository").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// GetFileContentAtRev returns the content of a file as of the given revision
+// without touching the worktree.
+func GetFileContentAtRev(rev, path string) (string, error) {
+	cmd := exec.Command("git", "show", rev+":"+path)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get content of %s at %s: %w", path, rev, err)
+	}
+	return string(out), nil
+}
+
 func GetStagedFileContent(path string) (string, error) {
 	// git show :path/to/file gets the staged content
 	// Note: relative paths must be correct.
@@ -32,8 +94,8 @@ func GetStagedFileContent(path string) (string, error) {
 	return string(out), nil
 }
 
-func GetStagedDiff(path string) (string, error) {
-	cmd := exec.Command("git", "diff", "--cached", "--unified=100", "--", path)
+func GetStagedDiff(path string, contextLines int) (string, error) {
+	cmd := exec.Command("git", "diff", "--cached", unified(contextLines), "--", path)
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get staged diff for %s: %w", path, err)
@@ -41,9 +103,19 @@ func GetStagedDiff(path string) (string, error) {
 	return string(out), nil
 }
 
-func GetWorktreeDiff(path string) (string, error) {
+// GetWorkingDiff returns the combined staged and unstaged diff of a file against HEAD.
+func GetWorkingDiff(path string, contextLines int) (string, error) {
+	cmd := exec.Command("git", "diff", "HEAD", unified(contextLines), "--", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working diff for %s: %w", path, err)
+	}
+	return string(out), nil
+}
+
+func GetWorktreeDiff(path string, contextLines int) (string, error) {
 	// Diff worktree against index
-	cmd := exec.Command("git", "diff", "--unified=100", "--", path)
+	cmd := exec.Command("git", "diff", unified(contextLines), "--", path)
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get worktree diff for %s: %w", path, err)
@@ -51,6 +123,20 @@ func GetWorktreeDiff(path string) (string, error) {
 	return string(out), nil
 }
 
+// DefaultDiffContextLines is the unified diff context used when
+// analysis.diff_context_lines is unset. It is generous so the LLM sees the code
+// surrounding each change.
+const DefaultDiffContextLines = 100
+
+// unified returns the --unified flag for contextLines, applying the default
+// when it is not positive.
+func unified(contextLines int) string {
+	if contextLines <= 0 {
+		contextLines = DefaultDiffContextLines
+	}
+	return fmt.Sprintf("--unified=%d", contextLines)
+}
+
 // GetRepoRoot returns the absolute path to the git repository root
 func GetRepoRoot() (string, error) {
 	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
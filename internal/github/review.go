@@ -0,0 +1,286 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tgenz1213/archguard/internal/analysis"
+)
+
+// markerPrefix starts the hidden HTML comment that identifies a finding in a
+// review or review comment body, so reruns can skip findings already posted.
+const markerPrefix = "<!-- archguard:"
+
+// ReviewClient posts ArchGuard violations to a pull request as a review with
+// inline comments.
+type ReviewClient struct {
+	apiURL string
+	token  string
+	repo   string // owner/name
+	pr     int
+	client *http.Client
+}
+
+// NewReviewClientFromEnv configures a client from the environment GitHub
+// Actions provides: GITHUB_TOKEN, GITHUB_REPOSITORY, and GITHUB_API_URL. The
+// pull request number comes from ARCHGUARD_PR_NUMBER, or from GITHUB_REF
+// (refs/pull/<n>/merge) on pull_request events.
+func NewReviewClientFromEnv() (*ReviewClient, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN is not set")
+	}
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if !strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY must be set to owner/name, got %q", repo)
+	}
+
+	prValue := os.Getenv("ARCHGUARD_PR_NUMBER")
+	if prValue == "" {
+		if m := regexp.MustCompile(`^refs/pull/(\d+)/`).FindStringSubmatch(os.Getenv("GITHUB_REF")); m != nil {
+			prValue = m[1]
+		}
+	}
+	pr, err := strconv.Atoi(prValue)
+	if err != nil || pr <= 0 {
+		return nil, fmt.Errorf("pull request number not found: set ARCHGUARD_PR_NUMBER or run on a pull_request event")
+	}
+
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+
+	return &ReviewClient{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		token:  token,
+		repo:   repo,
+		pr:     pr,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// BlobURLFromEnv returns the URL files of the commit under test are browsable
+// at, e.g. "https://github.com/owner/name/blob/<sha>", from GITHUB_SERVER_URL,
+// GITHUB_REPOSITORY, and GITHUB_SHA. It returns "" outside GitHub Actions.
+func BlobURLFromEnv() string {
+	repo, sha := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_SHA")
+	if !strings.Contains(repo, "/") || sha == "" {
+		return ""
+	}
+	serverURL := os.Getenv("GITHUB_SERVER_URL")
+	if serverURL == "" {
+		serverURL = "https://github.com"
+	}
+	return strings.TrimSuffix(serverURL, "/") + "/" + repo + "/blob/" + sha
+}
+
+// PR returns the pull request number the client posts to.
+func (c *ReviewClient) PR() int {
+	return c.pr
+}
+
+// PostReview posts the violations not already reported on the pull request
+// as a single review. Violations on lines that are part of the diff become
+// inline comments; the rest are listed in the review body, since GitHub
+// rejects comments outside the diff. It returns the number of new findings.
+func (c *ReviewClient) PostReview(ctx context.Context, records []analysis.ViolationRecord) (int, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	var pull struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := c.do(ctx, "GET", c.pullURL(""), nil, &pull); err != nil {
+		return 0, err
+	}
+
+	commentable, err := c.commentableLines(ctx)
+	if err != nil {
+		return 0, err
+	}
+	posted, err := c.postedMarkers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	type reviewComment struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Side string `json:"side"`
+		Body string `json:"body"`
+	}
+	var comments []reviewComment
+	var outsideDiff []string
+	for _, rec := range records {
+		marker := findingMarker(rec)
+		if posted[marker] {
+			continue
+		}
+		posted[marker] = true
+		if rec.Line > 0 && commentable[rec.File][rec.Line] {
+			comments = append(comments, reviewComment{Path: rec.File, Line: rec.Line, Side: "RIGHT", Body: commentBody(rec) + "\n\n" + marker})
+		} else {
+			outsideDiff = append(outsideDiff, fmt.Sprintf("- `%s` line %d: %s\n\n  %s\n  %s", rec.File, rec.Line, rec.ADRTitle, rec.Reasoning, marker))
+		}
+	}
+	if len(comments) == 0 && len(outsideDiff) == 0 {
+		return 0, nil
+	}
+
+	body := fmt.Sprintf("ArchGuard found %d architectural violation(s).", len(comments)+len(outsideDiff))
+	if len(outsideDiff) > 0 {
+		body += "\n\nOutside the diff:\n\n" + strings.Join(outsideDiff, "\n")
+	}
+	review := map[string]interface{}{
+		"commit_id": pull.Head.SHA,
+		"event":     "COMMENT",
+		"body":      body,
+		"comments":  comments,
+	}
+	if err := c.do(ctx, "POST", c.pullURL("/reviews"), review, nil); err != nil {
+		return 0, err
+	}
+	return len(comments) + len(outsideDiff), nil
+}
+
+// commentableLines maps each changed file to the right-side line numbers in
+// its diff hunks, the only lines GitHub accepts review comments on.
+func (c *ReviewClient) commentableLines(ctx context.Context) (map[string]map[int]bool, error) {
+	lines := make(map[string]map[int]bool)
+	for page := 1; ; page++ {
+		var files []struct {
+			Filename string `json:"filename"`
+			Patch    string `json:"patch"`
+		}
+		if err := c.do(ctx, "GET", c.pullURL(fmt.Sprintf("/files?per_page=100&page=%d", page)), nil, &files); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			lines[f.Filename] = patchLines(f.Patch)
+		}
+		if len(files) < 100 {
+			return lines, nil
+		}
+	}
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// patchLines returns the new-file line numbers of the context and added lines
+// in a unified diff patch.
+func patchLines(patch string) map[int]bool {
+	lines := make(map[int]bool)
+	line := 0
+	for _, l := range strings.Split(patch, "\n") {
+		if m := hunkHeader.FindStringSubmatch(l); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if line == 0 || strings.HasPrefix(l, "-") || strings.HasPrefix(l, "\\") {
+			continue
+		}
+		lines[line] = true
+		line++
+	}
+	return lines
+}
+
+// postedMarkers collects the finding markers in existing review comments and
+// review bodies on the pull request.
+func (c *ReviewClient) postedMarkers(ctx context.Context) (map[string]bool, error) {
+	markers := make(map[string]bool)
+	for _, path := range []string{"/comments", "/reviews"} {
+		for page := 1; ; page++ {
+			var items []struct {
+				Body string `json:"body"`
+			}
+			if err := c.do(ctx, "GET", c.pullURL(fmt.Sprintf("%s?per_page=100&page=%d", path, page)), nil, &items); err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				for _, m := range markerPattern.FindAllString(item.Body, -1) {
+					markers[m] = true
+				}
+			}
+			if len(items) < 100 {
+				break
+			}
+		}
+	}
+	return markers, nil
+}
+
+var markerPattern = regexp.MustCompile(regexp.QuoteMeta(markerPrefix) + `[0-9a-f]+ -->`)
+
+// findingMarker identifies a violation by ADR, file, line, and quoted code.
+func findingMarker(rec analysis.ViolationRecord) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%s", rec.ADRID, rec.File, rec.Line, rec.Code)))
+	return markerPrefix + hex.EncodeToString(sum[:8]) + " -->"
+}
+
+func commentBody(rec analysis.ViolationRecord) string {
+	body := fmt.Sprintf("**ArchGuard: %s** (ADR %s)\n\n%s", rec.ADRTitle, rec.ADRID, rec.Reasoning)
+	if rec.Rule != "" {
+		body += fmt.Sprintf("\n\n> %s", rec.Rule)
+	}
+	return body
+}
+
+func (c *ReviewClient) pullURL(suffix string) string {
+	return fmt.Sprintf("%s/repos/%s/pulls/%d%s", c.apiURL, c.repo, c.pr, suffix)
+}
+
+// do sends a GitHub API request, encoding in as the JSON body when non-nil and
+// decoding the response into out when non-nil.
+func (c *ReviewClient) do(ctx context.Context, method, url string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode github request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("github request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("github returned %d for %s %s: %s", resp.StatusCode, method, url, string(data))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode github response: %w", err)
+		}
+	}
+	return nil
+}
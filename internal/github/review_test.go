@@ -0,0 +1,93 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tgenz1213/archguard/internal/analysis"
+)
+
+func TestPatchLines(t *testing.T) {
+	patch := "@@ -1,3 +1,4 @@\n package main\n-import \"os\"\n+import \"fmt\"\n+import \"log\"\n func main() {}\n@@ -20,2 +21,2 @@\n x\n+y"
+	got := patchLines(patch)
+	for _, line := range []int{1, 2, 3, 4, 21, 22} {
+		if !got[line] {
+			t.Errorf("expected line %d to be commentable", line)
+		}
+	}
+	if len(got) != 6 {
+		t.Errorf("expected 6 commentable lines, got %v", got)
+	}
+}
+
+func TestPostReview_InlinesDiffLinesAndSkipsPostedFindings(t *testing.T) {
+	alreadyPosted := analysis.ViolationRecord{File: "main.go", Line: 2, ADRID: "0001", ADRTitle: "No fmt", Code: `import "fmt"`}
+	inDiff := analysis.ViolationRecord{File: "main.go", Line: 3, ADRID: "0002", ADRTitle: "No log", Code: `import "log"`}
+	outsideDiff := analysis.ViolationRecord{File: "main.go", Line: 40, ADRID: "0003", ADRTitle: "No globals", Code: "var x"}
+
+	var review struct {
+		CommitID string `json:"commit_id"`
+		Body     string `json:"body"`
+		Comments []struct {
+			Path string `json:"path"`
+			Line int    `json:"line"`
+			Body string `json:"body"`
+		} `json:"comments"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("missing token on %s", r.URL.Path)
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/app/pulls/7":
+			_, _ = w.Write([]byte(`{"head": {"sha": "abc123"}}`))
+		case r.URL.Path == "/repos/acme/app/pulls/7/files":
+			_, _ = w.Write([]byte(`[{"filename": "main.go", "patch": "@@ -1,2 +1,3 @@\n package main\n+import \"fmt\"\n+import \"log\""}]`))
+		case r.URL.Path == "/repos/acme/app/pulls/7/comments":
+			body, _ := json.Marshal([]map[string]string{{"body": "old\n\n" + findingMarker(alreadyPosted)}})
+			_, _ = w.Write(body)
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/app/pulls/7/reviews":
+			_, _ = w.Write([]byte(`[]`))
+		case r.Method == "POST" && r.URL.Path == "/repos/acme/app/pulls/7/reviews":
+			if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+				t.Errorf("failed to decode review: %v", err)
+			}
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("GITHUB_REPOSITORY", "acme/app")
+	t.Setenv("GITHUB_REF", "refs/pull/7/merge")
+	t.Setenv("ARCHGUARD_PR_NUMBER", "")
+	t.Setenv("GITHUB_API_URL", server.URL)
+	client, err := NewReviewClientFromEnv()
+	if err != nil {
+		t.Fatalf("NewReviewClientFromEnv failed: %v", err)
+	}
+
+	n, err := client.PostReview(context.Background(), []analysis.ViolationRecord{alreadyPosted, inDiff, outsideDiff})
+	if err != nil {
+		t.Fatalf("PostReview failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 new findings, got %d", n)
+	}
+	if review.CommitID != "abc123" {
+		t.Errorf("expected review on head commit, got %q", review.CommitID)
+	}
+	if len(review.Comments) != 1 || review.Comments[0].Line != 3 || !strings.Contains(review.Comments[0].Body, findingMarker(inDiff)) {
+		t.Errorf("expected one inline comment on line 3, got %+v", review.Comments)
+	}
+	if !strings.Contains(review.Body, "No globals") || strings.Contains(review.Body, "No fmt") {
+		t.Errorf("expected only the outside-diff finding in the review body, got:\n%s", review.Body)
+	}
+}
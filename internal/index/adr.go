@@ -7,23 +7,24 @@ import (
 	"path/filepath"
 	"strings"
 
+	pkgindex "github.com/tgenz1213/archguard/pkg/index"
 	"gopkg.in/yaml.v3"
 )
 
-type ADR struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Status    string    `json:"status"`
-	Scope     string    `json:"scope"` // Optional glob pattern from frontmatter
-	Content   string    `json:"content"`
-	Embedding []float32 `json:"embedding"`
-	RelPath   string    `json:"rel_path"`
-}
+// ADR and StringList are defined in pkg/index so that index backends can be
+// implemented outside this module.
+type (
+	ADR        = pkgindex.ADR
+	StringList = pkgindex.StringList
+)
 
 type FrontMatter struct {
-	Title  string `yaml:"title"`
-	Status string `yaml:"status"`
-	Scope  string `yaml:"scope"`
+	Title        string     `yaml:"title"`
+	Status       string     `yaml:"status"`
+	Scope        StringList `yaml:"scope"`
+	ScopeExclude StringList `yaml:"scope_exclude"`
+	Include      StringList `yaml:"include"`
+	Tags         StringList `yaml:"tags"`
 }
 
 func ParseADR(path string, rootDir string) (*ADR, error) {
@@ -36,7 +37,92 @@ func ParseADR(path string, rootDir string) (*ADR, error) {
 	filename := filepath.Base(path)
 	id := strings.Split(filename, "-")[0]
 
-	return ParseADRContent(data, id, relPath)
+	adr, err := ParseADRContent(data, id, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appendIncludes(adr, filepath.Dir(path), rootDir); err != nil {
+		return nil, err
+	}
+	return adr, nil
+}
+
+// appendIncludes appends the files listed in the ADR's include frontmatter to its
+// Content, resolving relative paths against the ADR's directory. Because the
+// included text becomes part of Content, edits to those files change the index
+// hash and trigger a re-index.
+//
+// Included text is embedded and sent to the LLM, so an include must resolve,
+// after following symlinks, to a file inside the repository (the working
+// directory) or the ADR directory adrRoot; anything else, such as
+// ~/.aws/credentials, is rejected.
+func appendIncludes(adr *ADR, baseDir, adrRoot string) error {
+	for _, inc := range adr.Include {
+		incPath := inc
+		if !filepath.IsAbs(incPath) {
+			incPath = filepath.Join(baseDir, incPath)
+		}
+		if resolved, err := filepath.EvalSymlinks(incPath); err == nil {
+			if !withinAny(resolved, ".", adrRoot) {
+				return fmt.Errorf("include %q in %s resolves to %s, outside the repository and ADR directory", inc, adr.RelPath, resolved)
+			}
+			incPath = resolved
+		}
+		data, err := os.ReadFile(incPath)
+		if err != nil {
+			return fmt.Errorf("failed to read include %q in %s: %w", inc, adr.RelPath, err)
+		}
+		adr.Content += fmt.Sprintf("\n\n## Included: %s\n\n%s", inc, string(data))
+	}
+	return nil
+}
+
+// withinAny reports whether path lies inside one of dirs, comparing the
+// absolute, symlink-resolved forms.
+func withinAny(path string, dirs ...string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range dirs {
+		if r, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = r
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sections splits ADR content at level-2 headings (## Context, ## Decision, ...)
+// and returns each non-empty section including its heading. Text before the
+// first heading is not part of any section.
+func Sections(content string) []string {
+	var sections []string
+	var heading string
+	var body []string
+	flush := func() {
+		if heading != "" && strings.TrimSpace(strings.Join(body, "\n")) != "" {
+			sections = append(sections, heading+"\n"+strings.TrimSpace(strings.Join(body, "\n")))
+		}
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "## ") {
+			flush()
+			heading, body = line, nil
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
 }
 
 func ParseADRContent(data []byte, id string, relPath string) (*ADR, error) {
@@ -53,13 +139,23 @@ func ParseADRContent(data []byte, id string, relPath string) (*ADR, error) {
 	if err := yaml.Unmarshal(parts[1], &fm); err != nil {
 		return nil, fmt.Errorf("failed to parse frontmatter in %s: %w", relPath, err)
 	}
+	// An empty title would surface as a nameless violation downstream.
+	if strings.TrimSpace(fm.Title) == "" {
+		return nil, fmt.Errorf("missing required frontmatter field \"title\" in %s", relPath)
+	}
+	if strings.TrimSpace(fm.Status) == "" {
+		return nil, fmt.Errorf("missing required frontmatter field \"status\" in %s", relPath)
+	}
 
 	return &ADR{
-		ID:      id,
-		Title:   fm.Title,
-		Status:  fm.Status,
-		Scope:   fm.Scope,
-		Content: string(parts[2]),
-		RelPath: relPath,
+		ID:           id,
+		Title:        fm.Title,
+		Status:       fm.Status,
+		Scope:        fm.Scope,
+		ScopeExclude: fm.ScopeExclude,
+		Include:      fm.Include,
+		Tags:         fm.Tags,
+		Content:      string(parts[2]),
+		RelPath:      relPath,
 	}, nil
 }
@@ -0,0 +1,205 @@
+package index
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseADRContent_ScopeExclude(t *testing.T) {
+	tests := []struct {
+		name string
+		fm   string
+		want []string
+	}{
+		{"single string", `scope_exclude: "test/**"`, []string{"test/**"}},
+		{"list", "scope_exclude:\n  - \"test/**\"\n  - \"generated/**\"", []string{"test/**", "generated/**"}},
+		{"absent", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("---\ntitle: \"T\"\nstatus: \"Accepted\"\n" + tt.fm + "\n---\n\n## Decision\nRule.")
+			adr, err := ParseADRContent(data, "0001", "0001-t.md")
+			if err != nil {
+				t.Fatalf("ParseADRContent failed: %v", err)
+			}
+			if !reflect.DeepEqual(adr.ScopeExclude, tt.want) {
+				t.Errorf("expected ScopeExclude %v, got %v", tt.want, adr.ScopeExclude)
+			}
+		})
+	}
+}
+
+func TestParseADRContent_Tags(t *testing.T) {
+	data := []byte("---\ntitle: \"T\"\nstatus: \"Accepted\"\ntags: [security, pci]\n---\n\n## Decision\nRule.")
+	adr, err := ParseADRContent(data, "0001", "0001-t.md")
+	if err != nil {
+		t.Fatalf("ParseADRContent failed: %v", err)
+	}
+	if !reflect.DeepEqual(adr.Tags, []string{"security", "pci"}) {
+		t.Errorf("expected Tags [security pci], got %v", adr.Tags)
+	}
+	if !adr.HasAnyTag([]string{"style", "PCI"}) {
+		t.Error("expected a case-insensitive match on PCI")
+	}
+	if adr.HasAnyTag([]string{"style"}) {
+		t.Error("expected no match on style")
+	}
+	if !adr.HasAnyTag(nil) {
+		t.Error("expected every ADR to match an empty tag list")
+	}
+}
+
+func TestParseADRContent_Scope(t *testing.T) {
+	tests := []struct {
+		name string
+		fm   string
+		want StringList
+	}{
+		{"single string", `scope: "cmd/**"`, StringList{"cmd/**"}},
+		{"list", "scope:\n  - \"cmd/**\"\n  - \"internal/server/**\"", StringList{"cmd/**", "internal/server/**"}},
+		{"absent", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("---\ntitle: \"T\"\nstatus: \"Accepted\"\n" + tt.fm + "\n---\n\n## Decision\nRule.")
+			adr, err := ParseADRContent(data, "0001", "0001-t.md")
+			if err != nil {
+				t.Fatalf("ParseADRContent failed: %v", err)
+			}
+			if !reflect.DeepEqual(adr.Scope, tt.want) {
+				t.Errorf("expected Scope %v, got %v", tt.want, adr.Scope)
+			}
+		})
+	}
+}
+
+func TestStringList_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want StringList
+	}{
+		{`"cmd/**"`, StringList{"cmd/**"}},
+		{`["cmd/**","pkg/**"]`, StringList{"cmd/**", "pkg/**"}},
+		{`""`, nil},
+		{`null`, nil},
+	}
+	for _, tt := range tests {
+		var got StringList
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseADRContent_RequiresTitleAndStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		fm    string
+		field string
+	}{
+		{"missing title", "status: \"Accepted\"", "title"},
+		{"blank title", "title: \"  \"\nstatus: \"Accepted\"", "title"},
+		{"missing status", "title: \"T\"", "status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte("---\n" + tt.fm + "\n---\n\n## Decision\nRule.")
+			_, err := ParseADRContent(data, "0001", "0001-t.md")
+			if err == nil || !strings.Contains(err.Error(), tt.field) || !strings.Contains(err.Error(), "0001-t.md") {
+				t.Errorf("expected error naming %q and the file, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestParseADR_RejectsIncludesOutsideRepo(t *testing.T) {
+	dir := t.TempDir()
+	adrDir := filepath.Join(dir, "adrs")
+	if err := os.MkdirAll(adrDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(secret, []byte("aws_secret_access_key = hunter2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(adrDir, "linked.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, include := range []string{"../credentials", secret, "linked.txt"} {
+		adrPath := filepath.Join(adrDir, "0001-deps.md")
+		content := "---\ntitle: \"Deps\"\nstatus: \"Accepted\"\ninclude: \"" + include + "\"\n---\n\n## Decision\nDo not leak."
+		if err := os.WriteFile(adrPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ParseADR(adrPath, adrDir)
+		if err == nil || !strings.Contains(err.Error(), "outside the repository and ADR directory") {
+			t.Errorf("include %q: expected a containment error, got %v", include, err)
+		}
+	}
+}
+
+func TestParseADR_AppendsIncludes(t *testing.T) {
+	dir := t.TempDir()
+	rulesDir := filepath.Join(dir, "rules")
+	if err := os.MkdirAll(rulesDir, 0755); err != nil {
+		t.Fatalf("failed to create rules dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rulesDir, "forbidden.txt"), []byte("github.com/forbidden/pkg"), 0644); err != nil {
+		t.Fatalf("failed to write include: %v", err)
+	}
+
+	adrPath := filepath.Join(dir, "0001-deps.md")
+	adrContent := "---\ntitle: \"Deps\"\nstatus: \"Accepted\"\ninclude: \"rules/forbidden.txt\"\n---\n\n## Decision\nDo not import forbidden packages."
+	if err := os.WriteFile(adrPath, []byte(adrContent), 0644); err != nil {
+		t.Fatalf("failed to write ADR: %v", err)
+	}
+
+	adr, err := ParseADR(adrPath, dir)
+	if err != nil {
+		t.Fatalf("ParseADR failed: %v", err)
+	}
+	if !strings.Contains(adr.Content, "github.com/forbidden/pkg") {
+		t.Errorf("expected included rules in content, got %q", adr.Content)
+	}
+
+	store := NewLocalStore(1)
+	before, _ := store.CalculateHash([]ADR{*adr}, "model")
+	if err := os.WriteFile(filepath.Join(rulesDir, "forbidden.txt"), []byte("github.com/other/pkg"), 0644); err != nil {
+		t.Fatalf("failed to update include: %v", err)
+	}
+	updated, err := ParseADR(adrPath, dir)
+	if err != nil {
+		t.Fatalf("ParseADR failed: %v", err)
+	}
+	after, _ := store.CalculateHash([]ADR{*updated}, "model")
+	if before == after {
+		t.Error("expected index hash to change when an included file changes")
+	}
+
+	if err := os.Remove(filepath.Join(rulesDir, "forbidden.txt")); err != nil {
+		t.Fatalf("failed to remove include: %v", err)
+	}
+	if _, err := ParseADR(adrPath, dir); err == nil {
+		t.Error("expected error for missing include file")
+	}
+}
+
+func TestSections(t *testing.T) {
+	content := "\n# Use gRPC\n\n## Context\nMany services.\n\n## Decision\nUse gRPC.\n\n## Consequences\n\n"
+	got := Sections(content)
+	want := []string{"## Context\nMany services.", "## Decision\nUse gRPC."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
@@ -21,6 +21,7 @@ type ConfluenceProvider struct {
 	username         string
 	token            string
 	acceptedStatuses []string
+	output
 }
 
 // NewConfluenceProvider creates a new ConfluenceProvider.
@@ -114,7 +115,7 @@ func (p *ConfluenceProvider) GetADRs(ctx context.Context) ([]ADR, error) {
 			adrID := fmt.Sprintf("confluence-%s", result.ID)
 			adr, err := ParseADRContent([]byte(rawText), adrID, relPath)
 			if err != nil {
-				fmt.Printf("Warning: skipping Confluence page %s: %v\n", relPath, err)
+				fmt.Fprintf(p.writer(), "Warning: skipping Confluence page %s: %v\n", relPath, err)
 				continue
 			}
 
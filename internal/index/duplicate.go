@@ -0,0 +1,21 @@
+package index
+
+import "sort"
+
+// DuplicateIDs groups the RelPaths of ADRs that share an ID, keyed by ID. Since
+// archguard-ignore directives refer to ADRs by ID, a collision makes
+// suppression ambiguous.
+func DuplicateIDs(adrs []ADR) map[string][]string {
+	byID := make(map[string][]string)
+	for _, adr := range adrs {
+		byID[adr.ID] = append(byID[adr.ID], adr.RelPath)
+	}
+	for id, paths := range byID {
+		if len(paths) < 2 {
+			delete(byID, id)
+			continue
+		}
+		sort.Strings(paths)
+	}
+	return byID
+}
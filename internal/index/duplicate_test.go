@@ -0,0 +1,20 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDuplicateIDs(t *testing.T) {
+	adrs := []ADR{
+		{ID: "0012", RelPath: "0012-use-grpc.md"},
+		{ID: "0011", RelPath: "0011-use-go.md"},
+		{ID: "0012", RelPath: "0012-drop-orm.md"},
+	}
+
+	got := DuplicateIDs(adrs)
+	want := map[string][]string{"0012": {"0012-drop-orm.md", "0012-use-grpc.md"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
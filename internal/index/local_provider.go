@@ -12,6 +12,7 @@ import (
 type LocalProvider struct {
 	dirPath          string
 	acceptedStatuses []string
+	output
 }
 
 // NewLocalProvider creates a new LocalProvider.
@@ -24,30 +25,54 @@ func NewLocalProvider(dirPath string, acceptedStatuses []string) *LocalProvider
 
 // GetADRs walks the directory tree and returns ADRs matching accepted statuses.
 func (p *LocalProvider) GetADRs(ctx context.Context) ([]ADR, error) {
+	entries, err := p.Preview(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var validADRs []ADR
+	for _, entry := range entries {
+		switch {
+		case entry.ADR == nil:
+			fmt.Fprintf(p.writer(), "Warning: skipping %s: %s\n", entry.Path, entry.Skipped)
+		case entry.Skipped == "":
+			validADRs = append(validADRs, *entry.ADR)
+		}
+	}
+	return validADRs, nil
+}
+
+// Preview walks the directory tree and reports every ADR file, with the
+// reason it is skipped when it fails to parse or its status is not accepted.
+func (p *LocalProvider) Preview(ctx context.Context) ([]PreviewEntry, error) {
+	var entries []PreviewEntry
+
+	if _, err := os.Stat(p.dirPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("ADR directory %q does not exist", p.dirPath)
+	}
 
-	err := filepath.Walk(p.dirPath, func(path string, info os.FileInfo, err error) error {
+	// filepath.Walk does not descend into a symlinked root, so resolve links
+	// such as docs/arch -> ../governance/adrs first.
+	root, err := filepath.EvalSymlinks(p.dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ADR directory %q: %w", p.dirPath, err)
+	}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if !info.IsDir() && strings.HasSuffix(info.Name(), ".md") {
-			adr, err := ParseADR(path, p.dirPath)
+			adr, err := ParseADR(path, root)
 			if err != nil {
-				fmt.Printf("Warning: skipping %s: %v\n", path, err)
+				entries = append(entries, PreviewEntry{Path: path, Skipped: err.Error()})
 				return nil
 			}
 
-			// Filter by status
-			accept := false
-			for _, status := range p.acceptedStatuses {
-				if status == "*" || strings.EqualFold(strings.TrimSpace(adr.Status), strings.TrimSpace(status)) {
-					accept = true
-					break
-				}
-			}
-			if accept {
-				validADRs = append(validADRs, *adr)
+			entry := PreviewEntry{Path: path, ADR: adr}
+			if !statusAccepted(adr.Status, p.acceptedStatuses) {
+				entry.Skipped = statusSkipped(adr.Status)
 			}
+			entries = append(entries, entry)
 		}
 		return nil
 	})
@@ -55,5 +80,16 @@ func (p *LocalProvider) GetADRs(ctx context.Context) ([]ADR, error) {
 	if err != nil {
 		return nil, err
 	}
-	return validADRs, nil
+	return entries, nil
+}
+
+// statusAccepted reports whether status is one of accepted, ignoring case and
+// surrounding space. "*" accepts every status.
+func statusAccepted(status string, accepted []string) bool {
+	for _, a := range accepted {
+		if a == "*" || strings.EqualFold(strings.TrimSpace(status), strings.TrimSpace(a)) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,92 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalProvider_FollowsSymlinkedRoot(t *testing.T) {
+	tmp := t.TempDir()
+	governance := filepath.Join(tmp, "governance", "adrs")
+	if err := os.MkdirAll(governance, 0755); err != nil {
+		t.Fatal(err)
+	}
+	adr := "---\ntitle: Use Go\nstatus: Accepted\n---\n## Decision\nUse Go.\n"
+	if err := os.WriteFile(filepath.Join(governance, "0001-use-go.md"), []byte(adr), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(tmp, "repo-adrs")
+	if err := os.Symlink(governance, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	adrs, err := NewLocalProvider(link, []string{"Accepted"}).GetADRs(context.Background())
+	if err != nil {
+		t.Fatalf("GetADRs failed: %v", err)
+	}
+	if len(adrs) != 1 || adrs[0].RelPath != "0001-use-go.md" {
+		t.Fatalf("expected the ADR behind the symlink, got %+v", adrs)
+	}
+}
+
+func TestLocalProvider_PreviewReportsSkipReasons(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"0001-use-go.md":   "---\ntitle: Use Go\nstatus: Accepted\n---\n## Decision\nUse Go.\n",
+		"0002-draft.md":    "---\ntitle: Draft\nstatus: Proposed\n---\n## Decision\nMaybe.\n",
+		"0003-broken.md":   "---\ntitle: [unterminated\n---\n",
+		"notes/readme.txt": "not an ADR",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := NewLocalProvider(dir, []string{"Accepted"}).Preview(context.Background())
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+	byName := make(map[string]PreviewEntry)
+	for _, entry := range entries {
+		byName[filepath.Base(entry.Path)] = entry
+	}
+	if e := byName["0001-use-go.md"]; e.ADR == nil || e.Skipped != "" {
+		t.Errorf("expected 0001 to be indexed, got %+v", e)
+	}
+	if e := byName["0002-draft.md"]; e.ADR == nil || !strings.Contains(e.Skipped, `"Proposed"`) {
+		t.Errorf("expected 0002 to be skipped for its status, got %+v", e)
+	}
+	if e := byName["0003-broken.md"]; e.ADR != nil || e.Skipped == "" {
+		t.Errorf("expected 0003 to be skipped as unparseable, got %+v", e)
+	}
+}
+
+func TestCompositeProvider_SetOutputReachesChildren(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0001-broken.md"), []byte("---\ntitle: [unterminated\n---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	provider := NewCompositeProvider(NewLocalProvider(dir, []string{"Accepted"}))
+	SetOutput(provider, &out)
+	if _, err := provider.GetADRs(context.Background()); err != nil {
+		t.Fatalf("GetADRs failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "failed to parse frontmatter in 0001-broken.md") {
+		t.Errorf("expected the skip warning on the configured writer, got %q", out.String())
+	}
+}
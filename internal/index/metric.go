@@ -0,0 +1,103 @@
+package index
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Metric is the vector comparison Search uses, set by vector_store.metric.
+// Use the metric the embedding model was trained for.
+type Metric string
+
+const (
+	// MetricCosine scores from -1 to 1; higher is more similar, and an ADR
+	// matches when its score is at least the threshold.
+	MetricCosine Metric = "cosine"
+	// MetricDot scores the raw dot product, for models whose embeddings are
+	// not normalized; higher is more similar, and an ADR matches when its
+	// score is at least the threshold.
+	MetricDot Metric = "dot"
+	// MetricEuclidean scores the Euclidean distance; lower is more similar,
+	// and an ADR matches when its distance is at most the threshold.
+	MetricEuclidean Metric = "euclidean"
+)
+
+// ParseMetric returns the Metric named by s, defaulting to cosine when s is
+// empty.
+func ParseMetric(s string) (Metric, error) {
+	switch m := Metric(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return MetricCosine, nil
+	case MetricCosine, MetricDot, MetricEuclidean:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid vector_store.metric %q (expected cosine, dot, or euclidean)", s)
+	}
+}
+
+// CheckThreshold rejects a similarity_threshold that no ADR can pass: a
+// distance must be positive, since only identical vectors are 0 apart.
+func (m Metric) CheckThreshold(threshold float64) error {
+	if m == MetricEuclidean && threshold <= 0 {
+		return fmt.Errorf("invalid vector_store.similarity_threshold %g for the euclidean metric (expected a positive maximum distance, e.g. 0.8)", threshold)
+	}
+	return nil
+}
+
+// Score compares two embeddings; vectors of different lengths never match.
+func (m Metric) Score(a, b []float32) float64 {
+	switch m {
+	case MetricDot:
+		if len(a) != len(b) {
+			return math.Inf(-1)
+		}
+		var dot float64
+		for i := range a {
+			dot += float64(a[i] * b[i])
+		}
+		return dot
+	case MetricEuclidean:
+		if len(a) != len(b) {
+			return math.Inf(1)
+		}
+		var sum float64
+		for i := range a {
+			d := float64(a[i] - b[i])
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	default:
+		return CosineSimilarity(a, b)
+	}
+}
+
+// Better reports whether score a is a closer match than score b.
+func (m Metric) Better(a, b float64) bool {
+	if m == MetricEuclidean {
+		return a < b
+	}
+	return a > b
+}
+
+// Matches reports whether score passes threshold: at least the threshold for
+// similarities, at most the threshold for distances.
+func (m Metric) Matches(score, threshold float64) bool {
+	if m == MetricEuclidean {
+		return score <= threshold
+	}
+	return score >= threshold
+}
+
+// NoThreshold returns a threshold every score passes, for listing the
+// nearest ADRs regardless of vector_store.similarity_threshold.
+func (m Metric) NoThreshold() float64 {
+	switch m {
+	case MetricDot:
+		return math.Inf(-1)
+	case MetricEuclidean:
+		return math.Inf(1)
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,30 @@
+package index
+
+import (
+	"io"
+	"os"
+)
+
+// output holds the writer that stores and providers print progress and
+// warnings to. Types that print embed it; see SetOutput.
+type output struct {
+	w io.Writer
+}
+
+func (o *output) setOutput(w io.Writer) { o.w = w }
+
+// writer returns the configured writer, defaulting to os.Stdout.
+func (o *output) writer() io.Writer {
+	if o.w == nil {
+		return os.Stdout
+	}
+	return o.w
+}
+
+// SetOutput directs the progress and warnings printed by a store or provider
+// to w. It is a no-op for types that do not print.
+func SetOutput(v any, w io.Writer) {
+	if o, ok := v.(interface{ setOutput(io.Writer) }); ok {
+		o.setOutput(w)
+	}
+}
@@ -3,6 +3,7 @@ package index
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -18,6 +19,8 @@ type PgStore struct {
 	connectionString string
 	projectName      string
 	concurrency      int
+	metric           Metric
+	output
 }
 
 // NewPgStore initializes a new PgStore connected to the given database URL.
@@ -77,6 +80,10 @@ func (s *PgStore) Load(path, modelName string, dim int, currentHash string) erro
 			embedding vector(%d),
 			UNIQUE (project_name, rel_path)
 		);
+		ALTER TABLE archguard_adrs
+			ADD COLUMN IF NOT EXISTS scope TEXT[] NOT NULL DEFAULT '{}',
+			ADD COLUMN IF NOT EXISTS scope_exclude TEXT[] NOT NULL DEFAULT '{}',
+			ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
 		CREATE INDEX IF NOT EXISTS archguard_adrs_embedding_idx ON archguard_adrs USING hnsw (embedding vector_cosine_ops);
 	`, dim)
 
@@ -97,7 +104,7 @@ func (s *PgStore) BuildIndex(ctx context.Context, modelName string, dim int, pro
 	}
 
 	// Fetch existing ADRs from database for this project
-	rows, err := s.pool.Query(ctx, "SELECT rel_path, title, status, content FROM archguard_adrs WHERE project_name = $1", s.projectName)
+	rows, err := s.pool.Query(ctx, "SELECT rel_path, title, status, content, scope, scope_exclude, tags FROM archguard_adrs WHERE project_name = $1", s.projectName)
 	if err != nil {
 		return fmt.Errorf("failed to query existing ADRs: %w", err)
 	}
@@ -106,27 +113,35 @@ func (s *PgStore) BuildIndex(ctx context.Context, modelName string, dim int, pro
 	existingMap := make(map[string]ADR)
 	for rows.Next() {
 		var relPath, title, status, content string
-		if err := rows.Scan(&relPath, &title, &status, &content); err != nil {
+		var scope, scopeExclude, tags []string
+		if err := rows.Scan(&relPath, &title, &status, &content, &scope, &scopeExclude, &tags); err != nil {
 			continue
 		}
 		existingMap[relPath] = ADR{
-			Title:   title,
-			Status:  status,
-			Content: content,
+			Title:        title,
+			Status:       status,
+			Scope:        scope,
+			ScopeExclude: scopeExclude,
+			Tags:         tags,
+			Content:      content,
 		}
 	}
 
 	var adrsToEmbed []int
 	for i, valid := range validADRs {
 		existing, ok := existingMap[valid.RelPath]
-		if ok && existing.Content == valid.Content && existing.Title == valid.Title && existing.Status == valid.Status {
+		if ok && existing.Content == valid.Content && existing.Title == valid.Title && existing.Status == valid.Status &&
+			slices.Equal(existing.Scope, valid.Scope) && slices.Equal(existing.ScopeExclude, valid.ScopeExclude) && slices.Equal(existing.Tags, valid.Tags) {
 			// Already embedded and unchanged
 		} else {
 			adrsToEmbed = append(adrsToEmbed, i)
 		}
 	}
 
-	fmt.Printf("Found %d valid ADRs. Generating embeddings for %d new/modified ADRs...\n", len(validADRs), len(adrsToEmbed))
+	fmt.Fprintf(s.writer(), "Found %d valid ADRs. Generating embeddings for %d new/modified ADRs...\n", len(validADRs), len(adrsToEmbed))
+	if len(validADRs) == 0 {
+		warnNoADRs(s.writer())
+	}
 
 	if len(adrsToEmbed) > 0 {
 		concurrency := s.concurrency
@@ -147,20 +162,24 @@ func (s *PgStore) BuildIndex(ctx context.Context, modelName string, dim int, pro
 				}
 				validADRs[idx].Embedding = emb
 
+				adr := validADRs[idx]
 				vec := pgvector.NewVector(emb)
 				_, err = s.pool.Exec(gCtx, `
-					INSERT INTO archguard_adrs (project_name, rel_path, title, status, content, embedding)
-					VALUES ($1, $2, $3, $4, $5, $6)
+					INSERT INTO archguard_adrs (project_name, rel_path, title, status, content, embedding, scope, scope_exclude, tags)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 					ON CONFLICT (project_name, rel_path) DO UPDATE SET
 						title = EXCLUDED.title,
 						status = EXCLUDED.status,
 						content = EXCLUDED.content,
-						embedding = EXCLUDED.embedding
-				`, s.projectName, validADRs[idx].RelPath, validADRs[idx].Title, validADRs[idx].Status, validADRs[idx].Content, vec)
+						embedding = EXCLUDED.embedding,
+						scope = EXCLUDED.scope,
+						scope_exclude = EXCLUDED.scope_exclude,
+						tags = EXCLUDED.tags
+				`, s.projectName, adr.RelPath, adr.Title, adr.Status, adr.Content, vec, textArray(adr.Scope), textArray(adr.ScopeExclude), textArray(adr.Tags))
 				if err != nil {
 					return fmt.Errorf("failed to upsert ADR %s: %w", validADRs[idx].RelPath, err)
 				}
-				fmt.Printf(".")
+				fmt.Fprintf(s.writer(), ".")
 				return nil
 			})
 		}
@@ -168,7 +187,7 @@ func (s *PgStore) BuildIndex(ctx context.Context, modelName string, dim int, pro
 		if err := g.Wait(); err != nil {
 			return err
 		}
-		fmt.Println()
+		fmt.Fprintln(s.writer())
 	}
 
 	// Delete missing ADRs
@@ -185,7 +204,7 @@ func (s *PgStore) BuildIndex(ctx context.Context, modelName string, dim int, pro
 	}
 
 	if len(toDelete) > 0 {
-		fmt.Printf("Deleting %d removed ADRs from database...\n", len(toDelete))
+		fmt.Fprintf(s.writer(), "Deleting %d removed ADRs from database...\n", len(toDelete))
 		for _, relPath := range toDelete {
 			_, err := s.pool.Exec(ctx, "DELETE FROM archguard_adrs WHERE project_name = $1 AND rel_path = $2", s.projectName, relPath)
 			if err != nil {
@@ -198,35 +217,50 @@ func (s *PgStore) BuildIndex(ctx context.Context, modelName string, dim int, pro
 	modifiedCount := len(adrsToEmbed) + len(toDelete)
 	totalCount := len(validADRs) + len(toDelete)
 	if totalCount > 0 && float64(modifiedCount)/float64(totalCount) >= 0.20 {
-		fmt.Println("Modifications exceeded 20% threshold. Rebuilding HNSW index...")
+		fmt.Fprintln(s.writer(), "Modifications exceeded 20% threshold. Rebuilding HNSW index...")
 		_, err := s.pool.Exec(ctx, "REINDEX INDEX archguard_adrs_embedding_idx")
 		if err != nil {
-			fmt.Printf("Warning: failed to reindex HNSW graph: %v\n", err)
+			fmt.Fprintf(s.writer(), "Warning: failed to reindex HNSW graph: %v\n", err)
 		}
 	}
 
 	return nil
 }
 
-// Search performs a vector similarity search across the Postgres store using cosine distance.
+// Search performs a vector similarity search across the Postgres store using
+// the store's metric. Only cosine searches use the HNSW index, which is built
+// with vector_cosine_ops; dot and euclidean searches scan the project's rows.
 func (s *PgStore) Search(queryEmbedding []float32, threshold float64, topK int) []SearchResult {
 	ctx := context.Background()
 	vec := pgvector.NewVector(queryEmbedding)
 
-	// pgvector uses <=> for cosine distance. Similarity is 1 - distance.
-	// So similarity >= threshold means distance <= 1 - threshold.
-	distanceThreshold := 1.0 - threshold
+	// Each operator is a distance ordered ascending; score converts it back to
+	// the metric's score and limit is the threshold as a distance.
+	var op, score string
+	var limit float64
+	switch s.metric {
+	case MetricDot:
+		// <#> is the negative inner product, so dot >= threshold means
+		// distance <= -threshold.
+		op, score, limit = "<#>", "-(embedding <#> $1)", -threshold
+	case MetricEuclidean:
+		op, score, limit = "<->", "embedding <-> $1", threshold
+	default:
+		// <=> is cosine distance. Similarity is 1 - distance, so
+		// similarity >= threshold means distance <= 1 - threshold.
+		op, score, limit = "<=>", "1 - (embedding <=> $1)", 1.0-threshold
+	}
 
-	query := `
-		SELECT rel_path, title, status, content, (1 - (embedding <=> $1)) as similarity
+	query := fmt.Sprintf(`
+		SELECT rel_path, title, status, content, scope, scope_exclude, tags, (%[2]s) as similarity
 		FROM archguard_adrs
-		WHERE project_name = $2 AND embedding <=> $1 <= $3
-		ORDER BY embedding <=> $1
+		WHERE project_name = $2 AND embedding %[1]s $1 <= $3
+		ORDER BY embedding %[1]s $1
 		LIMIT $4
-	`
-	rows, err := s.pool.Query(ctx, query, vec, s.projectName, distanceThreshold, topK)
+	`, op, score)
+	rows, err := s.pool.Query(ctx, query, vec, s.projectName, limit, topK)
 	if err != nil {
-		fmt.Printf("PgStore Search query failed: %v\n", err)
+		fmt.Fprintf(s.writer(), "PgStore Search query failed: %v\n", err)
 		return nil
 	}
 	defer rows.Close()
@@ -234,11 +268,13 @@ func (s *PgStore) Search(queryEmbedding []float32, threshold float64, topK int)
 	var results []SearchResult
 	for rows.Next() {
 		var adr ADR
+		var scope []string
 		var score float64
-		if err := rows.Scan(&adr.RelPath, &adr.Title, &adr.Status, &adr.Content, &score); err != nil {
-			fmt.Printf("PgStore Row scan failed: %v\n", err)
+		if err := rows.Scan(&adr.RelPath, &adr.Title, &adr.Status, &adr.Content, &scope, &adr.ScopeExclude, &adr.Tags, &score); err != nil {
+			fmt.Fprintf(s.writer(), "PgStore Row scan failed: %v\n", err)
 			continue
 		}
+		adr.Scope = scope
 
 		results = append(results, SearchResult{
 			ADR:   &adr,
@@ -248,3 +284,12 @@ func (s *PgStore) Search(queryEmbedding []float32, threshold float64, topK int)
 
 	return results
 }
+
+// textArray returns s, or an empty slice for nil, so pgx writes '{}' rather
+// than NULL into the NOT NULL array columns.
+func textArray(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
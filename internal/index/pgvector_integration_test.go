@@ -72,6 +72,8 @@ func TestPgStore_Integration(t *testing.T) {
 	adrContent := `---
 title: "Integration Test ADR"
 status: "Accepted"
+scope: "internal/**"
+tags: [security]
 ---
 Test Content`
 	err = os.WriteFile(filepath.Join(tmpDir, "test_adr.md"), []byte(adrContent), 0644)
@@ -104,6 +106,8 @@ Test Content`
 		assert.Equal(t, "Integration Test ADR", results[0].ADR.Title)
 		assert.Equal(t, "Accepted", results[0].ADR.Status)
 		assert.Contains(t, results[0].ADR.Content, "Test Content")
+		assert.Equal(t, index.StringList{"internal/**"}, results[0].ADR.Scope)
+		assert.Equal(t, []string{"security"}, results[0].ADR.Tags)
 		// Similarity score should be very high
 		assert.Greater(t, results[0].Score, 0.9)
 	}
@@ -0,0 +1,186 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyProvider reads rules from policy documents: Markdown files in which
+// every "## " section is a separate rule, for teams that keep one POLICY.md
+// instead of numbered ADRs. Its path may be a single file or a directory.
+type PolicyProvider struct {
+	path             string
+	acceptedStatuses []string
+	output
+}
+
+// NewPolicyProvider creates a new PolicyProvider.
+func NewPolicyProvider(path string, acceptedStatuses []string) *PolicyProvider {
+	return &PolicyProvider{
+		path:             path,
+		acceptedStatuses: acceptedStatuses,
+	}
+}
+
+// GetADRs returns one ADR per section of every policy document under the
+// provider's path. Documents with a frontmatter status outside the accepted
+// statuses are skipped; documents without frontmatter are always in force.
+func (p *PolicyProvider) GetADRs(ctx context.Context) ([]ADR, error) {
+	entries, err := p.Preview(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rules []ADR
+	for _, entry := range entries {
+		switch {
+		case entry.ADR == nil:
+			fmt.Fprintf(p.writer(), "Warning: skipping %s: %s\n", entry.Path, entry.Skipped)
+		case entry.Skipped == "":
+			rules = append(rules, *entry.ADR)
+		}
+	}
+	return rules, nil
+}
+
+// Preview reports every rule of every policy document, with the reason it is
+// skipped when its document fails to parse or has a status that is not
+// accepted.
+func (p *PolicyProvider) Preview(ctx context.Context) ([]PreviewEntry, error) {
+	root, err := filepath.EvalSymlinks(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve policy path %q: %w", p.path, err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	// A single file is reported relative to its own directory.
+	base := root
+	if !info.IsDir() {
+		base = filepath.Dir(root)
+	}
+
+	var entries []PreviewEntry
+	// ids holds the rule IDs taken by earlier documents, so a heading repeated
+	// in another document cannot share an ID (and archguard-ignore directive)
+	// with the first one.
+	ids := make(map[string]bool)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(base, path)
+		sections, status, err := ParsePolicyContent(data, relPath)
+		if err != nil {
+			entries = append(entries, PreviewEntry{Path: path, Skipped: err.Error()})
+			return nil
+		}
+		var skipped string
+		if status != "" && !statusAccepted(status, p.acceptedStatuses) {
+			skipped = statusSkipped(status)
+		}
+		stem := Slug(strings.TrimSuffix(info.Name(), ".md"))
+		for i := range sections {
+			sections[i].ID = uniqueID(ids, sections[i].ID, stem)
+			entries = append(entries, PreviewEntry{Path: path, ADR: &sections[i], Skipped: skipped})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ParsePolicyContent splits a policy document into one ADR per "## " section.
+// Each rule's ID is the slug of its heading and its RelPath is the document
+// path with the slug as fragment, e.g. "POLICY.md#no-raw-sql". Optional
+// frontmatter supplies a status and a scope shared by all sections; the
+// returned status is empty when the document has none.
+func ParsePolicyContent(data []byte, relPath string) ([]ADR, string, error) {
+	var fm FrontMatter
+	body := data
+	if bytes.HasPrefix(data, []byte("---")) {
+		parts := bytes.SplitN(data, []byte("---"), 3)
+		if len(parts) < 3 {
+			return nil, "", fmt.Errorf("invalid frontmatter format in %s", relPath)
+		}
+		if err := yaml.Unmarshal(parts[1], &fm); err != nil {
+			return nil, "", fmt.Errorf("failed to parse frontmatter in %s: %w", relPath, err)
+		}
+		body = parts[2]
+	}
+
+	status := strings.TrimSpace(fm.Status)
+	ruleStatus := status
+	if ruleStatus == "" {
+		ruleStatus = "Accepted"
+	}
+
+	var rules []ADR
+	seen := make(map[string]int)
+	for _, section := range Sections(string(body)) {
+		heading, _, _ := strings.Cut(section, "\n")
+		title := strings.TrimSpace(strings.TrimPrefix(heading, "## "))
+		id := Slug(title)
+		if id == "" {
+			id = "rule"
+		}
+		// Repeated headings get a numeric suffix so RelPaths stay unique.
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		rules = append(rules, ADR{
+			ID:           id,
+			Title:        title,
+			Status:       ruleStatus,
+			Scope:        fm.Scope,
+			ScopeExclude: fm.ScopeExclude,
+			Tags:         fm.Tags,
+			Content:      section,
+			RelPath:      relPath + "#" + id,
+		})
+	}
+	if len(rules) == 0 {
+		return nil, "", fmt.Errorf("no \"## \" sections found in %s", relPath)
+	}
+	return rules, status, nil
+}
+
+// uniqueID returns id, or id prefixed with the document stem when an earlier
+// document already uses it, e.g. "frontend-logging", and records the result
+// in ids.
+func uniqueID(ids map[string]bool, id, stem string) string {
+	if ids[id] {
+		prefixed := stem + "-" + id
+		id = prefixed
+		for n := 2; ids[id]; n++ {
+			id = fmt.Sprintf("%s-%d", prefixed, n)
+		}
+	}
+	ids[id] = true
+	return id
+}
+
+// Slug lowercases title and joins its runs of letters and digits with
+// hyphens, e.g. "Use gRPC (internal)" -> "use-grpc-internal".
+func Slug(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(words, "-")
+}
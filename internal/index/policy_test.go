@@ -0,0 +1,96 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicyContent(t *testing.T) {
+	doc := "# Engineering Policy\n\nIntro text.\n\n## No raw SQL\nUse the query builder.\n\n## Logging\nUse slog.\n\n## Logging\nNo fmt.Println.\n"
+
+	rules, status, err := ParsePolicyContent([]byte(doc), "POLICY.md")
+	if err != nil {
+		t.Fatalf("ParsePolicyContent failed: %v", err)
+	}
+	if status != "" {
+		t.Errorf("expected no status without frontmatter, got %q", status)
+	}
+	want := []struct{ id, title, relPath string }{
+		{"no-raw-sql", "No raw SQL", "POLICY.md#no-raw-sql"},
+		{"logging", "Logging", "POLICY.md#logging"},
+		{"logging-2", "Logging", "POLICY.md#logging-2"},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("expected %d rules, got %+v", len(want), rules)
+	}
+	for i, w := range want {
+		r := rules[i]
+		if r.ID != w.id || r.Title != w.title || r.RelPath != w.relPath || r.Status != "Accepted" {
+			t.Errorf("rule %d: got id=%q title=%q rel=%q status=%q", i, r.ID, r.Title, r.RelPath, r.Status)
+		}
+	}
+	if rules[0].Content != "## No raw SQL\nUse the query builder." {
+		t.Errorf("unexpected content %q", rules[0].Content)
+	}
+
+	if _, _, err := ParsePolicyContent([]byte("Just prose."), "POLICY.md"); err == nil {
+		t.Errorf("expected an error for a document without sections")
+	}
+}
+
+func TestPolicyProvider_FrontmatterScopeAndStatus(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("backend.md", "---\nstatus: Accepted\nscope: [\"cmd/**\", \"internal/**\"]\n---\n## Wrap errors\nUse %w.\n")
+	write("draft.md", "---\nstatus: Proposed\n---\n## Use gRPC\nEverywhere.\n")
+
+	rules, err := NewPolicyProvider(tmp, []string{"Accepted"}).GetADRs(context.Background())
+	if err != nil {
+		t.Fatalf("GetADRs failed: %v", err)
+	}
+	if len(rules) != 1 || rules[0].RelPath != "backend.md#wrap-errors" {
+		t.Fatalf("expected only the accepted document's rule, got %+v", rules)
+	}
+	if len(rules[0].Scope) != 2 || rules[0].Scope[1] != "internal/**" {
+		t.Errorf("expected frontmatter scope on the rule, got %v", rules[0].Scope)
+	}
+
+	single, err := NewPolicyProvider(filepath.Join(tmp, "backend.md"), []string{"Accepted"}).GetADRs(context.Background())
+	if err != nil {
+		t.Fatalf("GetADRs on a single file failed: %v", err)
+	}
+	if len(single) != 1 || single[0].RelPath != "backend.md#wrap-errors" {
+		t.Errorf("expected a single-file policy to be read, got %+v", single)
+	}
+}
+
+func TestPolicyProvider_UniqueIDsAcrossDocuments(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("backend.md", "## Logging\nUse slog.\n\n## No raw SQL\nUse the query builder.\n")
+	write("frontend.md", "## Logging\nUse the console wrapper.\n")
+
+	rules, err := NewPolicyProvider(tmp, []string{"Accepted"}).GetADRs(context.Background())
+	if err != nil {
+		t.Fatalf("GetADRs failed: %v", err)
+	}
+	var ids []string
+	for _, r := range rules {
+		ids = append(ids, r.ID+" "+r.RelPath)
+	}
+	want := "logging backend.md#logging,no-raw-sql backend.md#no-raw-sql,frontend-logging frontend.md#logging"
+	if got := strings.Join(ids, ","); got != want {
+		t.Errorf("expected IDs %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,76 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// PreviewEntry is one ADR as indexing would see it. ADR is nil when the file
+// failed to parse. Skipped is empty for an ADR that would be indexed, and
+// otherwise says why it would not be.
+type PreviewEntry struct {
+	Path    string
+	ADR     *ADR
+	Skipped string
+}
+
+// Previewer is implemented by providers that can report the ADRs they skip
+// and why, for index --validate-only. Providers without it are previewed
+// through GetADRs, so only the ADRs they return are listed.
+type Previewer interface {
+	Preview(ctx context.Context) ([]PreviewEntry, error)
+}
+
+// PreviewADRs lists the ADRs p would index and the files it would skip,
+// without embedding anything.
+func PreviewADRs(ctx context.Context, p Provider) ([]PreviewEntry, error) {
+	if previewer, ok := p.(Previewer); ok {
+		return previewer.Preview(ctx)
+	}
+	adrs, err := p.GetADRs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]PreviewEntry, len(adrs))
+	for i := range adrs {
+		entries[i] = PreviewEntry{Path: adrs[i].RelPath, ADR: &adrs[i]}
+	}
+	return entries, nil
+}
+
+// Preview previews every provider, tolerating failures like GetADRs.
+func (c *CompositeProvider) Preview(ctx context.Context) ([]PreviewEntry, error) {
+	results := make([][]PreviewEntry, len(c.providers))
+	errs := make([]error, len(c.providers))
+	var wg sync.WaitGroup
+	for i, p := range c.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = PreviewADRs(ctx, p)
+		}()
+	}
+	wg.Wait()
+
+	var entries []PreviewEntry
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(c.writer(), "Warning: failed to fetch ADRs from a provider: %v\n", err)
+			failed++
+			continue
+		}
+		entries = append(entries, results[i]...)
+	}
+	if len(c.providers) > 0 && failed == len(c.providers) {
+		return nil, fmt.Errorf("all providers failed to fetch ADRs: %v", errs[0])
+	}
+	return entries, nil
+}
+
+// statusSkipped is the PreviewEntry.Skipped reason for an ADR whose status is
+// not accepted.
+func statusSkipped(status string) string {
+	return fmt.Sprintf("status %q is not in analysis.accepted_statuses", status)
+}
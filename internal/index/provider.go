@@ -3,20 +3,16 @@ package index
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 
 	"golang.org/x/sync/errgroup"
 )
 
-// Provider defines how ArchGuard fetches ADR documents.
-type Provider interface {
-	// GetADRs fetches ADRs, returning only those that match the provider's criteria.
-	GetADRs(ctx context.Context) ([]ADR, error)
-}
-
 // CompositeProvider aggregates multiple providers and merges their results.
 type CompositeProvider struct {
 	providers []Provider
+	output
 }
 
 // NewCompositeProvider creates a new CompositeProvider with the given providers.
@@ -26,6 +22,15 @@ func NewCompositeProvider(providers ...Provider) *CompositeProvider {
 	}
 }
 
+// setOutput directs this provider's warnings, and those of every provider it
+// aggregates, to w.
+func (c *CompositeProvider) setOutput(w io.Writer) {
+	c.w = w
+	for _, p := range c.providers {
+		SetOutput(p, w)
+	}
+}
+
 // GetADRs fetches ADRs from all configured providers concurrently and aggregates them into a single slice.
 func (c *CompositeProvider) GetADRs(ctx context.Context) ([]ADR, error) {
 	var allADRs []ADR
@@ -43,7 +48,7 @@ func (c *CompositeProvider) GetADRs(ctx context.Context) ([]ADR, error) {
 
 			if err != nil {
 				// Do not crash the entire run if one remote provider drops connection.
-				fmt.Printf("Warning: failed to fetch ADRs from a provider: %v\n", err)
+				fmt.Fprintf(c.writer(), "Warning: failed to fetch ADRs from a provider: %v\n", err)
 				errs = append(errs, err)
 				return nil
 			}
@@ -60,3 +65,10 @@ func (c *CompositeProvider) GetADRs(ctx context.Context) ([]ADR, error) {
 
 	return allADRs, nil
 }
+
+// warnNoADRs prints a prominent warning when indexing finds no ADRs, since an
+// empty index makes every subsequent check pass trivially.
+func warnNoADRs(w io.Writer) {
+	fmt.Fprintln(w, "WARNING: No valid ADRs were found. The index is empty and 'archguard check' will not detect any violations.")
+	fmt.Fprintln(w, "         Verify that analysis.adr_path points to your ADRs and that their status matches analysis.accepted_statuses.")
+}
@@ -0,0 +1,12 @@
+package index
+
+import pkgindex "github.com/tgenz1213/archguard/pkg/index"
+
+// The store and provider interfaces are defined in pkg/index so that index
+// backends can be implemented and registered outside this module.
+type (
+	VectorStore  = pkgindex.VectorStore
+	Provider     = pkgindex.Provider
+	SearchResult = pkgindex.SearchResult
+	Factory      = pkgindex.Factory
+)
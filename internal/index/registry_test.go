@@ -0,0 +1,38 @@
+package index
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tgenz1213/archguard/internal/config"
+	pkgindex "github.com/tgenz1213/archguard/pkg/index"
+)
+
+func TestRegister_SelectsBackend(t *testing.T) {
+	want := NewLocalStore(1)
+	pkgindex.Register("test-qdrant", func(cfg *config.Config) (VectorStore, error) {
+		return want, nil
+	})
+
+	cfg := &config.Config{}
+	cfg.VectorStore.Backend = "test-qdrant"
+	store, err := NewVectorStore(cfg)
+	if err != nil || store != want {
+		t.Fatalf("expected the registered backend, got %#v, %v", store, err)
+	}
+
+	cfg.VectorStore.Backend = "unknown"
+	if _, err := NewVectorStore(cfg); err == nil || !strings.Contains(err.Error(), "unknown vector_store.backend") {
+		t.Errorf("expected an unknown backend error, got %v", err)
+	}
+	cfg.VectorStore.Backend = "pgvector"
+	if _, err := NewVectorStore(cfg); err == nil || !strings.Contains(err.Error(), "connection_string") {
+		t.Errorf("expected pgvector without a connection string to be rejected, got %v", err)
+	}
+	cfg.VectorStore.Backend = ""
+	if store, err := NewVectorStore(cfg); err != nil {
+		t.Errorf("expected the local index by default, got %v", err)
+	} else if _, ok := store.(*LocalStore); !ok {
+		t.Errorf("expected the local index by default, got %T", store)
+	}
+}
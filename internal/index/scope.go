@@ -0,0 +1,30 @@
+package index
+
+import "github.com/bmatcuk/doublestar/v4"
+
+// UnmatchedScopes returns the ADRs whose scope globs match none of the given
+// files. Such a scope is usually a typo and makes the ADR impossible to trigger.
+func UnmatchedScopes(adrs []ADR, files []string) []ADR {
+	var unmatched []ADR
+	for _, adr := range adrs {
+		if len(adr.Scope) == 0 {
+			continue
+		}
+		found := false
+		for _, f := range files {
+			for _, pattern := range adr.Scope {
+				if ok, _ := doublestar.Match(pattern, f); ok {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, adr)
+		}
+	}
+	return unmatched
+}
@@ -0,0 +1,20 @@
+package index
+
+import "testing"
+
+func TestUnmatchedScopes(t *testing.T) {
+	adrs := []ADR{
+		{ID: "0001", Scope: StringList{"internal/**"}},
+		{ID: "0002", Scope: StringList{"intenral/**"}},
+		{ID: "0003"},
+		{ID: "0004", Scope: StringList{"**/*.go"}},
+		{ID: "0005", Scope: StringList{"cmd/**", "internal/cli/**"}},
+		{ID: "0006", Scope: StringList{"cmd/**", "pkg/**"}},
+	}
+	files := []string{"internal/cli/cli.go", "README.md"}
+
+	got := UnmatchedScopes(adrs, files)
+	if len(got) != 2 || got[0].ID != "0002" || got[1].ID != "0006" {
+		t.Fatalf("expected ADRs 0002 and 0006 to be unmatched, got %+v", got)
+	}
+}
@@ -5,20 +5,25 @@ import (
 	"sort"
 )
 
-// SearchResult represents an ADR matched during a vector search with its similarity score.
-type SearchResult struct {
-	ADR   *ADR
-	Score float64
-}
-
 // Search performs a vector similarity search across the store, returning up to topK results
-// that meet or exceed the specified threshold.
+// that pass the specified threshold under the store's metric (see Metric.Matches),
+// best first. ADRs with section embeddings score the best match over the
+// whole-ADR vector and each section vector.
 func (s *LocalStore) Search(queryEmbedding []float32, threshold float64, topK int) []SearchResult {
 	var results []SearchResult
+	metric := s.metric
+	if metric == "" {
+		metric = MetricCosine
+	}
 
 	for i := range s.ADRs {
-		score := cosineSimilarity(queryEmbedding, s.ADRs[i].Embedding)
-		if score >= threshold {
+		score := metric.Score(queryEmbedding, s.ADRs[i].Embedding)
+		for _, emb := range s.ADRs[i].SectionEmbeddings {
+			if sec := metric.Score(queryEmbedding, emb); metric.Better(sec, score) {
+				score = sec
+			}
+		}
+		if metric.Matches(score, threshold) {
 			results = append(results, SearchResult{
 				ADR:   &s.ADRs[i],
 				Score: score,
@@ -26,8 +31,16 @@ func (s *LocalStore) Search(queryEmbedding []float32, threshold float64, topK in
 		}
 	}
 
+	// Ties are broken by ID, then RelPath, so the topK cut is reproducible.
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return metric.Better(a.Score, b.Score)
+		}
+		if a.ADR.ID != b.ADR.ID {
+			return a.ADR.ID < b.ADR.ID
+		}
+		return a.ADR.RelPath < b.ADR.RelPath
 	})
 
 	if len(results) > topK {
@@ -36,7 +49,9 @@ func (s *LocalStore) Search(queryEmbedding []float32, threshold float64, topK in
 	return results
 }
 
-func cosineSimilarity(a, b []float32) float64 {
+// CosineSimilarity scores two embeddings from -1 to 1; vectors of different
+// lengths score 0.
+func CosineSimilarity(a, b []float32) float64 {
 	if len(a) != len(b) {
 		return 0
 	}
@@ -6,31 +6,33 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/tgenz1213/archguard/internal/config"
 	"github.com/tgenz1213/archguard/internal/llm"
+	pkgindex "github.com/tgenz1213/archguard/pkg/index"
 	"golang.org/x/sync/errgroup"
 )
 
-// VectorStore defines the interface for interacting with the index storage.
-type VectorStore interface {
-	CalculateHash(adrs []ADR, modelName string) (string, error)
-	Load(path, modelName string, dim int, currentHash string) error
-	Save(path string) error
-	BuildIndex(ctx context.Context, modelName string, dim int, provider llm.Provider, adrProvider Provider) error
-	Search(queryEmbedding []float32, threshold float64, topK int) []SearchResult
-}
+// IndexFormatVersion is the version of the local index file layout. Bump it
+// when a change to LocalStore or ADR would make older indexes misread.
+const IndexFormatVersion = 1
 
 // LocalStore manages the persistence and retrieval of ADR embeddings and metadata.
 type LocalStore struct {
-	ADRs        []ADR  `json:"adrs"`
-	Hash        string `json:"hash"`
-	ModelName   string `json:"model_name"`
-	Dim         int    `json:"dim"`
-	concurrency int    `json:"-"`
+	FormatVersion int    `json:"format_version"`
+	ADRs          []ADR  `json:"adrs"`
+	Hash          string `json:"hash"`
+	ModelName     string `json:"model_name"`
+	Dim           int    `json:"dim"`
+	MultiVector   bool   `json:"multi_vector,omitempty"` // Whether the index was built with section embeddings
+	concurrency   int    `json:"-"`
+	multiVector   bool   `json:"-"`
+	metric        Metric `json:"-"`
+	output
 }
 
 // NewLocalStore initializes a new LocalStore instance.
@@ -41,12 +43,49 @@ func NewLocalStore(concurrency int) *LocalStore {
 	}
 }
 
-// NewVectorStore creates the appropriate VectorStore based on the configuration.
+// NewVectorStore creates the VectorStore selected by vector_store.backend:
+// the local JSON index, pgvector, or a backend added with pkg/index.Register.
+// Without a backend, a connection string selects pgvector and the local index
+// is used otherwise.
 func NewVectorStore(cfg *config.Config) (VectorStore, error) {
-	if cfg.VectorStore.ConnectionString != "" {
-		return NewPgStore(cfg.VectorStore.ConnectionString, cfg.ProjectName, cfg.VectorStore.EmbeddingConcurrency)
+	metric, err := ParseMetric(cfg.VectorStore.Metric)
+	if err != nil {
+		return nil, err
+	}
+	backend := cfg.VectorStore.Backend
+	if backend == "" {
+		backend = "local"
+		if cfg.VectorStore.ConnectionString != "" {
+			backend = "pgvector"
+		}
+	}
+	switch backend {
+	case "local":
+	case "pgvector":
+		if cfg.VectorStore.ConnectionString == "" {
+			return nil, fmt.Errorf("vector_store.backend pgvector needs vector_store.connection_string or ARCHGUARD_DB_URL")
+		}
+		if cfg.VectorStore.MultiVector {
+			fmt.Fprintln(os.Stderr, "Warning: vector_store.multi_vector is only supported by the local index; pgvector stores one vector per ADR.")
+		}
+		store, err := NewPgStore(cfg.VectorStore.ConnectionString, cfg.ProjectName, cfg.VectorStore.EmbeddingConcurrency)
+		if err != nil {
+			return nil, err
+		}
+		store.metric = metric
+		return store, nil
+	default:
+		factory, ok := pkgindex.Lookup(backend)
+		if !ok {
+			return nil, fmt.Errorf("unknown vector_store.backend: %s", backend)
+		}
+		return factory(cfg)
 	}
-	return NewLocalStore(cfg.VectorStore.EmbeddingConcurrency), nil
+
+	store := NewLocalStore(cfg.VectorStore.EmbeddingConcurrency)
+	store.multiVector = cfg.VectorStore.MultiVector
+	store.metric = metric
+	return store, nil
 }
 
 // CalculateHash generates a hash of all ADR file contents and the model name
@@ -56,12 +95,133 @@ func (s *LocalStore) CalculateHash(adrs []ADR, modelName string) (string, error)
 	hasher.Write([]byte(modelName))
 
 	for _, adr := range adrs {
-		hasher.Write([]byte(adr.RelPath))
-		hasher.Write([]byte(adr.Content))
+		writeADRHash(hasher, adr)
 	}
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
+// writeADRHash writes the fields of an ADR that invalidate the index: its
+// text and every frontmatter field that decides which files it is checked
+// against or how it is reported.
+func writeADRHash(w io.Writer, adr ADR) {
+	w.Write([]byte(adr.RelPath))
+	w.Write([]byte(adr.Content))
+	w.Write([]byte("id:" + adr.ID + "\x00title:" + adr.Title + "\x00status:" + adr.Status))
+	if len(adr.Scope) > 0 {
+		w.Write([]byte("scope:" + strings.Join(adr.Scope, ",")))
+	}
+	if len(adr.ScopeExclude) > 0 {
+		w.Write([]byte("scope_exclude:" + strings.Join(adr.ScopeExclude, ",")))
+	}
+	if len(adr.Tags) > 0 {
+		w.Write([]byte("tags:" + strings.Join(adr.Tags, ",")))
+	}
+}
+
+func adrHash(adr ADR) string {
+	hasher := sha256.New()
+	writeADRHash(hasher, adr)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// ChangedADRs compares the ADRs in the loaded index with the current ones by
+// per-file hash and describes each ADR added, removed, or changed since the
+// index was built, e.g. "ADR 0007 (0007-use-grpc.md) changed since last index".
+func (s *LocalStore) ChangedADRs(current []ADR) []string {
+	var changes []string
+	for _, c := range s.diffADRs(current) {
+		changes = append(changes, fmt.Sprintf("ADR %s (%s) %s since last index", c.adr.ID, c.adr.RelPath, c.kind))
+	}
+	return changes
+}
+
+// ChangedADRPaths returns the RelPaths of current ADRs that were added or
+// changed relative to the ADRs in s. Removed ADRs are not included, since
+// there is nothing left to check against them.
+func (s *LocalStore) ChangedADRPaths(current []ADR) []string {
+	var paths []string
+	for _, c := range s.diffADRs(current) {
+		if c.kind != "removed" {
+			paths = append(paths, c.adr.RelPath)
+		}
+	}
+	return paths
+}
+
+type adrChange struct {
+	adr  ADR
+	kind string // added, changed, or removed
+}
+
+// diffADRs matches ADRs by RelPath and compares them by per-file hash,
+// listing additions and changes in current order, then removals.
+func (s *LocalStore) diffADRs(current []ADR) []adrChange {
+	saved := make(map[string]string, len(s.ADRs))
+	for _, adr := range s.ADRs {
+		saved[adr.RelPath] = adrHash(adr)
+	}
+
+	var changes []adrChange
+	for _, adr := range current {
+		hash, ok := saved[adr.RelPath]
+		switch {
+		case !ok:
+			changes = append(changes, adrChange{adr, "added"})
+		case hash != adrHash(adr):
+			changes = append(changes, adrChange{adr, "changed"})
+		}
+		delete(saved, adr.RelPath)
+	}
+	for _, adr := range s.ADRs {
+		if _, ok := saved[adr.RelPath]; ok {
+			changes = append(changes, adrChange{adr, "removed"})
+		}
+	}
+	return changes
+}
+
+// PreviousIndexSuffix is appended to the index path for the copy of the index
+// that Save replaced, kept so changes between two indexes can be compared.
+const PreviousIndexSuffix = ".prev"
+
+// ReadLocalIndex reads a saved local index without validating it against the
+// current configuration.
+func ReadLocalIndex(path string) (*LocalStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFormatVersion(data); err != nil {
+		return nil, err
+	}
+	s := NewLocalStore(0)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// checkFormatVersion rejects an index written in a different format before
+// it is decoded, so that a changed layout is reported clearly instead of
+// failing to unmarshal or loading half-filled ADRs.
+func checkFormatVersion(data []byte) error {
+	var header struct {
+		FormatVersion int `json:"format_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+	// Indexes written before the field existed use the first format.
+	version := max(header.FormatVersion, 1)
+	if version > IndexFormatVersion {
+		return fmt.Errorf("index format version %d is too new for this version of ArchGuard (expected %d); upgrade ArchGuard or run `archguard index`", version, IndexFormatVersion)
+	}
+	if version < IndexFormatVersion {
+		return fmt.Errorf("index format version %d is too old (expected %d); run `archguard index`", version, IndexFormatVersion)
+	}
+	return nil
+}
+
 // Load reads the index from disk and validates metadata against the current configuration.
 func (s *LocalStore) Load(path, modelName string, dim int, currentHash string) error {
 	data, err := os.ReadFile(path)
@@ -72,11 +232,14 @@ func (s *LocalStore) Load(path, modelName string, dim int, currentHash string) e
 		return err
 	}
 
+	if err := checkFormatVersion(data); err != nil {
+		return err
+	}
 	if err := json.Unmarshal(data, s); err != nil {
 		return err
 	}
 
-	if s.ModelName != modelName || s.Dim != dim || s.Hash != currentHash {
+	if s.ModelName != modelName || s.Dim != dim || s.Hash != currentHash || s.MultiVector != s.multiVector {
 		var reasons []string
 		if s.ModelName != modelName {
 			reasons = append(reasons, fmt.Sprintf("Model mismatch (Saved: %q, Config: %q)", s.ModelName, modelName))
@@ -84,6 +247,9 @@ func (s *LocalStore) Load(path, modelName string, dim int, currentHash string) e
 		if s.Dim != dim {
 			reasons = append(reasons, fmt.Sprintf("Dimension mismatch (Saved: %d, Config: %d)", s.Dim, dim))
 		}
+		if s.MultiVector != s.multiVector {
+			reasons = append(reasons, fmt.Sprintf("Multi-vector mismatch (Saved: %t, Config: %t)", s.MultiVector, s.multiVector))
+		}
 		if s.Hash != currentHash {
 			reasons = append(reasons, fmt.Sprintf("Hash mismatch\n    Saved:   %s\n    Current: %s", s.Hash, currentHash))
 		}
@@ -100,6 +266,7 @@ func (s *LocalStore) Save(path string) error {
 		return err
 	}
 
+	s.FormatVersion = IndexFormatVersion
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
 		return err
@@ -110,6 +277,13 @@ func (s *LocalStore) Save(path string) error {
 		return err
 	}
 
+	// Keep the index being replaced when its ADRs differ, for --only-changed-adrs.
+	if old, err := ReadLocalIndex(path); err == nil && old.Hash != s.Hash {
+		if err := os.Rename(path, path+PreviousIndexSuffix); err != nil {
+			return err
+		}
+	}
+
 	return os.Rename(tmpPath, path)
 }
 
@@ -129,14 +303,21 @@ func (s *LocalStore) BuildIndex(ctx context.Context, modelName string, dim int,
 	var adrsToEmbed []int
 	for i, valid := range validADRs {
 		existing, ok := existingMap[valid.RelPath]
-		if ok && existing.Content == valid.Content && existing.Title == valid.Title && existing.Status == valid.Status {
+		if ok && existing.Content == valid.Content && existing.Title == valid.Title && existing.Status == valid.Status &&
+			(!s.multiVector || len(existing.SectionEmbeddings) == len(Sections(valid.Content))) {
 			validADRs[i].Embedding = existing.Embedding
+			if s.multiVector {
+				validADRs[i].SectionEmbeddings = existing.SectionEmbeddings
+			}
 		} else {
 			adrsToEmbed = append(adrsToEmbed, i)
 		}
 	}
 
-	fmt.Printf("Found %d valid ADRs. Generating embeddings for %d new/modified ADRs...\n", len(validADRs), len(adrsToEmbed))
+	fmt.Fprintf(s.writer(), "Found %d valid ADRs. Generating embeddings for %d new/modified ADRs...\n", len(validADRs), len(adrsToEmbed))
+	if len(validADRs) == 0 {
+		warnNoADRs(s.writer())
+	}
 
 	if len(adrsToEmbed) > 0 {
 		concurrency := s.concurrency
@@ -156,7 +337,19 @@ func (s *LocalStore) BuildIndex(ctx context.Context, modelName string, dim int,
 					return fmt.Errorf("failed to embed ADR %s: %w", validADRs[idx].RelPath, err)
 				}
 				validADRs[idx].Embedding = emb
-				fmt.Printf(".")
+
+				if s.multiVector {
+					var sectionEmbs [][]float32
+					for _, section := range Sections(validADRs[idx].Content) {
+						emb, err := provider.CreateEmbedding(gCtx, fmt.Sprintf("Title: %s\n%s", validADRs[idx].Title, section))
+						if err != nil {
+							return fmt.Errorf("failed to embed section of ADR %s: %w", validADRs[idx].RelPath, err)
+						}
+						sectionEmbs = append(sectionEmbs, emb)
+					}
+					validADRs[idx].SectionEmbeddings = sectionEmbs
+				}
+				fmt.Fprintf(s.writer(), ".")
 				return nil
 			})
 		}
@@ -164,15 +357,30 @@ func (s *LocalStore) BuildIndex(ctx context.Context, modelName string, dim int,
 		if err := g.Wait(); err != nil {
 			return err
 		}
-		fmt.Println()
+		fmt.Fprintln(s.writer())
+	}
+
+	if dim <= 0 && len(validADRs) > 0 {
+		dim = len(validADRs[0].Embedding)
+	}
+	// A provider that returns a truncated vector would otherwise leave the ADR
+	// unreachable, since CosineSimilarity scores mismatched lengths as 0.
+	for _, a := range validADRs {
+		if len(a.Embedding) != dim {
+			return fmt.Errorf("embedding for ADR %s has dimension %d, expected %d", a.RelPath, len(a.Embedding), dim)
+		}
+		for _, emb := range a.SectionEmbeddings {
+			if len(emb) != dim {
+				return fmt.Errorf("section embedding for ADR %s has dimension %d, expected %d", a.RelPath, len(emb), dim)
+			}
+		}
 	}
 
 	s.ADRs = validADRs
 	s.ModelName = modelName
+	s.MultiVector = s.multiVector
 	if dim > 0 {
 		s.Dim = dim
-	} else if len(validADRs) > 0 && len(validADRs[0].Embedding) > 0 {
-		s.Dim = len(validADRs[0].Embedding)
 	}
 
 	hash, err := s.CalculateHash(validADRs, modelName)
@@ -126,3 +126,315 @@ func TestLocalStore_BuildIndex_ReturnsErrorOnEmbedFailure(t *testing.T) {
 		t.Errorf("expected error to reference failing ADR path, got: %v", err)
 	}
 }
+
+func TestLocalStore_BuildIndex_RejectsMismatchedDimensions(t *testing.T) {
+	adrs := []ADR{
+		{RelPath: "0001-a.md", Title: "A", Status: "Accepted", Content: "content a"},
+		{RelPath: "0002-short.md", Title: "B", Status: "Accepted", Content: "content b"},
+	}
+	provider := &llm.MockProvider{
+		EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			if strings.Contains(text, "Title: B") {
+				return []float32{0.1}, nil
+			}
+			return []float32{0.1, 0.2, 0.3}, nil
+		},
+	}
+
+	store := NewLocalStore(1)
+	err := store.BuildIndex(context.Background(), "mock-model", 0, provider, &mockADRProvider{adrs: adrs})
+	if err == nil {
+		t.Fatal("expected dimension mismatch error, got nil")
+	}
+	if !strings.Contains(err.Error(), "0002-short.md") {
+		t.Errorf("expected error to reference mismatched ADR path, got: %v", err)
+	}
+	if len(store.ADRs) != 0 {
+		t.Errorf("expected store to be left unchanged, got %d ADRs", len(store.ADRs))
+	}
+}
+
+func TestLocalStore_ChangedADRs(t *testing.T) {
+	store := NewLocalStore(1)
+	store.ADRs = []ADR{
+		{ID: "0001", RelPath: "0001-use-go.md", Content: "Use Go."},
+		{ID: "0007", RelPath: "0007-use-grpc.md", Content: "Use gRPC."},
+		{ID: "0008", RelPath: "0008-drop-orm.md", Content: "No ORM."},
+	}
+	current := []ADR{
+		{ID: "0001", RelPath: "0001-use-go.md", Content: "Use Go."},
+		{ID: "0007", RelPath: "0007-use-grpc.md", Content: "Use gRPC for internal services."},
+		{ID: "0009", RelPath: "0009-use-otel.md", Content: "Use OpenTelemetry."},
+	}
+
+	got := store.ChangedADRs(current)
+	want := []string{
+		"ADR 0007 (0007-use-grpc.md) changed since last index",
+		"ADR 0009 (0009-use-otel.md) added since last index",
+		"ADR 0008 (0008-drop-orm.md) removed since last index",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected changes:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestLocalStore_CalculateHashCoversFrontmatter(t *testing.T) {
+	base := ADR{ID: "0007", Title: "Use gRPC", Status: "Accepted", RelPath: "0007-use-grpc.md", Content: "Use gRPC."}
+	store := NewLocalStore(1)
+	hash := func(adr ADR) string {
+		t.Helper()
+		h, err := store.CalculateHash([]ADR{adr}, "model")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	edits := map[string]func(*ADR){
+		"id":            func(a *ADR) { a.ID = "0008" },
+		"title":         func(a *ADR) { a.Title = "Use gRPC internally" },
+		"status":        func(a *ADR) { a.Status = "Deprecated" },
+		"scope":         func(a *ADR) { a.Scope = StringList{"services/**"} },
+		"scope_exclude": func(a *ADR) { a.ScopeExclude = []string{"services/legacy/**"} },
+		"tags":          func(a *ADR) { a.Tags = []string{"api"} },
+	}
+	for field, edit := range edits {
+		edited := base
+		edit(&edited)
+		if hash(edited) == hash(base) {
+			t.Errorf("editing %s did not change the index hash", field)
+		}
+	}
+}
+
+func TestLocalStore_SaveKeepsPreviousIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	save := func(hash string, adrs ...ADR) {
+		t.Helper()
+		store := NewLocalStore(1)
+		store.Hash = hash
+		store.ADRs = adrs
+		if err := store.Save(path); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	grpc := ADR{ID: "0007", RelPath: "0007-use-grpc.md", Content: "Use gRPC."}
+	save("v1", grpc)
+	save("v1", grpc)
+	if _, err := os.Stat(path + PreviousIndexSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected no previous index when nothing changed, stat err = %v", err)
+	}
+
+	edited := ADR{ID: "0007", RelPath: "0007-use-grpc.md", Content: "Use gRPC for internal services."}
+	otel := ADR{ID: "0009", RelPath: "0009-use-otel.md", Content: "Use OpenTelemetry."}
+	save("v2", edited, otel)
+
+	prev, err := ReadLocalIndex(path + PreviousIndexSuffix)
+	if err != nil {
+		t.Fatalf("expected previous index: %v", err)
+	}
+	if prev.Hash != "v1" {
+		t.Errorf("expected previous index hash v1, got %q", prev.Hash)
+	}
+	got := prev.ChangedADRPaths([]ADR{edited, otel})
+	if strings.Join(got, ",") != "0007-use-grpc.md,0009-use-otel.md" {
+		t.Errorf("unexpected changed ADRs: %v", got)
+	}
+}
+
+func TestLocalStore_ScopeOnlyEditIsChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	save := func(adrs ...ADR) {
+		t.Helper()
+		store := NewLocalStore(1)
+		hash, err := store.CalculateHash(adrs, "model")
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Hash = hash
+		store.ADRs = adrs
+		if err := store.Save(path); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	grpc := ADR{ID: "0007", RelPath: "0007-use-grpc.md", Content: "Use gRPC.", Scope: StringList{"services/**"}}
+	otel := ADR{ID: "0009", RelPath: "0009-use-otel.md", Content: "Use OpenTelemetry."}
+	save(grpc, otel)
+	rescoped := grpc
+	rescoped.Scope = StringList{"services/**", "cmd/**"}
+	save(rescoped, otel)
+
+	prev, err := ReadLocalIndex(path + PreviousIndexSuffix)
+	if err != nil {
+		t.Fatalf("expected a previous index after a scope-only edit: %v", err)
+	}
+	got := prev.ChangedADRPaths([]ADR{rescoped, otel})
+	if strings.Join(got, ",") != "0007-use-grpc.md" {
+		t.Errorf("expected only the rescoped ADR to be changed, got %v", got)
+	}
+}
+
+func TestLocalStore_LoadChecksFormatVersion(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	saved := filepath.Join(dir, "saved.json")
+	store := NewLocalStore(1)
+	store.Hash, store.ModelName, store.Dim = "h", "m", 2
+	if err := store.Save(saved); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"saved by this version", saved, ""},
+		{"written before versioning", write("legacy.json", `{"adrs": [], "hash": "h", "model_name": "m", "dim": 2}`), ""},
+		{"too new", write("new.json", `{"format_version": 99, "adrs": {"changed": "layout"}}`), "too new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewLocalStore(1).Load(tt.path, "m", 2, "h")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), "archguard index") {
+				t.Errorf("expected a %q error naming archguard index, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLocalStore_MultiVectorSearchUsesBestSection(t *testing.T) {
+	adrs := []ADR{{
+		RelPath: "0001-a.md", Title: "A", Status: "Accepted",
+		Content: "# A\n\n## Context\nWe talk to many services.\n\n## Decision\nUse gRPC for internal calls.\n",
+	}}
+	provider := &llm.MockProvider{
+		EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			if strings.HasPrefix(text, "Title: A\n## Decision") {
+				return []float32{0, 1}, nil
+			}
+			return []float32{1, 0}, nil
+		},
+	}
+	query := []float32{0, 1}
+
+	single := NewLocalStore(1)
+	if err := single.BuildIndex(context.Background(), "mock-model", 2, provider, &mockADRProvider{adrs: adrs}); err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if got := single.Search(query, 0.9, 5); len(got) != 0 {
+		t.Fatalf("expected no match from the whole-ADR vector, got %d", len(got))
+	}
+
+	multi := NewLocalStore(1)
+	multi.multiVector = true
+	if err := multi.BuildIndex(context.Background(), "mock-model", 2, provider, &mockADRProvider{adrs: adrs}); err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if n := len(multi.ADRs[0].SectionEmbeddings); n != 2 {
+		t.Fatalf("expected 2 section embeddings, got %d", n)
+	}
+	got := multi.Search(query, 0.9, 5)
+	if len(got) != 1 || got[0].Score < 0.99 {
+		t.Fatalf("expected the Decision section to match, got %+v", got)
+	}
+}
+
+func TestLocalStore_SearchWithHashEmbeddings(t *testing.T) {
+	provider := &llm.MockProvider{EmbeddingDim: 64, HashEmbeddings: true}
+	ctx := context.Background()
+
+	store := NewLocalStore(5)
+	for i, content := range []string{"Use gRPC for services.", "Frontend in TypeScript.", "No raw SQL."} {
+		emb, err := provider.CreateEmbedding(ctx, content)
+		if err != nil {
+			t.Fatalf("CreateEmbedding failed: %v", err)
+		}
+		store.ADRs = append(store.ADRs, ADR{ID: fmt.Sprintf("%04d", i+1), Content: content, Embedding: emb})
+	}
+
+	query, err := provider.CreateEmbedding(ctx, "No raw SQL.")
+	if err != nil {
+		t.Fatalf("CreateEmbedding failed: %v", err)
+	}
+	results := store.Search(query, 0.9, 3)
+	if len(results) != 1 || results[0].ADR.ID != "0003" {
+		t.Fatalf("expected only ADR 0003 above the threshold, got %+v", results)
+	}
+
+	if got := store.Search(query, -1, 2); len(got) != 2 || got[0].ADR.ID != "0003" {
+		t.Errorf("expected top-2 results led by ADR 0003, got %+v", got)
+	}
+}
+
+func TestLocalStore_SearchBreaksTiesByIDThenPath(t *testing.T) {
+	emb := []float32{1, 0}
+	store := NewLocalStore(1)
+	store.ADRs = []ADR{
+		{ID: "0003", RelPath: "0003-c.md", Embedding: emb},
+		{ID: "0001", RelPath: "b/0001-a.md", Embedding: emb},
+		{ID: "0002", RelPath: "0002-b.md", Embedding: emb},
+		{ID: "0001", RelPath: "a/0001-a.md", Embedding: emb},
+	}
+
+	got := store.Search(emb, 0.5, 3)
+	var paths []string
+	for _, r := range got {
+		paths = append(paths, r.ADR.RelPath)
+	}
+	want := "a/0001-a.md,b/0001-a.md,0002-b.md"
+	if strings.Join(paths, ",") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(paths, ","))
+	}
+}
+
+func TestLocalStore_SearchMetrics(t *testing.T) {
+	// Against the query, "long" points the same way but is three times as
+	// long; "near" is slightly off-axis but close in space.
+	store := NewLocalStore(1)
+	store.ADRs = []ADR{
+		{ID: "long", Embedding: []float32{3, 0}},
+		{ID: "near", Embedding: []float32{0.9, 0.1}},
+	}
+	query := []float32{1, 0}
+
+	tests := []struct {
+		metric    Metric
+		threshold float64
+		want      string
+	}{
+		{MetricCosine, 0.5, "long,near"},
+		{MetricDot, 1, "long"},
+		{MetricEuclidean, 0.5, "near"},
+		{MetricEuclidean, MetricEuclidean.NoThreshold(), "near,long"},
+	}
+	for _, tt := range tests {
+		store.metric = tt.metric
+		var ids []string
+		for _, r := range store.Search(query, tt.threshold, 5) {
+			ids = append(ids, r.ADR.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%s with threshold %v: expected %s, got %s", tt.metric, tt.threshold, tt.want, got)
+		}
+	}
+
+	if _, err := ParseMetric("manhattan"); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ConflictResult is the model's verdict on whether two ADRs contradict each other.
+type ConflictResult struct {
+	Conflict  bool   `json:"conflict"`
+	Reasoning string `json:"reasoning"`
+	RuleA     string `json:"rule_a,omitempty"` // Conflicting sentence from the first ADR
+	RuleB     string `json:"rule_b,omitempty"` // Conflicting sentence from the second ADR
+}
+
+// conflictResultSchema describes ConflictResult for structured outputs; see
+// analysisResultSchema.
+var conflictResultSchema = jsonSchema{
+	Name: "conflict_result",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"conflict":  map[string]interface{}{"type": "boolean"},
+			"reasoning": map[string]interface{}{"type": "string"},
+			"rule_a":    map[string]interface{}{"type": "string"},
+			"rule_b":    map[string]interface{}{"type": "string"},
+		},
+		"required":             []string{"conflict", "reasoning", "rule_a", "rule_b"},
+		"additionalProperties": false,
+	},
+}
+
+// ConflictSystemPrompt asks for contradictions between two decisions, not overlap.
+const ConflictSystemPrompt = `You are a careful reviewer of an Architecture Decision Record (ADR) log.
+Your ONLY task is to decide whether two ADRs contain Decisions that cannot both be followed.
+
+CRITICAL GUIDELINES:
+1. OVERLAP IS NOT A CONFLICT: Two ADRs about the same topic that agree, or where one refines the other, do not conflict.
+2. SUPERSEDED IS NOT A CONFLICT: If one ADR explicitly replaces or amends the other, it is not a conflict.
+3. LITERAL RULES ONLY: Report a conflict only when following a sentence of one Decision would break a sentence of the other.
+4. FALSE BY DEFAULT: If you cannot quote two contradicting sentences, "conflict" MUST be false.`
+
+const ConflictPrompt = `### INPUT DATA
+<adr_a title=%q>
+%s
+</adr_a>
+
+<adr_b title=%q>
+%s
+</adr_b>
+
+### TASK
+Do the Decisions of adr_a and adr_b contradict each other?
+
+### OUTPUT FORMAT (JSON ONLY)
+{
+  "conflict": bool,
+  "reasoning": "Single sentence explaining the contradiction, or why there is none.",
+  "rule_a": "The conflicting sentence from adr_a, copied verbatim.",
+  "rule_b": "The conflicting sentence from adr_b, copied verbatim."
+}`
+
+// GetConflictPrompt formats ConflictPrompt, neutralising delimiters in the
+// ADR contents.
+func GetConflictPrompt(titleA, contentA, titleB, contentB string) string {
+	escape := func(s string) string {
+		s = strings.ReplaceAll(s, "</adr_a>", "[ADR_END]")
+		return strings.ReplaceAll(EscapePromptDelimiter(s), "</adr_b>", "[ADR_END]")
+	}
+	return fmt.Sprintf(ConflictPrompt, titleA, escape(contentA), titleB, escape(contentB))
+}
+
+// CheckConflict asks the model whether two ADRs contradict each other,
+// retrying like AnalyzeDriftWithOptions.
+func CheckConflict(ctx context.Context, p Provider, titleA, contentA, titleB, contentB string, opts RetryOptions) (*ConflictResult, error) {
+	prompt := GetConflictPrompt(titleA, contentA, titleB, contentB)
+	ctx = withResponseSchema(ctx, conflictResultSchema)
+
+	var final ConflictResult
+	err := chatWithRetry(ctx, p, ConflictSystemPrompt, prompt, opts, func(raw string) error {
+		var res ConflictResult
+		if err := json.Unmarshal([]byte(CleanJSON(raw)), &res); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+		}
+		final = res
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &final, nil
+}
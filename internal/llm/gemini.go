@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"google.golang.org/genai"
 )
@@ -25,10 +27,15 @@ func NewGeminiProvider(apiKey, model, embedModel string) *GeminiProvider {
 		model:      model,
 		embedModel: embedModel,
 		baseURL:    "https://generativelanguage.googleapis.com",
-		client:     &http.Client{},
+		client:     sharedClient(),
 	}
 }
 
+// SetHeaders adds headers to every request the provider sends.
+func (p *GeminiProvider) SetHeaders(headers map[string]string) {
+	p.client = withHeaders(p.client, headers)
+}
+
 // errorCapturingTransport wraps an http.RoundTripper and remembers the
 // status line and raw body of the most recent non-2xx response it saw. The
 // genai SDK's own error type (genai.APIError) discards the HTTP status text
@@ -38,9 +45,11 @@ func NewGeminiProvider(apiKey, model, embedModel string) *GeminiProvider {
 // contract: callers get both the HTTP status and whatever error detail the
 // server sent, structured or not.
 type errorCapturingTransport struct {
-	base       http.RoundTripper
-	lastStatus string
-	lastBody   []byte
+	base           http.RoundTripper
+	lastStatus     string
+	lastStatusCode int
+	lastRetryAfter string
+	lastBody       []byte
 }
 
 func (t *errorCapturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -54,6 +63,8 @@ func (t *errorCapturingTransport) RoundTrip(req *http.Request) (*http.Response,
 		_ = resp.Body.Close()
 		if readErr == nil {
 			t.lastStatus = resp.Status
+			t.lastStatusCode = resp.StatusCode
+			t.lastRetryAfter = resp.Header.Get("Retry-After")
 			t.lastBody = body
 		}
 		// Restore the body so the genai SDK can still read and report on it.
@@ -104,7 +115,15 @@ func (p *GeminiProvider) newClient(ctx context.Context) (*genai.Client, *errorCa
 // otherwise fall back to the raw body.
 func (p *GeminiProvider) apiError(err error, transport *errorCapturingTransport) error {
 	if transport != nil && transport.lastStatus != "" {
-		return buildAPIError(transport.lastStatus, transport.lastBody)
+		apiErr := buildAPIError(transport.lastStatus, transport.lastBody)
+		if transport.lastStatusCode == http.StatusTooManyRequests {
+			retryAfter := ParseRetryAfter(transport.lastRetryAfter)
+			if retryAfter == 0 {
+				retryAfter = parseGeminiRetryDelay(transport.lastBody)
+			}
+			return &RateLimitError{RetryAfter: retryAfter, Err: apiErr}
+		}
+		return classifyStatus(transport.lastStatusCode, apiErr)
 	}
 	return fmt.Errorf("gemini api error: %w", err)
 }
@@ -121,6 +140,31 @@ func buildAPIError(status string, body []byte) error {
 	return fmt.Errorf("gemini api error (%s): %s", status, errRes.Error.Message)
 }
 
+// parseGeminiRetryDelay extracts the structured google.rpc.RetryInfo hint
+// (e.g. "retryDelay": "30s") that Gemini includes in 429 error details.
+func parseGeminiRetryDelay(body []byte) time.Duration {
+	var errRes struct {
+		Error struct {
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errRes); err != nil {
+		return 0
+	}
+	for _, d := range errRes.Error.Details {
+		if !strings.HasSuffix(d.Type, "google.rpc.RetryInfo") || d.RetryDelay == "" {
+			continue
+		}
+		if delay, err := time.ParseDuration(d.RetryDelay); err == nil && delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
 func (p *GeminiProvider) Chat(ctx context.Context, system, user string) (string, error) {
 	client, transport, err := p.newClient(ctx)
 	if err != nil {
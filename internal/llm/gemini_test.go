@@ -3,10 +3,12 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGeminiProvider_Chat(t *testing.T) {
@@ -353,3 +355,34 @@ func TestGeminiProvider_ErrorHandling_EmptyErrorMessage(t *testing.T) {
 		t.Errorf("Expected error to contain status code, got: %s", errMsg)
 	}
 }
+
+func TestGeminiProvider_ErrorHandling_RateLimitRetryInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, errw := w.Write([]byte(`{"error": {"message": "Quota exceeded", "details": [{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "12s"}]}}`))
+		if errw != nil {
+			t.Fatalf("Failed to write response: %v", errw)
+		}
+	}))
+	defer server.Close()
+
+	p := &GeminiProvider{
+		apiKey:  "test-api-key",
+		model:   "gemini-1.5-flash",
+		baseURL: server.URL,
+		client:  server.Client(),
+	}
+
+	_, err := p.Chat(context.Background(), "system prompt", "user prompt")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected RateLimitError, got: %v", err)
+	}
+	if rateLimitErr.RetryAfter != 12*time.Second {
+		t.Errorf("Expected RetryAfter of 12s, got %v", rateLimitErr.RetryAfter)
+	}
+	if !strings.Contains(err.Error(), "Quota exceeded") {
+		t.Errorf("Expected error to contain server message, got: %s", err.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package llm
+
+import "net/http"
+
+// HeaderSetter is implemented by providers that can add fixed HTTP headers
+// to every request, such as the tenant or routing headers an LLM gateway
+// requires (llm.headers).
+type HeaderSetter interface {
+	SetHeaders(headers map[string]string)
+}
+
+// headerTransport sets fixed headers on each request before sending it.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withHeaders returns a copy of client whose requests carry headers. A nil
+// client stands for http.DefaultClient.
+func withHeaders(client *http.Client, headers map[string]string) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(headers) == 0 {
+		return client
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = &headerTransport{base: base, headers: headers}
+	return &wrapped
+}
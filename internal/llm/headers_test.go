@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetHeaders_AppliedToEveryProvider(t *testing.T) {
+	headers := map[string]string{"X-Tenant-ID": "team-a", "X-Request-Source": "archguard"}
+	responses := map[string]string{
+		"openai": `{"data":[{"embedding":[0.1]}]}`,
+		"ollama": `{"embedding":[0.1]}`,
+		"gemini": `{"embeddings":[{"values":[0.1]}]}`,
+	}
+
+	for name, body := range responses {
+		t.Run(name, func(t *testing.T) {
+			var got http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			var p interface {
+				Provider
+				HeaderSetter
+			}
+			switch name {
+			case "openai":
+				p = NewOpenAIProviderWithBaseURL("key", "gpt-4o-mini", "text-embedding-3-small", server.URL, server.Client())
+			case "ollama":
+				p = NewOllamaProviderWithBaseURL(server.URL, "llama3.2", "nomic-embed-text", 0)
+			case "gemini":
+				p = &GeminiProvider{apiKey: "key", embedModel: "text-embedding-004", baseURL: server.URL, client: server.Client()}
+			}
+			p.SetHeaders(headers)
+
+			if _, err := p.CreateEmbedding(context.Background(), "text"); err != nil {
+				t.Fatalf("CreateEmbedding failed: %v", err)
+			}
+			for k, v := range headers {
+				if got.Get(k) != v {
+					t.Errorf("expected header %s=%q, got %q", k, v, got.Get(k))
+				}
+			}
+		})
+	}
+}
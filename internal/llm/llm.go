@@ -3,11 +3,15 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	pkgllm "github.com/tgenz1213/archguard/pkg/llm"
 )
 
 /**
@@ -15,14 +19,104 @@ import (
  */
 
 type AnalysisResult struct {
-	Violation  bool   `json:"violation"`
-	Reasoning  string `json:"reasoning"`
-	QuotedCode string `json:"quoted_code"`
+	Violation    bool   `json:"violation"`
+	Reasoning    string `json:"reasoning"`
+	QuotedCode   string `json:"quoted_code"`
+	ViolatedRule string `json:"violated_rule,omitempty"` // Exact ADR sentence the code breaks
+	Raw          string `json:"raw,omitempty"`           // Unmodified provider response, kept for auditing
+	// NeedsFullContext is set when the model could not decide from a diff alone.
+	NeedsFullContext bool `json:"needs_full_context,omitempty"`
 }
 
-type Provider interface {
-	CreateEmbedding(ctx context.Context, text string) ([]float32, error)
-	Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+// Provider is defined in pkg/llm so that providers can be implemented outside
+// this module.
+type Provider = pkgllm.Provider
+
+// Provider failure categories. Providers wrap HTTP errors so callers can
+// match them with errors.Is; AnalyzeDrift retries rate-limit and server
+// errors but fails fast on auth and bad-request errors.
+var (
+	ErrAuth       = errors.New("authentication failed (check ARCHGUARD_API_KEY or llm.api_key)")
+	ErrRateLimit  = errors.New("rate limited")
+	ErrServer     = errors.New("provider server error")
+	ErrBadRequest = errors.New("bad request (check llm.model and vector_store.model)")
+)
+
+// Response failures: the provider answered, but not with a usable verdict.
+// Both are retried unless RetryOptions.NoParseRetry is set.
+var (
+	ErrEmptyResponse = errors.New("provider returned an empty response")
+	ErrInvalidJSON   = errors.New("invalid json from provider")
+)
+
+// IsParseFailure reports whether err means the provider's response could not
+// be read as an AnalysisResult, as opposed to the request itself failing.
+func IsParseFailure(err error) bool {
+	return errors.Is(err, ErrEmptyResponse) || errors.Is(err, ErrInvalidJSON)
+}
+
+// classifyStatus wraps err with the failure category for an HTTP status code.
+// Errors for unrecognized or successful statuses are returned unchanged.
+func classifyStatus(code int, err error) error {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return fmt.Errorf("%w: %w", ErrAuth, err)
+	case code == http.StatusTooManyRequests:
+		return &RateLimitError{Err: err}
+	case code == http.StatusRequestTimeout || code >= 500:
+		return fmt.Errorf("%w: %w", ErrServer, err)
+	case code >= 400:
+		return fmt.Errorf("%w: %w", ErrBadRequest, err)
+	}
+	return err
+}
+
+// isPermanent reports whether retrying err cannot succeed.
+func isPermanent(err error) bool {
+	return errors.Is(err, ErrAuth) || errors.Is(err, ErrBadRequest)
+}
+
+// RateLimitError reports that a provider rejected a request due to rate
+// limiting. RetryAfter carries the server's retry hint, or zero if none was sent.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited (retry after %s): %v", e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("rate limited: %v", e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimit
+}
+
+// ParseRetryAfter interprets a Retry-After header value, which is either a
+// number of seconds or an HTTP date. It returns zero if the value is absent or invalid.
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
 }
 
 /**
@@ -38,6 +132,50 @@ CRITICAL GUIDELINES:
 3. NO STYLE NITS: Do not flag unidiomatic code unless the ADR explicitly forbids it.
 4. FALSE BY DEFAULT: If you cannot find a clear, literal contradiction, "violation" MUST be false.`
 
+// LenientSystemPrompt only reports unmistakable contradictions, trading recall for minimal noise.
+const LenientSystemPrompt = `You are a literal-minded Architectural Compliance Auditor.
+Your ONLY task is to identify direct contradictions between the provided Code and the mandatory 'Decision' section of the ADR.
+
+CRITICAL GUIDELINES:
+1. COMPLIANCE IS NOT A VIOLATION: If the code follows the rule (e.g. ADR says "Use Go" and code is Go), it is NOT a violation.
+2. NO INFERENCE: Do not assume "intent." If the ADR says "Use Go" and the code is Go, it is a PASS.
+3. NO STYLE NITS: Do not flag unidiomatic code unless the ADR explicitly forbids it.
+4. EXPLICIT RULES ONLY: Ignore recommendations ("should", "prefer", "consider"); only "must"/"must not" style rules can be violated.
+5. FALSE BY DEFAULT: Unless the quoted code alone proves the contradiction beyond doubt, "violation" MUST be false.`
+
+// StrictSystemPrompt drops the no-inference rule so that code contradicting the
+// intent of a Decision is flagged even when no sentence is broken word for word.
+const StrictSystemPrompt = `You are a rigorous Architectural Compliance Auditor.
+Your task is to identify code that contradicts the 'Decision' section of the ADR, either literally or in intent.
+
+CRITICAL GUIDELINES:
+1. COMPLIANCE IS NOT A VIOLATION: If the code follows the rule (e.g. ADR says "Use Go" and code is Go), it is NOT a violation.
+2. INTENT COUNTS: Flag code that works around or undermines the purpose of a Decision, even if it avoids the exact wording (e.g. ADR says "All data access goes through the repository layer" and a handler builds raw SQL).
+3. NO STYLE NITS: Do not flag unidiomatic code unless the ADR explicitly forbids it.
+4. BE SPECIFIC: Only report a violation you can tie to a concrete Decision sentence and a concrete code snippet.`
+
+// Strictness presets for analysis.strictness.
+const (
+	StrictnessLenient  = "lenient"
+	StrictnessBalanced = "balanced"
+	StrictnessStrict   = "strict"
+)
+
+// SystemPromptForStrictness returns the built-in system prompt for a strictness
+// preset. An empty level selects balanced, which is DefaultSystemPrompt.
+func SystemPromptForStrictness(level string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", StrictnessBalanced:
+		return DefaultSystemPrompt, nil
+	case StrictnessLenient:
+		return LenientSystemPrompt, nil
+	case StrictnessStrict:
+		return StrictSystemPrompt, nil
+	default:
+		return "", fmt.Errorf("invalid analysis.strictness %q (expected lenient, balanced, or strict)", level)
+	}
+}
+
 const ChatPrompt = `### INPUT DATA
 File Path: %s
 
@@ -56,12 +194,15 @@ Does the code_context literally violate the 'Decision' section of the ADR?
 1. Identify the literal requirement in the ADR.
 2. Identify the actual implementation in the code_context.
 3. If they match or don't explicitly contradict, violation is false.
+4. Set needs_full_context to true only if code_context is a partial diff and the verdict depends on code not shown.
 
 ### OUTPUT FORMAT (JSON ONLY)
 {
   "violation": bool,
   "reasoning": "Single sentence explaining the contradiction.",
-  "quoted_code": "The snippet breaking the rule."
+  "quoted_code": "The snippet breaking the rule.",
+  "violated_rule": "The exact sentence from the ADR's Decision section that is broken, copied verbatim.",
+  "needs_full_context": bool
 }`
 
 // EscapePromptDelimiter prevents prompt injection by neutralising common LLM delimiters.
@@ -80,49 +221,162 @@ func GetAnalyzeDriftPrompt(adrContent, codeContext, filename string) string {
 	return fmt.Sprintf(ChatPrompt, filename, safeADR, safeCode)
 }
 
-func AnalyzeDrift(ctx context.Context, p Provider, adrContent, codeContext, filename, systemPrompt string) (*AnalysisResult, error) {
-	prompt := GetAnalyzeDriftPrompt(adrContent, codeContext, filename)
+/**
+ * REGION: Retry Policy
+ */
 
-	const maxRetries = 3
+// RetryOptions controls how AnalyzeDrift backs off between failed attempts.
+type RetryOptions struct {
+	MaxRetries      int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Jitter          float64 // randomization factor, e.g. 0.25 for ±25%
+	NoParseRetry    bool    // return empty or unparseable responses at once instead of retrying
+}
+
+// DefaultRetryOptions returns the retry policy used when none is configured.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries:      3,
+		InitialInterval: 2 * time.Second,
+		MaxInterval:     30 * time.Second,
+		Jitter:          0.25,
+	}
+}
 
+// newBackOff builds a jittered exponential backoff so concurrent callers that
+// fail together do not retry in lockstep.
+func newBackOff(opts RetryOptions) *backoff.ExponentialBackOff {
 	bo := backoff.NewExponentialBackOff()
-	bo.InitialInterval = 2 * time.Second
+	bo.InitialInterval = opts.InitialInterval
+	bo.MaxInterval = opts.MaxInterval
 	bo.Multiplier = 2
-	bo.RandomizationFactor = 0
+	bo.RandomizationFactor = opts.Jitter
 	bo.MaxElapsedTime = 0 // no overall deadline; ctx handles cancellation
+	bo.Reset()
+	return bo
+}
+
+// retryAfterBackOff defers to a server-provided retry hint when one was
+// recorded for the last failed attempt, falling back to the wrapped schedule.
+// The hint is capped at max (llm.retry_max_ms), so a gateway answering
+// Retry-After: 86400 cannot stall the run for a day.
+type retryAfterBackOff struct {
+	backoff.BackOff
+	hint *time.Duration
+	max  time.Duration
+}
+
+func (b *retryAfterBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next == backoff.Stop {
+		return next
+	}
+	if *b.hint > 0 {
+		next = min(*b.hint, b.max)
+		*b.hint = 0
+	}
+	return next
+}
+
+func AnalyzeDrift(ctx context.Context, p Provider, adrContent, codeContext, filename, systemPrompt string) (*AnalysisResult, error) {
+	return AnalyzeDriftWithOptions(ctx, p, adrContent, codeContext, filename, systemPrompt, DefaultRetryOptions())
+}
+
+// AnalyzeDriftWithOptions is AnalyzeDrift with an explicit retry policy.
+func AnalyzeDriftWithOptions(ctx context.Context, p Provider, adrContent, codeContext, filename, systemPrompt string, opts RetryOptions) (*AnalysisResult, error) {
+	prompt := GetAnalyzeDriftPrompt(adrContent, codeContext, filename)
 
-	var lastErr error
 	var final AnalysisResult
+	err := chatWithRetry(ctx, p, systemPrompt, prompt, opts, func(raw string) error {
+		cleaned := CleanJSON(raw)
+		var res AnalysisResult
+		if err := json.Unmarshal([]byte(cleaned), &res); err != nil {
+			// Second attempt at unmarshaling raw output
+			if err2 := json.Unmarshal([]byte(raw), &res); err2 != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidJSON, err2)
+			}
+		}
+		// Set after unmarshaling so a "raw" key in the model output cannot overwrite it.
+		res.Raw = raw
+		final = res
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &final, nil
+}
+
+// chatWithRetry sends prompt until parse accepts a response, retrying
+// transient provider errors and, unless opts.NoParseRetry is set, empty
+// responses and those parse rejects.
+func chatWithRetry(ctx context.Context, p Provider, systemPrompt, prompt string, opts RetryOptions, parse func(raw string) error) error {
+	defaults := DefaultRetryOptions()
+	if opts.InitialInterval <= 0 {
+		opts.InitialInterval = defaults.InitialInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = defaults.MaxInterval
+	}
+	if opts.MaxInterval < opts.InitialInterval {
+		opts.MaxInterval = opts.InitialInterval
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	maxRetries := uint64(opts.MaxRetries)
+
+	var retryAfter time.Duration
+	bo := &retryAfterBackOff{BackOff: newBackOff(opts), hint: &retryAfter, max: opts.MaxInterval}
+
+	var lastErr error
+	var permanent bool
 
 	operation := func() error {
 		raw, err := p.Chat(ctx, systemPrompt, prompt)
 		if err != nil {
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) {
+				retryAfter = rateLimitErr.RetryAfter
+			}
 			lastErr = err
+			if isPermanent(err) {
+				permanent = true
+				return backoff.Permanent(err)
+			}
 			return err
 		}
 
-		cleaned := CleanJSON(raw)
-		var res AnalysisResult
-		if err := json.Unmarshal([]byte(cleaned), &res); err != nil {
-			// Second attempt at unmarshaling raw output
-			if err2 := json.Unmarshal([]byte(raw), &res); err2 != nil {
-				lastErr = fmt.Errorf("invalid json from provider: %w", err2)
-				return lastErr
+		parseFailed := func(err error) error {
+			lastErr = err
+			if opts.NoParseRetry {
+				permanent = true
+				return backoff.Permanent(err)
 			}
+			return err
+		}
+		if strings.TrimSpace(raw) == "" {
+			return parseFailed(ErrEmptyResponse)
+		}
+
+		if err := parse(raw); err != nil {
+			return parseFailed(err)
 		}
-		final = res
 		return nil
 	}
 
 	retryPolicy := backoff.WithContext(backoff.WithMaxRetries(bo, maxRetries), ctx)
 	if err := backoff.Retry(operation, retryPolicy); err != nil {
 		if ctxErr := ctx.Err(); ctxErr != nil {
-			return nil, ctxErr
+			return ctxErr
 		}
-		return nil, fmt.Errorf("analysis failed after %d retries: %w", maxRetries, lastErr)
+		if permanent {
+			return fmt.Errorf("analysis failed: %w", lastErr)
+		}
+		return fmt.Errorf("analysis failed after %d retries: %w", maxRetries, lastErr)
 	}
-
-	return &final, nil
+	return nil
 }
 
 func CleanJSON(input string) string {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -43,22 +44,44 @@ func TestAnalyzeDrift_Retry(t *testing.T) {
 	}
 }
 
-func TestAnalyzeDrift_MaxRetriesExceeded(t *testing.T) {
-	attempts := 0
-	provider := &MockProvider{
-		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
-			attempts++
-			return "", fmt.Errorf("persistent error")
-		},
+func TestAnalyzeDrift_MaxRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		defaults   bool
+		maxRetries int
+		attempts   int
+	}{
+		{name: "default", defaults: true, attempts: 4},
+		{name: "max_retries=0", maxRetries: 0, attempts: 1},
+		{name: "max_retries=1", maxRetries: 1, attempts: 2},
+		{name: "max_retries=5", maxRetries: 5, attempts: 6},
+		{name: "max_retries=-1", maxRetries: -1, attempts: 1},
 	}
 
-	_, err := AnalyzeDrift(context.Background(), provider, "adr", "code", "file.go", "system")
-	if err == nil {
-		t.Fatal("Expected error, got nil")
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			provider := &MockProvider{
+				ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+					attempts++
+					return "", fmt.Errorf("persistent error")
+				},
+			}
 
-	if attempts != 4 {
-		t.Errorf("Expected 4 attempts, got %d", attempts)
+			var err error
+			if tt.defaults {
+				_, err = AnalyzeDrift(context.Background(), provider, "adr", "code", "file.go", "system")
+			} else {
+				opts := RetryOptions{MaxRetries: tt.maxRetries, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+				_, err = AnalyzeDriftWithOptions(context.Background(), provider, "adr", "code", "file.go", "system", opts)
+			}
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if attempts != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, attempts)
+			}
+		})
 	}
 }
 
@@ -77,3 +100,191 @@ func TestAnalyzeDrift_ContextCancelled(t *testing.T) {
 		t.Fatalf("expected context.Canceled, got %v", err)
 	}
 }
+
+func TestNewBackOff_AppliesJitter(t *testing.T) {
+	opts := RetryOptions{MaxRetries: 3, InitialInterval: 2 * time.Second, MaxInterval: 30 * time.Second, Jitter: 0.25}
+
+	seen := make(map[time.Duration]bool)
+	for range 50 {
+		d := newBackOff(opts).NextBackOff()
+		if d < 1500*time.Millisecond || d > 2500*time.Millisecond {
+			t.Fatalf("expected first backoff within ±25%% of 2s, got %v", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected jittered backoff intervals to vary, got %v", seen)
+	}
+}
+
+func TestAnalyzeDriftWithOptions_CustomBaseInterval(t *testing.T) {
+	attempts := 0
+	provider := &MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			attempts++
+			if attempts < 2 {
+				return "", fmt.Errorf("simulated 429 error")
+			}
+			return `{"violation": false, "reasoning": "success", "quoted_code": ""}`, nil
+		},
+	}
+
+	opts := RetryOptions{MaxRetries: 3, InitialInterval: 10 * time.Millisecond, MaxInterval: 20 * time.Millisecond, Jitter: 0.25}
+
+	start := time.Now()
+	if _, err := AnalyzeDriftWithOptions(context.Background(), provider, "adr", "code", "file.go", "system", opts); err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected configured base interval to shorten backoff, took %v", elapsed)
+	}
+}
+
+func TestAnalyzeDrift_HonorsRetryAfter(t *testing.T) {
+	attempts := 0
+	provider := &MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			attempts++
+			if attempts < 2 {
+				return "", &RateLimitError{RetryAfter: 50 * time.Millisecond, Err: fmt.Errorf("simulated 429 error")}
+			}
+			return `{"violation": false, "reasoning": "success", "quoted_code": ""}`, nil
+		},
+	}
+
+	start := time.Now()
+	if _, err := AnalyzeDrift(context.Background(), provider, "adr", "code", "file.go", "system"); err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	duration := time.Since(start)
+
+	if duration < 50*time.Millisecond || duration >= time.Second {
+		t.Errorf("expected retry to wait for the server hint (~50ms) instead of the default schedule, took %v", duration)
+	}
+}
+
+func TestAnalyzeDrift_ClampsRetryAfter(t *testing.T) {
+	attempts := 0
+	provider := &MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			attempts++
+			if attempts < 2 {
+				return "", &RateLimitError{RetryAfter: 24 * time.Hour, Err: fmt.Errorf("simulated 429 error")}
+			}
+			return `{"violation": false, "reasoning": "success", "quoted_code": ""}`, nil
+		},
+	}
+	opts := RetryOptions{MaxRetries: 3, InitialInterval: 10 * time.Millisecond, MaxInterval: 20 * time.Millisecond}
+
+	start := time.Now()
+	if _, err := AnalyzeDriftWithOptions(context.Background(), provider, "adr", "code", "file.go", "system", opts); err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Retry-After to be capped at the max interval, took %v", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := ParseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("expected 3s, got %v", got)
+	}
+	if got := ParseRetryAfter(""); got != 0 {
+		t.Errorf("expected 0 for empty header, got %v", got)
+	}
+	if got := ParseRetryAfter("soon"); got != 0 {
+		t.Errorf("expected 0 for invalid header, got %v", got)
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := ParseRetryAfter(future); got <= 0 || got > time.Minute {
+		t.Errorf("expected HTTP-date header to yield up to 1m, got %v", got)
+	}
+}
+
+func TestAnalyzeDrift_PreservesRawResponse(t *testing.T) {
+	raw := "Here you go:\n```json\n{\"violation\": true, \"reasoning\": \"r\", \"quoted_code\": \"q\", \"raw\": \"spoofed\"}\n```"
+	provider := &MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return raw, nil
+		},
+	}
+
+	res, err := AnalyzeDrift(context.Background(), provider, "adr", "code", "file.go", "system")
+	if err != nil {
+		t.Fatalf("Expected success, got error: %v", err)
+	}
+	if res.Raw != raw {
+		t.Errorf("expected Raw to hold the unmodified response, got %q", res.Raw)
+	}
+	if !res.Violation || res.Reasoning != "r" {
+		t.Errorf("expected parsed fields to be populated, got %+v", res)
+	}
+}
+
+func TestAnalyzeDrift_FailsFastOnAuthError(t *testing.T) {
+	attempts := 0
+	provider := &MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			attempts++
+			return "", classifyStatus(http.StatusUnauthorized, fmt.Errorf("invalid api key"))
+		},
+	}
+
+	_, err := AnalyzeDrift(context.Background(), provider, "adr", "code", "file.go", "system")
+	if !errors.Is(err, ErrAuth) {
+		t.Fatalf("expected ErrAuth, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt for an auth error, got %d", attempts)
+	}
+}
+
+func TestAnalyzeDrift_EmptyResponse(t *testing.T) {
+	for _, noRetry := range []bool{false, true} {
+		attempts := 0
+		provider := &MockProvider{
+			ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+				attempts++
+				return "  \n", nil
+			},
+		}
+		opts := RetryOptions{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, NoParseRetry: noRetry}
+
+		_, err := AnalyzeDriftWithOptions(context.Background(), provider, "adr", "code", "file.go", "system", opts)
+		if !errors.Is(err, ErrEmptyResponse) || !IsParseFailure(err) {
+			t.Fatalf("NoParseRetry=%v: expected ErrEmptyResponse, got %v", noRetry, err)
+		}
+		want := 3
+		if noRetry {
+			want = 1
+		}
+		if attempts != want {
+			t.Errorf("NoParseRetry=%v: expected %d attempts, got %d", noRetry, want, attempts)
+		}
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	base := fmt.Errorf("boom")
+	cases := []struct {
+		code int
+		want error
+	}{
+		{http.StatusUnauthorized, ErrAuth},
+		{http.StatusForbidden, ErrAuth},
+		{http.StatusTooManyRequests, ErrRateLimit},
+		{http.StatusInternalServerError, ErrServer},
+		{http.StatusServiceUnavailable, ErrServer},
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusNotFound, ErrBadRequest},
+	}
+	for _, c := range cases {
+		err := classifyStatus(c.code, base)
+		if !errors.Is(err, c.want) {
+			t.Errorf("classifyStatus(%d) = %v, want %v", c.code, err, c.want)
+		}
+		if !errors.Is(err, base) {
+			t.Errorf("classifyStatus(%d) lost the wrapped error", c.code)
+		}
+	}
+}
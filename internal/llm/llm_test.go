@@ -1,9 +1,43 @@
 package llm
 
 import (
+	"context"
+	"math"
 	"testing"
 )
 
+func TestMockProvider_HashEmbeddings(t *testing.T) {
+	m := &MockProvider{EmbeddingDim: 32, HashEmbeddings: true}
+	ctx := context.Background()
+
+	a1, _ := m.CreateEmbedding(ctx, "alpha")
+	a2, _ := m.CreateEmbedding(ctx, "alpha")
+	b, _ := m.CreateEmbedding(ctx, "beta")
+
+	if len(a1) != 32 {
+		t.Fatalf("expected 32 dimensions, got %d", len(a1))
+	}
+	var norm, same, diff float64
+	for i := range a1 {
+		norm += float64(a1[i]) * float64(a1[i])
+		if a1[i] != a2[i] {
+			same++
+		}
+		if a1[i] != b[i] {
+			diff++
+		}
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("expected unit vector, got squared norm %f", norm)
+	}
+	if same != 0 {
+		t.Errorf("expected identical embeddings for identical text")
+	}
+	if diff == 0 {
+		t.Errorf("expected different embeddings for different text")
+	}
+}
+
 func TestCleanJSON(t *testing.T) {
 	tests := []struct {
 		name     string
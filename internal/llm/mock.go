@@ -2,6 +2,9 @@ package llm
 
 import (
 	"context"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
 )
 
 type MockProvider struct {
@@ -10,6 +13,10 @@ type MockProvider struct {
 	ChatFunc     func(ctx context.Context, system, user string) (string, error)
 	Debug        bool
 	EmbeddingDim int
+	// HashEmbeddings derives each embedding from a hash of the text: equal
+	// texts get identical unit vectors and different texts get near-orthogonal
+	// ones, so tests can exercise similarity thresholds and top-K ranking.
+	HashEmbeddings bool
 }
 
 func (m *MockProvider) SetDebug(debug bool) {
@@ -25,11 +32,35 @@ func (m *MockProvider) CreateEmbedding(ctx context.Context, text string) ([]floa
 	if dim == 0 {
 		dim = 1536
 	}
+	if m.HashEmbeddings {
+		return hashEmbedding(text, dim), nil
+	}
 	v := make([]float32, dim)
 	v[0] = 1.0
 	return v, nil
 }
 
+// hashEmbedding returns a stable pseudo-random unit vector seeded by text.
+func hashEmbedding(text string, dim int) []float32 {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	seed := h.Sum64()
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	v := make([]float32, dim)
+	var norm float64
+	for i := range v {
+		x := rng.NormFloat64()
+		v[i] = float32(x)
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / norm)
+	}
+	return v
+}
+
 func (m *MockProvider) Chat(ctx context.Context, system, user string) (string, error) {
 	if m.ChatFunc != nil {
 		return m.ChatFunc(ctx, system, user)
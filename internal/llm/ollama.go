@@ -3,8 +3,10 @@ package llm
 import (
 	"context"
 	"encoding/json"
-	"net/http"
+	"errors"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/ollama/ollama/api"
 )
@@ -15,6 +17,11 @@ type OllamaProvider struct {
 	embedModel  string
 	temperature float64
 	client      *api.Client
+	base        *url.URL
+
+	// streamIdle enables streamed chat responses, abandoned after this long
+	// without a chunk (llm.stream). Zero waits for the whole response.
+	streamIdle time.Duration
 }
 
 // NewOllamaProvider initializes the Ollama provider with necessary configuration.
@@ -44,16 +51,27 @@ func newOllamaProvider(baseURL, model, embedModel string, temperature float64) *
 		model:       model,
 		embedModel:  embedModel,
 		temperature: temperature,
-		client:      api.NewClient(base, http.DefaultClient),
+		client:      api.NewClient(base, sharedClient()),
+		base:        base,
 	}
 }
 
+// SetHeaders adds headers to every request the provider sends.
+func (p *OllamaProvider) SetHeaders(headers map[string]string) {
+	p.client = api.NewClient(p.base, withHeaders(sharedClient(), headers))
+}
+
+// SetStreaming makes Chat stream its responses; see StreamSetter.
+func (p *OllamaProvider) SetStreaming(idleTimeout time.Duration) {
+	p.streamIdle = idleTimeout
+}
+
 /**
  * REGION: Interface Implementation
  */
 
 func (p *OllamaProvider) Chat(ctx context.Context, system, user string) (string, error) {
-	stream := false
+	stream := p.streamIdle > 0
 	req := &api.ChatRequest{
 		Model:  p.model,
 		Stream: &stream,
@@ -67,15 +85,30 @@ func (p *OllamaProvider) Chat(ctx context.Context, system, user string) (string,
 		},
 	}
 
-	var content string
-	err := p.client.Chat(ctx, req, func(res api.ChatResponse) error {
-		content = res.Message.Content
+	if !stream {
+		var content string
+		err := p.client.Chat(ctx, req, func(res api.ChatResponse) error {
+			content = res.Message.Content
+			return nil
+		})
+		if err != nil {
+			return "", wrapOllamaError(err)
+		}
+		return content, nil
+	}
+
+	streamCtx, touch, stop := withIdleTimeout(ctx, p.streamIdle)
+	defer stop()
+	var content strings.Builder
+	err := p.client.Chat(streamCtx, req, func(res api.ChatResponse) error {
+		touch()
+		content.WriteString(res.Message.Content)
 		return nil
 	})
 	if err != nil {
-		return "", err
+		return "", stallCause(streamCtx, wrapOllamaError(err))
 	}
-	return content, nil
+	return content.String(), nil
 }
 
 func (p *OllamaProvider) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
@@ -86,7 +119,7 @@ func (p *OllamaProvider) CreateEmbedding(ctx context.Context, text string) ([]fl
 
 	res, err := p.client.Embeddings(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, wrapOllamaError(err)
 	}
 
 	embedding := make([]float32, len(res.Embedding))
@@ -95,3 +128,16 @@ func (p *OllamaProvider) CreateEmbedding(ctx context.Context, text string) ([]fl
 	}
 	return embedding, nil
 }
+
+// wrapOllamaError classifies an Ollama client error by its HTTP status.
+func wrapOllamaError(err error) error {
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		return classifyStatus(statusErr.StatusCode, err)
+	}
+	var authErr api.AuthorizationError
+	if errors.As(err, &authErr) {
+		return classifyStatus(authErr.StatusCode, err)
+	}
+	return err
+}
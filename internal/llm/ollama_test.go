@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestOllamaProvider_Chat(t *testing.T) {
@@ -38,6 +39,36 @@ func TestOllamaProvider_Chat(t *testing.T) {
 	}
 }
 
+func TestOllamaProvider_ChatStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		if reqBody["stream"] != true {
+			t.Errorf("expected a streamed request, got stream=%v", reqBody["stream"])
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, chunk := range []string{`{\"violation\"`, `: false`, `}`} {
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"` + chunk + `"},"done":false}` + "\n"))
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":""},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	p := NewOllamaProviderWithBaseURL(server.URL, "llama3.2", "nomic-embed-text", 0.0)
+	p.SetStreaming(time.Second)
+
+	res, err := p.Chat(context.Background(), "system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if res != `{"violation": false}` {
+		t.Errorf("unexpected response: %q", res)
+	}
+}
+
 func TestOllamaProvider_CreateEmbedding(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var reqBody map[string]interface{}
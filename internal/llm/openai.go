@@ -2,8 +2,12 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -16,12 +20,67 @@ type OpenAIProvider struct {
 	client     openai.Client
 	model      string
 	embedModel string
+
+	// noSchema is set once the model rejects json_schema response formats;
+	// later requests go straight to json_object.
+	noSchema atomic.Bool
+
+	// requestOptions are applied to every request, e.g. llm.headers.
+	requestOptions []option.RequestOption
+
+	// streamIdle enables streamed chat responses, abandoned after this long
+	// without a chunk (llm.stream). Zero sends non-streamed requests.
+	streamIdle time.Duration
+}
+
+// analysisResultSchema describes AnalysisResult for structured outputs. Strict
+// mode requires every property to be listed as required, so optional fields
+// come back as "" or false rather than missing.
+var analysisResultSchema = jsonSchema{
+	Name: "analysis_result",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"violation":          map[string]interface{}{"type": "boolean"},
+			"reasoning":          map[string]interface{}{"type": "string"},
+			"quoted_code":        map[string]interface{}{"type": "string"},
+			"violated_rule":      map[string]interface{}{"type": "string"},
+			"needs_full_context": map[string]interface{}{"type": "boolean"},
+		},
+		"required":             []string{"violation", "reasoning", "quoted_code", "violated_rule", "needs_full_context"},
+		"additionalProperties": false,
+	},
+}
+
+// jsonSchema is a named JSON schema that structured outputs constrain a
+// chat response to.
+type jsonSchema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+type responseSchemaKey struct{}
+
+// withResponseSchema returns a context whose Chat calls ask for responses
+// matching schema instead of AnalysisResult. It travels with the context so
+// that wrappers such as SplitProvider and ThrottledProvider pass it through.
+func withResponseSchema(ctx context.Context, schema jsonSchema) context.Context {
+	return context.WithValue(ctx, responseSchemaKey{}, schema)
+}
+
+// responseSchema returns the schema set with withResponseSchema, or the
+// AnalysisResult schema.
+func responseSchema(ctx context.Context) jsonSchema {
+	if schema, ok := ctx.Value(responseSchemaKey{}).(jsonSchema); ok {
+		return schema
+	}
+	return analysisResultSchema
 }
 
 // NewOpenAIProvider constructs an OpenAIProvider that talks to the real
 // OpenAI API.
 func NewOpenAIProvider(apiKey, model, embedModel string) *OpenAIProvider {
-	return NewOpenAIProviderWithBaseURL(apiKey, model, embedModel, openAIBaseURL, &http.Client{})
+	return NewOpenAIProviderWithBaseURL(apiKey, model, embedModel, openAIBaseURL, sharedClient())
 }
 
 // NewOpenAIProviderWithBaseURL constructs an OpenAIProvider pointed at a
@@ -40,33 +99,96 @@ func NewOpenAIProviderWithBaseURL(apiKey, model, embedModel, baseURL string, htt
 	}
 }
 
+// SetHeaders adds headers to every request the provider sends.
+func (p *OpenAIProvider) SetHeaders(headers map[string]string) {
+	for k, v := range headers {
+		p.requestOptions = append(p.requestOptions, option.WithHeader(k, v))
+	}
+}
+
+// SetStreaming makes Chat stream its responses; see StreamSetter.
+func (p *OpenAIProvider) SetStreaming(idleTimeout time.Duration) {
+	p.streamIdle = idleTimeout
+}
+
+// Chat requests a structured output matching AnalysisResult, or the schema
+// the caller set on ctx, so responses always unmarshal. Models without
+// structured-output support fall back to plain JSON mode.
 func (p *OpenAIProvider) Chat(ctx context.Context, system, user string) (string, error) {
-	resp, err := p.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	params := openai.ChatCompletionNewParams{
 		Model: p.model,
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(system),
 			openai.UserMessage(user),
 		},
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+	}
+	if !p.noSchema.Load() {
+		schema := responseSchema(ctx)
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   schema.Name,
+					Strict: openai.Bool(true),
+					Schema: schema.Schema,
+				},
+			},
+		}
+	} else {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
 			OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
-		},
-	})
+		}
+	}
+
+	content, err := p.complete(ctx, params)
+	if err != nil && params.ResponseFormat.OfJSONSchema != nil && schemaUnsupported(err) {
+		p.noSchema.Store(true)
+		return p.Chat(ctx, system, user)
+	}
 	if err != nil {
-		return "", fmt.Errorf("openai chat completion failed: %w", err)
+		return "", wrapOpenAIError("openai chat completion failed", err)
 	}
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no choices returned")
+	return content, nil
+}
+
+// complete sends a chat request, streamed when streaming is enabled, and
+// returns the content of the first choice.
+func (p *OpenAIProvider) complete(ctx context.Context, params openai.ChatCompletionNewParams) (string, error) {
+	if p.streamIdle <= 0 {
+		resp, err := p.client.Chat.Completions.New(ctx, params, p.requestOptions...)
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("no choices returned")
+		}
+		return resp.Choices[0].Message.Content, nil
+	}
+
+	streamCtx, touch, stop := withIdleTimeout(ctx, p.streamIdle)
+	defer stop()
+	stream := p.client.Chat.Completions.NewStreaming(streamCtx, params, p.requestOptions...)
+	defer stream.Close()
+
+	var content strings.Builder
+	for stream.Next() {
+		touch()
+		if chunk := stream.Current(); len(chunk.Choices) > 0 {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+		}
 	}
-	return resp.Choices[0].Message.Content, nil
+	if err := stream.Err(); err != nil {
+		return "", stallCause(streamCtx, err)
+	}
+	return content.String(), nil
 }
 
 func (p *OpenAIProvider) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
 		Input: openai.EmbeddingNewParamsInputUnion{OfString: openai.String(text)},
 		Model: p.embedModel,
-	})
+	}, p.requestOptions...)
 	if err != nil {
-		return nil, fmt.Errorf("openai embedding request failed: %w", err)
+		return nil, wrapOpenAIError("openai embedding request failed", err)
 	}
 	if len(resp.Data) == 0 {
 		return nil, fmt.Errorf("no embedding data returned")
@@ -79,3 +201,33 @@ func (p *OpenAIProvider) CreateEmbedding(ctx context.Context, text string) ([]fl
 	}
 	return embedding, nil
 }
+
+// schemaUnsupported reports whether err is the 400 OpenAI returns for models
+// that do not support json_schema response formats.
+func schemaUnsupported(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	return apiErr.Param == "response_format" || strings.Contains(apiErr.Message, "response_format") || strings.Contains(apiErr.Message, "json_schema")
+}
+
+// wrapOpenAIError annotates an SDK error and classifies it by HTTP status,
+// converting 429 responses into a RateLimitError that carries the server's
+// Retry-After hint.
+func wrapOpenAIError(msg string, err error) error {
+	wrapped := fmt.Errorf("%s: %w", msg, err)
+
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return wrapped
+	}
+	if apiErr.StatusCode == http.StatusTooManyRequests {
+		var retryAfter time.Duration
+		if apiErr.Response != nil {
+			retryAfter = ParseRetryAfter(apiErr.Response.Header.Get("Retry-After"))
+		}
+		return &RateLimitError{RetryAfter: retryAfter, Err: wrapped}
+	}
+	return classifyStatus(apiErr.StatusCode, wrapped)
+}
@@ -3,10 +3,12 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestOpenAIProvider_Chat(t *testing.T) {
@@ -32,6 +34,10 @@ func TestOpenAIProvider_Chat(t *testing.T) {
 		if !ok || len(messages) != 2 {
 			t.Fatalf("expected 2 messages, got %v", reqBody["messages"])
 		}
+		format, _ := reqBody["response_format"].(map[string]interface{})
+		if format["type"] != "json_schema" {
+			t.Errorf("expected json_schema response format, got %v", reqBody["response_format"])
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"violation\": false}"}}]}`))
@@ -49,6 +55,77 @@ func TestOpenAIProvider_Chat(t *testing.T) {
 	}
 }
 
+func TestCheckConflict_OpenAIUsesConflictSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			ResponseFormat struct {
+				JSONSchema struct {
+					Name   string `json:"name"`
+					Schema struct {
+						Required []string `json:"required"`
+					} `json:"schema"`
+				} `json:"json_schema"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		if s := reqBody.ResponseFormat.JSONSchema; s.Name != "conflict_result" || !strings.Contains(strings.Join(s.Schema.Required, ","), "conflict") {
+			t.Errorf("expected the conflict_result schema, got %+v", s)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"conflict\": true, \"reasoning\": \"r\", \"rule_a\": \"Use REST.\", \"rule_b\": \"Use gRPC.\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProviderWithBaseURL("test-api-key", "gpt-4o-mini", "text-embedding-3-small", server.URL, server.Client())
+	opts := RetryOptions{MaxRetries: 0, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	res, err := CheckConflict(context.Background(), p, "A", "Use REST.", "B", "Use gRPC.", opts)
+	if err != nil {
+		t.Fatalf("CheckConflict failed: %v", err)
+	}
+	if !res.Conflict || res.RuleA != "Use REST." {
+		t.Errorf("expected the conflict to be parsed, got %+v", res)
+	}
+}
+
+func TestOpenAIProvider_Chat_FallsBackToJSONObject(t *testing.T) {
+	var formats []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			ResponseFormat struct {
+				Type string `json:"type"`
+			} `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		formats = append(formats, reqBody.ResponseFormat.Type)
+
+		w.Header().Set("Content-Type", "application/json")
+		if reqBody.ResponseFormat.Type == "json_schema" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model.","param":"response_format","type":"invalid_request_error"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"violation\": false}"}}]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProviderWithBaseURL("test-api-key", "gpt-3.5-turbo", "text-embedding-3-small", server.URL, server.Client())
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Chat(context.Background(), "system", "user"); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+	}
+	want := []string{"json_schema", "json_object", "json_object"}
+	if strings.Join(formats, ",") != strings.Join(want, ",") {
+		t.Errorf("expected formats %v, got %v", want, formats)
+	}
+}
+
 func TestOpenAIProvider_CreateEmbedding(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.URL.Path, "embeddings") {
@@ -99,3 +176,80 @@ func TestOpenAIProvider_ChatErrorOnNon200(t *testing.T) {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+func TestOpenAIProvider_Chat_RateLimitRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "7")
+		w.Header().Set("x-should-retry", "false")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error": {"message": "Rate limit reached", "type": "requests"}}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProviderWithBaseURL("test-api-key", "gpt-4o-mini", "text-embedding-3-small", server.URL, server.Client())
+
+	_, err := p.Chat(context.Background(), "system prompt", "user prompt")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if rateLimitErr.RetryAfter != 7*time.Second {
+		t.Errorf("expected RetryAfter of 7s, got %v", rateLimitErr.RetryAfter)
+	}
+}
+
+func TestOpenAIProvider_ChatStreaming(t *testing.T) {
+	chunks := []string{`{\"violation\"`, `: false`, `}`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		if reqBody["stream"] != true {
+			t.Errorf("expected a streamed request, got stream=%v", reqBody["stream"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, chunk := range chunks {
+			// Each chunk arrives within the idle timeout, the whole response does not.
+			time.Sleep(40 * time.Millisecond)
+			_, _ = w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"` + chunk + `"}}]}` + "\n\n"))
+			flusher.Flush()
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProviderWithBaseURL("test-api-key", "gpt-4o-mini", "text-embedding-3-small", server.URL, server.Client())
+	p.SetStreaming(100 * time.Millisecond)
+
+	res, err := p.Chat(context.Background(), "system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if res != `{"violation": false}` {
+		t.Errorf("unexpected response: %q", res)
+	}
+}
+
+func TestOpenAIProvider_ChatStreaming_Stalled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"{"}}]}` + "\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProviderWithBaseURL("test-api-key", "gpt-4o-mini", "text-embedding-3-small", server.URL, server.Client())
+	p.SetStreaming(50 * time.Millisecond)
+
+	_, err := p.Chat(context.Background(), "system prompt", "user prompt")
+	if !errors.Is(err, ErrStreamStalled) {
+		t.Fatalf("expected ErrStreamStalled, got %v", err)
+	}
+	if isPermanent(err) {
+		t.Errorf("expected a stalled stream to be retried, got permanent error %v", err)
+	}
+}
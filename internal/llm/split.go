@@ -0,0 +1,24 @@
+package llm
+
+import "context"
+
+// SplitProvider routes chat and embedding requests to different backends, so a
+// cheap local embedding model can be paired with a hosted chat model.
+type SplitProvider struct {
+	chat  Provider
+	embed Provider
+}
+
+// NewSplitProvider creates a Provider that sends Chat calls to chat and
+// CreateEmbedding calls to embed.
+func NewSplitProvider(chat, embed Provider) *SplitProvider {
+	return &SplitProvider{chat: chat, embed: embed}
+}
+
+func (p *SplitProvider) Chat(ctx context.Context, system, user string) (string, error) {
+	return p.chat.Chat(ctx, system, user)
+}
+
+func (p *SplitProvider) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return p.embed.CreateEmbedding(ctx, text)
+}
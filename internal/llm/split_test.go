@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSplitProvider_RoutesCalls(t *testing.T) {
+	chat := &MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			return "from chat", nil
+		},
+		EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			t.Error("embedding request routed to chat provider")
+			return nil, nil
+		},
+	}
+	embed := &MockProvider{
+		ChatFunc: func(ctx context.Context, system, user string) (string, error) {
+			t.Error("chat request routed to embedding provider")
+			return "", nil
+		},
+		EmbedFunc: func(ctx context.Context, text string) ([]float32, error) {
+			return []float32{1, 2}, nil
+		},
+	}
+
+	p := NewSplitProvider(chat, embed)
+
+	res, err := p.Chat(context.Background(), "system", "user")
+	if err != nil || res != "from chat" {
+		t.Fatalf("expected chat response, got %q (err %v)", res, err)
+	}
+
+	emb, err := p.CreateEmbedding(context.Background(), "text")
+	if err != nil || len(emb) != 2 {
+		t.Fatalf("expected embedding from embed provider, got %v (err %v)", emb, err)
+	}
+}
@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStreamStalled reports that a streamed chat response stopped producing
+// chunks. It is retried like a server error.
+var ErrStreamStalled = errors.New("response stream stalled")
+
+// StreamSetter is implemented by providers that can stream chat responses
+// (llm.stream). Chat still returns the assembled response, but a request is
+// only abandoned once no chunk has arrived for idleTimeout, so a long
+// analysis that is still producing tokens is not cut off.
+type StreamSetter interface {
+	SetStreaming(idleTimeout time.Duration)
+}
+
+// withIdleTimeout returns a context that is cancelled with ErrStreamStalled
+// when touch is not called for timeout. stop releases the timer and must be
+// called once the stream is done.
+func withIdleTimeout(ctx context.Context, timeout time.Duration) (streamCtx context.Context, touch, stop func()) {
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(timeout, func() {
+		cancel(fmt.Errorf("%w: no data for %s", ErrStreamStalled, timeout))
+	})
+	touch = func() { timer.Reset(timeout) }
+	stop = func() {
+		timer.Stop()
+		cancel(nil)
+	}
+	return streamCtx, touch, stop
+}
+
+// stallCause returns the ErrStreamStalled cause of a stream cancelled by
+// withIdleTimeout, so callers report the stall instead of a bare
+// "context canceled", or err unchanged.
+func stallCause(streamCtx context.Context, err error) error {
+	if cause := context.Cause(streamCtx); errors.Is(cause, ErrStreamStalled) {
+		return cause
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ThrottledProvider spaces requests to the wrapped provider evenly so that no
+// more than a fixed number are sent per minute, for long-running scans that
+// must stay under a provider quota.
+type ThrottledProvider struct {
+	inner    Provider
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewThrottledProvider limits p to perMinute requests, counting chat and
+// embedding calls together.
+func NewThrottledProvider(p Provider, perMinute int) *ThrottledProvider {
+	return &ThrottledProvider{inner: p, interval: time.Minute / time.Duration(perMinute)}
+}
+
+// wait blocks until the next request slot, or until ctx is cancelled.
+func (p *ThrottledProvider) wait(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.next
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next = slot.Add(p.interval)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *ThrottledProvider) Chat(ctx context.Context, system, user string) (string, error) {
+	if err := p.wait(ctx); err != nil {
+		return "", err
+	}
+	return p.inner.Chat(ctx, system, user)
+}
+
+func (p *ThrottledProvider) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.inner.CreateEmbedding(ctx, text)
+}
@@ -0,0 +1,29 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThrottledProvider_SpacesRequests(t *testing.T) {
+	p := NewThrottledProvider(&MockProvider{EmbeddingDim: 2}, 1200) // one request per 50ms
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := p.CreateEmbedding(context.Background(), "text"); err != nil {
+			t.Fatalf("CreateEmbedding failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected 3 requests to take at least 100ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p = NewThrottledProvider(&MockProvider{}, 1)
+	_, _ = p.CreateEmbedding(context.Background(), "first")
+	if _, err := p.CreateEmbedding(ctx, "second"); err == nil {
+		t.Error("expected cancelled context to abort the wait")
+	}
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMaxIdleConnsPerHost is the number of idle connections kept per
+// provider host when llm.http.max_idle_conns_per_host is unset. It is well
+// above http.DefaultTransport's 2, so a scan with analysis.max_concurrency
+// parallel requests reuses its connections instead of opening (and TLS
+// handshaking) a new one for most requests.
+const DefaultMaxIdleConnsPerHost = 32
+
+// TransportOptions tunes the HTTP transport shared by the built-in providers
+// (llm.http). Zero values use the defaults.
+type TransportOptions struct {
+	MaxIdleConnsPerHost int           // Defaults to DefaultMaxIdleConnsPerHost
+	IdleConnTimeout     time.Duration // Defaults to 90 seconds
+	DisableHTTP2        bool          // Use HTTP/1.1 only
+}
+
+var (
+	transportMu sync.Mutex
+	transport   = NewTransport(TransportOptions{})
+)
+
+// NewTransport returns a keep-alive transport based on http.DefaultTransport,
+// so proxy settings from the environment still apply, with HTTP/2 negotiated
+// over TLS unless opts.DisableHTTP2 is set.
+func NewTransport(opts TransportOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if opts.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	t.MaxIdleConns = max(t.MaxIdleConns, t.MaxIdleConnsPerHost)
+	if opts.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	t.ForceAttemptHTTP2 = !opts.DisableHTTP2
+	if opts.DisableHTTP2 {
+		// A non-nil, empty TLSNextProto turns off HTTP/2 negotiation.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
+
+// SetTransportOptions replaces the transport shared by the built-in
+// providers. Providers constructed afterwards use it; call it before creating
+// them.
+func SetTransportOptions(opts TransportOptions) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	transport = NewTransport(opts)
+}
+
+// sharedClient returns an HTTP client on the shared transport, so every
+// provider instance, chat and embedding alike, draws from one connection
+// pool.
+func sharedClient() *http.Client {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	return &http.Client{Transport: transport}
+}
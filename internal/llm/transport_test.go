@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewTransport(t *testing.T) {
+	def := NewTransport(TransportOptions{})
+	if def.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || !def.ForceAttemptHTTP2 {
+		t.Errorf("unexpected defaults: MaxIdleConnsPerHost=%d ForceAttemptHTTP2=%v", def.MaxIdleConnsPerHost, def.ForceAttemptHTTP2)
+	}
+
+	tuned := NewTransport(TransportOptions{MaxIdleConnsPerHost: 200, IdleConnTimeout: time.Second, DisableHTTP2: true})
+	if tuned.MaxIdleConnsPerHost != 200 || tuned.MaxIdleConns < 200 || tuned.IdleConnTimeout != time.Second {
+		t.Errorf("options not applied: %+v", tuned)
+	}
+	if tuned.ForceAttemptHTTP2 || tuned.TLSNextProto == nil {
+		t.Error("expected HTTP/2 to be disabled")
+	}
+}
+
+func TestOllamaProvider_ReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"embedding": [0.1, 0.2]}`))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	p := NewOllamaProviderWithBaseURL(server.URL, "llama3", "nomic-embed-text", 0)
+	for range 5 {
+		if _, err := p.CreateEmbedding(context.Background(), "text"); err != nil {
+			t.Fatalf("CreateEmbedding failed: %v", err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("expected 5 sequential requests to share 1 connection, got %d", n)
+	}
+}
@@ -0,0 +1,33 @@
+// Package cli runs the archguard command line from a main package outside
+// this module, so a binary can link in providers registered with pkg/llm and
+// index backends registered with pkg/index:
+//
+//	package main
+//
+//	import (
+//		"os"
+//
+//		_ "example.com/archguard-gateway" // calls llm.Register in init
+//		"github.com/tgenz1213/archguard/pkg/cli"
+//	)
+//
+//	func main() { os.Exit(cli.Main()) }
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tgenz1213/archguard/internal/cli"
+)
+
+// Main runs the command given by os.Args, printing any error to stderr, and
+// returns the process exit code.
+func Main() int {
+	code, err := cli.Execute(nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return int(code)
+	}
+	return int(cli.ExitSuccess)
+}
@@ -0,0 +1,150 @@
+// Package index lets code outside this module add index backends. Implement
+// VectorStore, register a Factory for it under a name from an init function,
+// and build an archguard binary that links the package in (see pkg/cli);
+// setting vector_store.backend to that name then selects it.
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tgenz1213/archguard/internal/config"
+	"github.com/tgenz1213/archguard/pkg/llm"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the loaded archguard.yaml, as passed to a Factory.
+type Config = config.Config
+
+// VectorStore stores ADR embeddings and finds the ADRs most similar to a
+// file's embedding.
+type VectorStore interface {
+	CalculateHash(adrs []ADR, modelName string) (string, error)
+	Load(path, modelName string, dim int, currentHash string) error
+	Save(path string) error
+	BuildIndex(ctx context.Context, modelName string, dim int, provider llm.Provider, adrProvider Provider) error
+	Search(queryEmbedding []float32, threshold float64, topK int) []SearchResult
+}
+
+// Provider defines how ArchGuard fetches ADR documents.
+type Provider interface {
+	// GetADRs fetches ADRs, returning only those that match the provider's criteria.
+	GetADRs(ctx context.Context) ([]ADR, error)
+}
+
+// SearchResult represents an ADR matched during a vector search with its similarity score.
+type SearchResult struct {
+	ADR   *ADR
+	Score float64
+}
+
+type ADR struct {
+	ID           string     `json:"id"`
+	Title        string     `json:"title"`
+	Status       string     `json:"status"`
+	Scope        StringList `json:"scope"`                   // Optional glob patterns from frontmatter; matching any applies
+	ScopeExclude []string   `json:"scope_exclude,omitempty"` // Optional glob patterns carved out of Scope
+	Include      []string   `json:"include,omitempty"`       // Optional rule files appended to Content
+	Tags         []string   `json:"tags,omitempty"`          // Optional labels for check --tags
+	Content      string     `json:"content"`
+	Embedding    []float32  `json:"embedding"`
+	RelPath      string     `json:"rel_path"`
+	// SectionEmbeddings holds one vector per "## " section when
+	// vector_store.multi_vector is enabled; Search scores the best of these
+	// and Embedding.
+	SectionEmbeddings [][]float32 `json:"section_embeddings,omitempty"`
+}
+
+// HasAnyTag reports whether the ADR carries at least one of tags, ignoring
+// case. Every ADR matches an empty tag list.
+func (a *ADR) HasAnyTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, want := range tags {
+		for _, tag := range a.Tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StringList accepts either a single YAML string or a list of strings.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value == "" {
+			*l = nil
+			return nil
+		}
+		*l = StringList{value.Value}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// UnmarshalJSON accepts the single string written by indexes built before
+// scope could be a list, as well as an array.
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single == "" {
+			*l = nil
+		} else {
+			*l = StringList{single}
+		}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Factory constructs a VectorStore from the loaded config.
+type Factory func(cfg *Config) (VectorStore, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a custom index backend, such as a remote vector database,
+// selectable by name through vector_store.backend. Built-in backends take
+// precedence over registered names. Like llm.Register, it panics if factory
+// is nil or name is already registered, and is meant to be called from an
+// init function.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("index: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("index: Register called twice for backend %q", name))
+	}
+	registry[name] = factory
+}
+
+// Lookup returns the factory registered under name.
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	return factory, ok
+}
@@ -0,0 +1,52 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tgenz1213/archguard/pkg/llm"
+)
+
+type stubStore struct{ dim int }
+
+func (s *stubStore) CalculateHash(adrs []ADR, modelName string) (string, error) { return "", nil }
+
+func (s *stubStore) Load(path, modelName string, dim int, currentHash string) error { return nil }
+
+func (s *stubStore) Save(path string) error { return nil }
+
+func (s *stubStore) BuildIndex(ctx context.Context, modelName string, dim int, provider llm.Provider, adrProvider Provider) error {
+	return nil
+}
+
+func (s *stubStore) Search(queryEmbedding []float32, threshold float64, topK int) []SearchResult {
+	return nil
+}
+
+func TestRegister_Lookup(t *testing.T) {
+	Register("test-qdrant", func(cfg *Config) (VectorStore, error) {
+		return &stubStore{dim: cfg.VectorStore.EmbeddingDim}, nil
+	})
+
+	factory, ok := Lookup("test-qdrant")
+	if !ok {
+		t.Fatal("expected registered backend to be found")
+	}
+	cfg := &Config{}
+	cfg.VectorStore.EmbeddingDim = 8
+	store, err := factory(cfg)
+	if s, ok := store.(*stubStore); err != nil || !ok || s.dim != 8 {
+		t.Fatalf("expected factory to build a store from config, got %#v, %v", store, err)
+	}
+
+	if _, ok := Lookup("unknown"); ok {
+		t.Error("expected unknown backend to be absent")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected duplicate registration to panic")
+		}
+	}()
+	Register("test-qdrant", func(*Config) (VectorStore, error) { return nil, nil })
+}
@@ -0,0 +1,54 @@
+// Package llm lets code outside this module add LLM providers. Implement
+// Provider, register a Factory for it under a name from an init function, and
+// build an archguard binary that links the package in (see pkg/cli); setting
+// llm.provider or vector_store.provider to that name then selects it.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tgenz1213/archguard/internal/config"
+)
+
+// Config is the loaded archguard.yaml, as passed to a Factory.
+type Config = config.Config
+
+// Provider creates embeddings for retrieval and chat completions for analysis.
+type Provider interface {
+	CreateEmbedding(ctx context.Context, text string) ([]float32, error)
+	Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+}
+
+// Factory constructs a Provider from the loaded config.
+type Factory func(cfg *Config) Provider
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a custom provider selectable by name through llm.provider or
+// vector_store.provider. Built-in providers take precedence over registered
+// names. Like database/sql.Register, it panics if factory is nil or name is
+// already registered, and is meant to be called from an init function.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("llm: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("llm: Register called twice for provider %q", name))
+	}
+	registry[name] = factory
+}
+
+// Lookup returns the factory registered under name.
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	return factory, ok
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+type stubProvider struct{ dim int }
+
+func (p *stubProvider) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return make([]float32, p.dim), nil
+}
+
+func (p *stubProvider) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return "", nil
+}
+
+func TestRegister_Lookup(t *testing.T) {
+	Register("test-gateway", func(cfg *Config) Provider {
+		return &stubProvider{dim: cfg.VectorStore.EmbeddingDim}
+	})
+
+	factory, ok := Lookup("test-gateway")
+	if !ok {
+		t.Fatal("expected registered provider to be found")
+	}
+	cfg := &Config{}
+	cfg.VectorStore.EmbeddingDim = 8
+	if p, ok := factory(cfg).(*stubProvider); !ok || p.dim != 8 {
+		t.Fatalf("expected factory to build a provider from config, got %#v", p)
+	}
+
+	if _, ok := Lookup("unknown"); ok {
+		t.Error("expected unknown provider to be absent")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected duplicate registration to panic")
+		}
+	}()
+	Register("test-gateway", func(*Config) Provider { return &stubProvider{} })
+}
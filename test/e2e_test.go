@@ -201,20 +201,73 @@ analysis:
 		}
 		runCheck(t, tempDir, binaryPath, fixtureFilename, int(cli.ExitSuccess))
 	})
+
+	t.Run("Detects violation at a historical revision", func(t *testing.T) {
+		if err := os.WriteFile(fixturePath, []byte(fixtureContent), 0644); err != nil {
+			t.Fatalf("Failed to recreate fixture: %v", err)
+		}
+		runGit(t, tempDir, "add", fixtureFilename)
+		runGit(t, tempDir, "-c", "user.name=archguard", "-c", "user.email=archguard@example.com", "commit", "-m", "add fixture")
+		if err := os.Remove(fixturePath); err != nil {
+			t.Fatalf("Failed to remove fixture: %v", err)
+		}
+
+		runCheckArgs(t, tempDir, binaryPath, []string{"--rev", "HEAD"}, int(cli.ExitDriftDetected))
+	})
+
+	t.Run("Detects violation in a source tree without git", func(t *testing.T) {
+		treeDir := t.TempDir()
+		for name, content := range map[string]string{
+			"archguard.yaml": configContent,
+			".env":           "",
+			fixtureFilename:  fixtureContent,
+			filepath.Join("docs", "arch", "0000-no-secrets-in-log.md"): adrContent,
+		} {
+			path := filepath.Join(treeDir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+
+		runIndexCmd(t, treeDir, binaryPath, int(cli.ExitSuccess), "--no-git")
+		runCheckArgs(t, treeDir, binaryPath, []string{"--no-git"}, int(cli.ExitDriftDetected))
+	})
+}
+
+// runGit executes a git command in dir and fails the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\nOutput: %s", args, err, out)
+	}
 }
 
 // runCheck executes the archguard check command.
 func runCheck(t *testing.T, dir, binaryPath, target string, expectedExitCode int) {
 	t.Helper()
 
+	var args []string
+	if target != "" {
+		args = append(args, target)
+	}
+	runCheckArgs(t, dir, binaryPath, args, expectedExitCode)
+}
+
+// runCheckArgs executes the archguard check command with arbitrary arguments.
+func runCheckArgs(t *testing.T, dir, binaryPath string, checkArgs []string, expectedExitCode int) {
+	t.Helper()
+
 	const maxRetries = 3
 	var lastErr error
 
 	for i := range maxRetries {
-		args := []string{"check"}
-		if target != "" {
-			args = append(args, target)
-		}
+		args := append([]string{"check"}, checkArgs...)
 
 		cmd := exec.Command(binaryPath, args...)
 		cmd.Dir = dir
@@ -247,10 +300,10 @@ func runCheck(t *testing.T, dir, binaryPath, target string, expectedExitCode int
 }
 
 // runIndexCmd executes the archguard index command and checks the expected exit code.
-func runIndexCmd(t *testing.T, dir, binaryPath string, expectedExitCode int) {
+func runIndexCmd(t *testing.T, dir, binaryPath string, expectedExitCode int, indexArgs ...string) {
 	t.Helper()
 
-	cmd := exec.Command(binaryPath, "index")
+	cmd := exec.Command(binaryPath, append([]string{"index"}, indexArgs...)...)
 	cmd.Dir = dir
 	cmd.Env = append(os.Environ(), "ARCHGUARD_API_KEY=mock_key")
 